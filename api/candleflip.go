@@ -2,12 +2,20 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"goLangServer/config"
+	"goLangServer/db"
 	"goLangServer/ws"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 /* =========================
@@ -23,20 +31,27 @@ type RoomResponse struct {
 }
 
 type BatchResponse struct {
-	BatchID        string          `json:"batchId"`
-	PlayerAddress  string          `json:"playerAddress"`
-	AmountPerRoom  string          `json:"amountPerRoom"`
-	TotalRooms     int             `json:"totalRooms"`
-	PlayerSide     string          `json:"playerSide"`
-	AISide         string          `json:"aiSide"`
-	Status         string          `json:"status"`
-	WonRooms       int             `json:"wonRooms"`
-	PayoutAmount   string          `json:"payoutAmount,omitempty"`
-	PayoutTxHash   string          `json:"payoutTxHash,omitempty"`
-	PayoutError    string          `json:"payoutError,omitempty"`
-	ServerSeed     string          `json:"serverSeed,omitempty"`
-	ServerSeedHash string          `json:"serverSeedHash"`
-	Rooms          []RoomResponse  `json:"rooms"`
+	BatchID        string         `json:"batchId"`
+	PlayerAddress  string         `json:"playerAddress"`
+	AmountPerRoom  string         `json:"amountPerRoom"`
+	TotalRooms     int            `json:"totalRooms"`
+	PlayerSide     string         `json:"playerSide"`
+	AISide         string         `json:"aiSide"`
+	Status         string         `json:"status"`
+	WonRooms       int            `json:"wonRooms"`
+	PayoutAmount   string         `json:"payoutAmount,omitempty"`
+	PayoutTxHash   string         `json:"payoutTxHash,omitempty"`
+	PayoutError    string         `json:"payoutError,omitempty"`
+	ServerSeed     string         `json:"serverSeed,omitempty"`
+	ServerSeedHash string         `json:"serverSeedHash"`
+	ClientSeed     string         `json:"clientSeed,omitempty"`
+	Nonce          uint64         `json:"nonce"`
+	RNGVersion     int            `json:"rngVersion"`
+	PriceModel     int            `json:"priceModel"`
+	Odds           float64        `json:"odds"` // Payout per won room
+	CreatedAt      time.Time      `json:"createdAt"`
+	CompletedAt    *time.Time     `json:"completedAt,omitempty"`
+	Rooms          []RoomResponse `json:"rooms"`
 }
 
 type AllBatchesResponse struct {
@@ -45,6 +60,22 @@ type AllBatchesResponse struct {
 	Count   int             `json:"count"`
 }
 
+type BatchHistoryResponse struct {
+	Success bool                `json:"success"`
+	Batches []BatchResponse     `json:"batches"`
+	Count   int                 `json:"count"`
+	Total   int                 `json:"total"` // Batches matching the filters
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+	Stats   *db.CandleflipStats `json:"stats"` // Totals over all matching batches
+}
+
+type BatchStatsResponse struct {
+	Success bool                `json:"success"`
+	Address string              `json:"address"`
+	Stats   *db.CandleflipStats `json:"stats"`
+}
+
 type SingleBatchResponse struct {
 	Success bool          `json:"success"`
 	Batch   BatchResponse `json:"batch,omitempty"`
@@ -68,46 +99,8 @@ func HandleGetAllBatches(w http.ResponseWriter, r *http.Request) {
 
 	for _, batch := range batches {
 		batch.RLock()
-		
-		batchResp := BatchResponse{
-			BatchID:        batch.BatchID,
-			PlayerAddress:  batch.PlayerAddress.Hex(),
-			AmountPerRoom:  batch.AmountPerRoom.String(),
-			TotalRooms:     batch.TotalRooms,
-			PlayerSide:     batch.PlayerSide,
-			AISide:         getOppositeSide(batch.PlayerSide),
-			Status:         batch.Status,
-			WonRooms:       batch.WonRooms,
-			ServerSeedHash: batch.ServerSeedHash,
-			Rooms:          make([]RoomResponse, len(batch.Rooms)),
-		}
 
-		// Include server seed only if batch is completed or paid
-		if batch.Status == "completed" || batch.Status == "paid" {
-			batchResp.ServerSeed = batch.ServerSeed
-		}
-
-		// Include payout info
-		if batch.PayoutAmount != nil {
-			batchResp.PayoutAmount = batch.PayoutAmount.String()
-		}
-		if batch.PayoutTxHash != "" {
-			batchResp.PayoutTxHash = batch.PayoutTxHash
-		}
-		if batch.PayoutError != "" {
-			batchResp.PayoutError = batch.PayoutError
-		}
-
-		// Copy room data
-		for i, room := range batch.Rooms {
-			batchResp.Rooms[i] = RoomResponse{
-				RoomNumber: room.RoomNumber,
-				Status:     room.Status,
-				FinalPrice: room.FinalPrice,
-				Winner:     room.Winner,
-				PlayerWon:  room.PlayerWon,
-			}
-		}
+		batchResp := newBatchResponse(r.Context(), batch)
 
 		batch.RUnlock()
 
@@ -142,45 +135,7 @@ func HandleGetBatchByID(w http.ResponseWriter, r *http.Request) {
 	batch.RLock()
 	defer batch.RUnlock()
 
-	batchResp := BatchResponse{
-		BatchID:        batch.BatchID,
-		PlayerAddress:  batch.PlayerAddress.Hex(),
-		AmountPerRoom:  batch.AmountPerRoom.String(),
-		TotalRooms:     batch.TotalRooms,
-		PlayerSide:     batch.PlayerSide,
-		AISide:         getOppositeSide(batch.PlayerSide),
-		Status:         batch.Status,
-		WonRooms:       batch.WonRooms,
-		ServerSeedHash: batch.ServerSeedHash,
-		Rooms:          make([]RoomResponse, len(batch.Rooms)),
-	}
-
-	// Include server seed only if batch is completed or paid
-	if batch.Status == "completed" || batch.Status == "paid" {
-		batchResp.ServerSeed = batch.ServerSeed
-	}
-
-	// Include payout info
-	if batch.PayoutAmount != nil {
-		batchResp.PayoutAmount = batch.PayoutAmount.String()
-	}
-	if batch.PayoutTxHash != "" {
-		batchResp.PayoutTxHash = batch.PayoutTxHash
-	}
-	if batch.PayoutError != "" {
-		batchResp.PayoutError = batch.PayoutError
-	}
-
-	// Copy room data
-	for i, room := range batch.Rooms {
-		batchResp.Rooms[i] = RoomResponse{
-			RoomNumber: room.RoomNumber,
-			Status:     room.Status,
-			FinalPrice: room.FinalPrice,
-			Winner:     room.Winner,
-			PlayerWon:  room.PlayerWon,
-		}
-	}
+	batchResp := newBatchResponse(r.Context(), batch)
 
 	response := SingleBatchResponse{
 		Success: true,
@@ -216,19 +171,34 @@ func HandleVerifyBatch(w http.ResponseWriter, r *http.Request) {
 	defer batch.RUnlock()
 
 	// Only return server seed if game is completed
-	if batch.Status != "completed" && batch.Status != "paid" {
+	if !batch.Finished() {
 		sendError(w, http.StatusBadRequest, "Batch is not yet completed")
 		return
 	}
 
+	serverSeed := revealedServerSeed(r.Context(), batch)
+	if serverSeed == "" {
+		sendError(w, http.StatusBadRequest, "Server seed not revealed yet. Rotate your seed pair to reveal it.")
+		return
+	}
+
+	message := "Verify by hashing the serverSeed and comparing with serverSeedHash. Each room can be reproduced using the seed format: serverSeed-room-{roomIndex}"
+	if batch.ClientSeed != "" {
+		message = "Verify by hashing the serverSeed and comparing with serverSeedHash. Room N is seeded with hex(HMAC-SHA256(serverSeed, clientSeed + \":\" + (nonce + N - 1)))"
+	}
+
 	response := map[string]interface{}{
 		"success":        true,
 		"batchId":        batch.BatchID,
-		"serverSeed":     batch.ServerSeed,
+		"serverSeed":     serverSeed,
 		"serverSeedHash": batch.ServerSeedHash,
+		"clientSeed":     batch.ClientSeed,
+		"nonce":          batch.Nonce,
+		"rngVersion":     batch.RNGVersion,
+		"priceModel":     batch.PriceModel,
 		"totalRooms":     batch.TotalRooms,
 		"wonRooms":       batch.WonRooms,
-		"message":        "Verify by hashing the serverSeed and comparing with serverSeedHash. Each room can be reproduced using the seed format: serverSeed-room-{roomNumber}",
+		"message":        message,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -237,13 +207,224 @@ func HandleVerifyBatch(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔍 Batch verification - Batch: %s", batchID)
 }
 
+// HandleGetBatchHistory returns a page of a player's finished batches
+// GET /api/candleflip/history?address=0x...&side=bull&outcome=win&from=...&to=...&limit=20&offset=0
+func HandleGetBatchHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	batches, total, err := ws.GetBatchHistory(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	stats, err := db.GetCandleflipStats(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := BatchHistoryResponse{
+		Success: true,
+		Batches: make([]BatchResponse, 0, len(batches)),
+		Count:   len(batches),
+		Total:   total,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		Stats:   stats,
+	}
+	for _, batch := range batches {
+		response.Batches = append(response.Batches, newBatchResponse(r.Context(), batch))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("📋 Retrieved %d/%d CandleFlip batches for %s", len(batches), total, filter.PlayerAddress)
+}
+
+// HandleGetBatchStats returns win/loss and net profit totals for a player
+// GET /api/candleflip/stats?address=0x...&side=bull&from=...&to=...
+func HandleGetBatchStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if db.PostgresPool == nil {
+		sendError(w, http.StatusServiceUnavailable, "Database unavailable")
+		return
+	}
+
+	stats, err := db.GetCandleflipStats(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BatchStatsResponse{
+		Success: true,
+		Address: filter.PlayerAddress,
+		Stats:   stats,
+	})
+}
+
 /* =========================
    HELPER FUNCTIONS
 ========================= */
 
+// newBatchResponse converts a batch to its API representation.
+// The caller must hold the batch's read lock.
+func newBatchResponse(ctx context.Context, batch *ws.CandleflipBatch) BatchResponse {
+	batchResp := BatchResponse{
+		BatchID:        batch.BatchID,
+		PlayerAddress:  batch.PlayerAddress.Hex(),
+		AmountPerRoom:  batch.AmountPerRoom.String(),
+		TotalRooms:     batch.TotalRooms,
+		PlayerSide:     batch.PlayerSide,
+		AISide:         getOppositeSide(batch.PlayerSide),
+		Status:         batch.Status,
+		WonRooms:       batch.WonRooms,
+		ServerSeedHash: batch.ServerSeedHash,
+		ClientSeed:     batch.ClientSeed,
+		Nonce:          batch.Nonce,
+		RNGVersion:     batch.RNGVersion,
+		PriceModel:     batch.PriceModel,
+		Odds:           config.WeiToMultiplier(batch.Odds),
+		CreatedAt:      batch.CreatedAt,
+		Rooms:          make([]RoomResponse, len(batch.Rooms)),
+	}
+
+	if !batch.CompletedAt.IsZero() {
+		completedAt := batch.CompletedAt
+		batchResp.CompletedAt = &completedAt
+	}
+
+	// Include server seed only once it has been revealed
+	batchResp.ServerSeed = revealedServerSeed(ctx, batch)
+
+	// Include payout info
+	if batch.PayoutAmount != nil {
+		batchResp.PayoutAmount = batch.PayoutAmount.String()
+	}
+	if batch.PayoutTxHash != "" {
+		batchResp.PayoutTxHash = batch.PayoutTxHash
+	}
+	if batch.PayoutError != "" {
+		batchResp.PayoutError = batch.PayoutError
+	}
+
+	// Copy room data
+	for i, room := range batch.Rooms {
+		batchResp.Rooms[i] = RoomResponse{
+			RoomNumber: room.RoomNumber,
+			Status:     room.Status,
+			FinalPrice: room.FinalPrice,
+			Winner:     room.Winner,
+			PlayerWon:  room.PlayerWon,
+		}
+	}
+
+	return batchResp
+}
+
+// parseHistoryFilter reads history filters from the query string.
+// Dates are RFC 3339 timestamps or YYYY-MM-DD; "to" is exclusive.
+func parseHistoryFilter(r *http.Request) (db.CandleflipHistoryFilter, error) {
+	query := r.URL.Query()
+	filter := db.CandleflipHistoryFilter{Limit: 20}
+
+	address := query.Get("address")
+	if !common.IsHexAddress(address) {
+		return filter, fmt.Errorf("valid address is required")
+	}
+	filter.PlayerAddress = common.HexToAddress(address).Hex()
+
+	filter.Side = query.Get("side")
+	if filter.Side != "" && filter.Side != "bull" && filter.Side != "bear" {
+		return filter, fmt.Errorf("side must be 'bull' or 'bear'")
+	}
+
+	filter.Outcome = query.Get("outcome")
+	if filter.Outcome != "" && filter.Outcome != "win" && filter.Outcome != "loss" && filter.Outcome != "push" {
+		return filter, fmt.Errorf("outcome must be 'win', 'loss' or 'push'")
+	}
+
+	for _, bound := range []struct {
+		name string
+		dest **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s date", bound.name)
+		}
+		*bound.dest = &t
+	}
+
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > 100 {
+			return filter, fmt.Errorf("limit must be between 1 and 100")
+		}
+		filter.Limit = limit
+	}
+
+	if o := query.Get("offset"); o != "" {
+		offset, err := strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("invalid offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// revealedServerSeed returns the batch's server seed if players may see it.
+// Legacy batches reveal their own seed on completion; seed pair batches only
+// once the player has rotated the pair.
+func revealedServerSeed(ctx context.Context, batch *ws.CandleflipBatch) string {
+	if !batch.Finished() {
+		return ""
+	}
+	if batch.ClientSeed == "" {
+		return batch.ServerSeed
+	}
+
+	seed, err := db.GetRevealedSeed(ctx, batch.ServerSeedHash)
+	if err != nil {
+		log.Printf("⚠️ Failed to look up revealed seed: %v", err)
+		return ""
+	}
+	return seed
+}
+
 func getOppositeSide(side string) string {
 	if side == "bull" {
 		return "bear"
 	}
 	return "bull"
-}
\ No newline at end of file
+}
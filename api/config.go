@@ -0,0 +1,39 @@
+// api/config.go
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goLangServer/config"
+)
+
+// serverConfig is the runtime configuration, replaced by Configure on startup
+var serverConfig = config.Default()
+
+// Configure applies the runtime configuration to the API handlers
+func Configure(cfg *config.Config) {
+	serverConfig = cfg
+}
+
+/* =========================
+   ADMIN CONFIG ENDPOINT
+========================= */
+
+// HandleGetConfig returns the running configuration with secrets redacted
+// GET /api/admin/config
+func HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"config":  serverConfig.Redacted(),
+	})
+}
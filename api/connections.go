@@ -0,0 +1,39 @@
+// api/connections.go
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"goLangServer/ws"
+)
+
+/* =========================
+   ADMIN CONNECTIONS ENDPOINT
+========================= */
+
+// HandleListConnections lists open WebSocket connections with their metrics
+// GET /api/admin/connections
+func HandleListConnections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	conns := ws.ConnectionStats()
+	byEndpoint := make(map[string]int)
+	for _, c := range conns {
+		byEndpoint[c.Endpoint]++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"count":       len(conns),
+		"byEndpoint":  byEndpoint,
+		"connections": conns,
+	})
+}
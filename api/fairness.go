@@ -0,0 +1,220 @@
+// api/fairness.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"goLangServer/contract"
+	"goLangServer/db"
+	"goLangServer/ws"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/* =========================
+   REQUEST/RESPONSE TYPES
+========================= */
+
+// ClientSeedRequest sets a player's client seed. It must be signed by the
+// player as an EIP-712 SeedPair with action "setClientSeed".
+type ClientSeedRequest struct {
+	Address    string `json:"address"`
+	ClientSeed string `json:"clientSeed"`
+	Nonce      uint64 `json:"nonce"`     // Player's next relayer nonce
+	Signature  string `json:"signature"` // EIP-712 SeedPair signature
+}
+
+// RotateSeedRequest reveals the active server seed and commits a new one. It
+// must be signed by the player as an EIP-712 SeedPair with action "rotate".
+type RotateSeedRequest struct {
+	Address    string `json:"address"`
+	ClientSeed string `json:"clientSeed,omitempty"` // Optional new client seed
+	Nonce      uint64 `json:"nonce"`
+	Signature  string `json:"signature"`
+}
+
+// RevealedSeedPair is a rotated seed pair whose server seed is now public
+type RevealedSeedPair struct {
+	ServerSeed     string `json:"serverSeed"`
+	ServerSeedHash string `json:"serverSeedHash"`
+	ClientSeed     string `json:"clientSeed"`
+	Nonce          uint64 `json:"nonce"` // Number of rounds played with this pair
+}
+
+// SeedPairResponse returns a player's active seed pair
+type SeedPairResponse struct {
+	Success  bool              `json:"success"`
+	Active   *db.SeedPairData  `json:"active"`
+	Revealed *RevealedSeedPair `json:"revealed,omitempty"`
+	Games    []string          `json:"games"` // Games whose rounds are seeded from the pair
+	Message  string            `json:"message,omitempty"`
+}
+
+/* =========================
+   SEED PAIR ENDPOINTS
+========================= */
+
+// seedPairGames lists the games seeded from players' seed pairs. Crash rounds
+// are shared by every player, so their server seed comes from the crash seed
+// chain and only the client seed of the pair is mixed in.
+var seedPairGames = []string{"candleflip"}
+
+// authorizeSeedPair checks the player signed the seed pair request and
+// consumes its nonce. It writes the error response and reports false if not.
+func authorizeSeedPair(w http.ResponseWriter, r *http.Request, req *contract.SeedPairRequest, signature string) bool {
+	sig := common.FromHex(signature)
+	if len(sig) != 65 {
+		sendError(w, http.StatusUnauthorized, "Valid signature is required")
+		return false
+	}
+	req.Signature = sig
+
+	rel, err := ws.Relayer()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		sendError(w, http.StatusServiceUnavailable, "Signature verification unavailable")
+		return false
+	}
+	if err := rel.AuthorizeSeedPair(r.Context(), req); err != nil {
+		log.Printf("⚠️ Rejected seed pair request from %s: %v", req.PlayerAddress.Hex(), err)
+		sendError(w, http.StatusUnauthorized, "Invalid signature or nonce")
+		return false
+	}
+	return true
+}
+
+// HandleGetSeedPair returns the player's active candleflip seed pair (the server seed only as its hash)
+// GET /api/fair/seeds?address=0x...
+func HandleGetSeedPair(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if !common.IsHexAddress(address) {
+		sendError(w, http.StatusBadRequest, "Valid address is required")
+		return
+	}
+	playerAddr := common.HexToAddress(address).Hex()
+
+	pair, err := db.GetSeedPair(r.Context(), playerAddr)
+	if err != nil {
+		log.Printf("❌ Failed to get seed pair: %v", err)
+		sendError(w, http.StatusInternalServerError, "Failed to retrieve seed pair")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeedPairResponse{
+		Success: true,
+		Active:  pair,
+		Games:   seedPairGames,
+	})
+}
+
+// HandleSetClientSeed changes the player's client seed for future rounds
+// POST /api/fair/client-seed
+func HandleSetClientSeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ClientSeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !common.IsHexAddress(req.Address) {
+		sendError(w, http.StatusBadRequest, "Valid address is required")
+		return
+	}
+	if maxLen := serverConfig.Bets.MaxClientSeedLength; req.ClientSeed == "" || len(req.ClientSeed) > maxLen {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("Client seed must be 1-%d characters", maxLen))
+		return
+	}
+	player := common.HexToAddress(req.Address)
+	playerAddr := player.Hex()
+
+	if !authorizeSeedPair(w, r, &contract.SeedPairRequest{
+		PlayerAddress: player,
+		Action:        contract.SeedPairActionSetClientSeed,
+		ClientSeed:    req.ClientSeed,
+		Nonce:         req.Nonce,
+	}, req.Signature) {
+		return
+	}
+
+	pair, err := db.SetClientSeed(r.Context(), playerAddr, req.ClientSeed)
+	if err != nil {
+		log.Printf("❌ Failed to set client seed: %v", err)
+		sendError(w, http.StatusInternalServerError, "Failed to set client seed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeedPairResponse{
+		Success: true,
+		Active:  pair,
+		Games:   seedPairGames,
+		Message: "Client seed updated. It applies from the next candleflip batch and crash round.",
+	})
+}
+
+// HandleRotateSeedPair reveals the player's server seed and commits a new one
+// POST /api/fair/rotate
+func HandleRotateSeedPair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RotateSeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !common.IsHexAddress(req.Address) {
+		sendError(w, http.StatusBadRequest, "Valid address is required")
+		return
+	}
+	if maxLen := serverConfig.Bets.MaxClientSeedLength; len(req.ClientSeed) > maxLen {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("Client seed must be at most %d characters", maxLen))
+		return
+	}
+	player := common.HexToAddress(req.Address)
+	playerAddr := player.Hex()
+
+	if !authorizeSeedPair(w, r, &contract.SeedPairRequest{
+		PlayerAddress: player,
+		Action:        contract.SeedPairActionRotate,
+		ClientSeed:    req.ClientSeed,
+		Nonce:         req.Nonce,
+	}, req.Signature) {
+		return
+	}
+
+	revealed, active, err := db.RotateSeedPair(r.Context(), playerAddr, req.ClientSeed)
+	if err != nil {
+		log.Printf("❌ Failed to rotate seed pair: %v", err)
+		sendError(w, http.StatusInternalServerError, "Failed to rotate seed pair")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SeedPairResponse{
+		Success: true,
+		Active:  active,
+		Revealed: &RevealedSeedPair{
+			ServerSeed:     revealed.ServerSeed,
+			ServerSeedHash: revealed.ServerSeedHash,
+			ClientSeed:     revealed.ClientSeed,
+			Nonce:          revealed.Nonce,
+		},
+		Games:   seedPairGames,
+		Message: "Server seed revealed. Every candleflip round played with it can now be verified.",
+	})
+
+	log.Printf("🔄 Seed pair rotated for %s", playerAddr)
+}
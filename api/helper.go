@@ -1,8 +1,10 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 // sendError sends a JSON error response
@@ -13,4 +15,21 @@ func sendError(w http.ResponseWriter, statusCode int, message string) {
 		"success": false,
 		"error":   message,
 	})
-}
\ No newline at end of file
+}
+
+// requireAdmin checks the request's bearer token against the configured admin
+// API key. Admin endpoints are disabled when ADMIN_API_KEY is not set.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	key := serverConfig.Server.AdminAPIKey
+	if key == "" {
+		sendError(w, http.StatusForbidden, "Admin API disabled")
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+		sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	return true
+}
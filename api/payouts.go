@@ -0,0 +1,132 @@
+// api/payouts.go
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"goLangServer/db"
+)
+
+/* =========================
+   REQUEST/RESPONSE TYPES
+========================= */
+
+// RetryPayoutRequest moves failed payouts back to pending
+type RetryPayoutRequest struct {
+	ID  int64 `json:"id,omitempty"`  // Payout to retry
+	All bool  `json:"all,omitempty"` // Retry every failed payout instead
+}
+
+// PayoutsResponse lists payouts
+type PayoutsResponse struct {
+	Success bool               `json:"success"`
+	Payouts []*db.PayoutRecord `json:"payouts"`
+	Count   int                `json:"count"`
+}
+
+/* =========================
+   ADMIN PAYOUT ENDPOINTS
+========================= */
+
+// HandleListPayouts lists payouts in a given state (failed by default)
+// GET /api/admin/payouts?status=failed&limit=100
+func HandleListPayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = db.PayoutStatusFailed
+	}
+	switch status {
+	case db.PayoutStatusPending, db.PayoutStatusSent, db.PayoutStatusConfirmed, db.PayoutStatusFailed:
+	default:
+		sendError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			sendError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	payouts, err := db.GetPayoutsByStatus(r.Context(), status, limit)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if payouts == nil {
+		payouts = []*db.PayoutRecord{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PayoutsResponse{
+		Success: true,
+		Payouts: payouts,
+		Count:   len(payouts),
+	})
+}
+
+// HandleRetryPayouts moves one or all failed payouts back to pending
+// POST /api/admin/payouts/retry
+func HandleRetryPayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req RetryPayoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ID == 0 && !req.All {
+		sendError(w, http.StatusBadRequest, "Payout id or all is required")
+		return
+	}
+
+	ids := []int64{req.ID}
+	if req.All {
+		failed, err := db.GetPayoutsByStatus(r.Context(), db.PayoutStatusFailed, 1000)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		ids = ids[:0]
+		for _, p := range failed {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	retried := []*db.PayoutRecord{}
+	for _, id := range ids {
+		p, err := db.RetryPayout(r.Context(), id)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		retried = append(retried, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PayoutsResponse{
+		Success: true,
+		Payouts: retried,
+		Count:   len(retried),
+	})
+}
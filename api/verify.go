@@ -2,10 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"goLangServer/contract"
+	"goLangServer/crypto"
 	"goLangServer/db"
+	"goLangServer/game"
+	"goLangServer/ws"
 )
 
 /* =========================
@@ -18,9 +25,19 @@ type VerifyGameResponse struct {
 	GameID             string                 `json:"gameId"`
 	ServerSeed         string                 `json:"serverSeed"`
 	ServerSeedHash     string                 `json:"serverSeedHash"`
+	ClientSeed         string                 `json:"clientSeed,omitempty"` // Round seed is HMAC(serverSeed, clientSeed:chainRound) when set
 	Peak               float64                `json:"peak"`
 	Rugged             bool                   `json:"rugged"`
+	RNGVersion         int                    `json:"rngVersion"`
+	Distribution       game.CrashDistribution `json:"distribution"`
+	CandleLayout       game.CandleLayout      `json:"candleLayout"`
+	ChainRound         uint64                 `json:"chainRound,omitempty"`
+	TerminatingHash    string                 `json:"terminatingHash,omitempty"`
+	ChainVerified      bool                   `json:"chainVerified"`
+	Recovered          bool                   `json:"recovered,omitempty"`
 	CandlestickHistory interface{}            `json:"candlestickHistory"`
+	Verified           bool                   `json:"verified"`
+	VerifyError        string                 `json:"verifyError,omitempty"`
 	Message            string                 `json:"message,omitempty"`
 }
 
@@ -30,12 +47,17 @@ type VerifyGameResponse struct {
 
 // HandleVerifyGame handles game verification requests
 // GET /api/verify/:gameId
+// GET /api/verify/:gameId/ticks
 func HandleVerifyGame(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Extract game ID from URL path
 	// Expected format: /api/verify/{gameId}
 	gameID := r.URL.Path[len("/api/verify/"):]
+	if id, ok := strings.CutSuffix(gameID, "/ticks"); ok {
+		handleVerifyTicks(w, r, id)
+		return
+	}
 	if gameID == "" {
 		sendError(w, http.StatusBadRequest, "Game ID is required")
 		return
@@ -54,22 +76,148 @@ func HandleVerifyGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Replay the round from its seed and check it against the stored result
+	roundSeed := crypto.CrashRoundSeed(history.ServerSeed, history.ClientSeed, history.ChainRound)
+	verifyErr := game.VerifyCrashHistory(history.RNGVersion, history.Distribution, history.CandleLayout, roundSeed, history.GameID, history.Peak, history.Rugged, history.CandlestickHistory)
+
 	// Send response with provably fair data
 	response := VerifyGameResponse{
 		Success:            true,
 		GameID:             history.GameID,
 		ServerSeed:         history.ServerSeed,
 		ServerSeedHash:     history.ServerSeedHash,
+		ClientSeed:         history.ClientSeed,
 		Peak:               history.Peak,
 		Rugged:             history.Rugged,
+		RNGVersion:         history.RNGVersion,
+		Distribution:       history.Distribution.Resolved(),
+		CandleLayout:       history.CandleLayout.Resolved(),
+		Recovered:          history.Recovered,
 		CandlestickHistory: history.CandlestickHistory,
+		Verified:           verifyErr == nil,
 		Message:            "Game data retrieved successfully. Verify by hashing the serverSeed and comparing with serverSeedHash.",
 	}
 
+	if verifyErr != nil {
+		response.VerifyError = verifyErr.Error()
+	}
+
+	// Prove the seed was pre-committed: it must hash to the seed of the round
+	// before it (round 1 to the terminating hash), so the rounds link back to
+	// the published commitment one hash at a time
+	if history.ChainRound > 0 {
+		response.ChainRound = history.ChainRound
+		response.TerminatingHash = history.TerminatingHash
+
+		var previousSeed string
+		if history.ChainRound > 1 {
+			previousSeed, err = db.GetChainRoundSeed(ctx, history.TerminatingHash, history.ChainRound-1)
+			if err != nil {
+				log.Printf("❌ %v", err)
+			}
+		}
+		response.ChainVerified = crypto.VerifyChainLink(history.ServerSeed, history.ChainRound, previousSeed, history.TerminatingHash)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 
-	log.Printf("🔍 Game verification - Game: %s", gameID)
+	log.Printf("🔍 Game verification - Game: %s, Verified: %v", gameID, response.Verified)
+}
+
+// handleVerifyTicks replays a round from its revealed seed and returns every
+// tick price, so players can check the exact multiplier they cashed out at.
+// ?format=json (default), csv or binary (see game.TickSeries.MarshalBinary).
+func handleVerifyTicks(w http.ResponseWriter, r *http.Request, gameID string) {
+	if gameID == "" {
+		sendError(w, http.StatusBadRequest, "Game ID is required")
+		return
+	}
+
+	history, err := db.GetCrashHistory(r.Context(), gameID)
+	if err != nil {
+		log.Printf("❌ Failed to get crash history: %v", err)
+		sendError(w, http.StatusInternalServerError, "Failed to retrieve game history")
+		return
+	}
+	if history == nil {
+		sendError(w, http.StatusNotFound, "Game not found")
+		return
+	}
+
+	roundSeed := crypto.CrashRoundSeed(history.ServerSeed, history.ClientSeed, history.ChainRound)
+	series := game.ReplayTickSeries(history.RNGVersion, history.Distribution, roundSeed, history.GameID)
+
+	// The stored candles must be exactly what the replayed ticks produce
+	consistencyErr := game.VerifyCrashHistory(history.RNGVersion, history.Distribution, history.CandleLayout, roundSeed, history.GameID, history.Peak, history.Rugged, history.CandlestickHistory)
+	if consistencyErr != nil {
+		log.Printf("⚠️ Stored candles of game %s do not match replay: %v", gameID, consistencyErr)
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		response := map[string]interface{}{
+			"success":           true,
+			"gameId":            history.GameID,
+			"serverSeed":        history.ServerSeed,
+			"serverSeedHash":    history.ServerSeedHash,
+			"clientSeed":        history.ClientSeed,
+			"rngVersion":        series.RNGVersion,
+			"distribution":      history.Distribution.Resolved(),
+			"candleLayout":      history.CandleLayout.Resolved(),
+			"peak":              series.Peak,
+			"rugged":            series.Rugged,
+			"totalTicks":        len(series.Prices),
+			"ticks":             series.Ticks(),
+			"candlesConsistent": consistencyErr == nil,
+		}
+		if consistencyErr != nil {
+			response["consistencyError"] = consistencyErr.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "crash-"+history.GameID+"-ticks.csv"))
+		w.Header().Set("X-Candles-Consistent", strconv.FormatBool(consistencyErr == nil))
+		if err := series.WriteCSV(w); err != nil {
+			log.Printf("❌ Failed to write tick CSV: %v", err)
+		}
+
+	case "binary":
+		data, err := series.MarshalBinary()
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("X-Candles-Consistent", strconv.FormatBool(consistencyErr == nil))
+		w.Write(data)
+
+	default:
+		sendError(w, http.StatusBadRequest, "Format must be json, csv or binary")
+		return
+	}
+
+	log.Printf("🔍 Tick replay - Game: %s, Ticks: %d, Consistent: %v", gameID, len(series.Prices), consistencyErr == nil)
+}
+
+// HandleGetSeedChain returns the commitment of the active crash seed chain
+// GET /api/crash/chain
+func HandleGetSeedChain(w http.ResponseWriter, r *http.Request) {
+	info, ok := ws.GetSeedChainInfo()
+	if !ok {
+		sendError(w, http.StatusServiceUnavailable, "Seed chain not initialized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"chain":   info,
+		"message": "Round N uses a seed whose SHA-256 equals the seed of round N-1, and round 1's equals terminatingHash.",
+	})
 }
 
 // HandleHealthCheck handles health check requests
@@ -89,10 +237,17 @@ func HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 		postgresHealth = "error: " + err.Error()
 	}
 
+	// Check the contract client
+	var contractHealth interface{} = "not initialized"
+	if contract.GameHouse != nil {
+		contractHealth = contract.GameHouse.HealthCheck(ctx)
+	}
+
 	response := map[string]interface{}{
 		"success":  true,
 		"redis":    redisHealth,
 		"postgres": postgresHealth,
+		"contract": contractHealth,
 		"message":  "Health check completed",
 	}
 
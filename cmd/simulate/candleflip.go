@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"goLangServer/game"
+)
+
+// candleflipPartial is one worker's share of a candleflip simulation
+type candleflipPartial struct {
+	rooms      int64
+	bull       int64
+	finalPrice stat
+	finals     *histogram
+}
+
+func newCandleflipPartial() *candleflipPartial {
+	return &candleflipPartial{
+		finals: newHistogram("%.2f", 0, 0.5, 0.9, 1.0, 1.1, 1.5, 2.0),
+	}
+}
+
+func (p *candleflipPartial) merge(o *candleflipPartial) {
+	p.rooms += o.rooms
+	p.bull += o.bull
+	p.finalPrice.merge(o.finalPrice)
+	p.finals.merge(o.finals)
+}
+
+// simulateCandleflip plays rooms [start, end) and accumulates them into p
+func simulateCandleflip(opts options, p *candleflipPartial, start, end int, done func()) {
+	for i := start; i < end; i++ {
+		room := game.PlayCandleflipRoom(opts.rngVersion, opts.priceModel, fmt.Sprintf("%s-room-%d", opts.seed, i))
+
+		p.rooms++
+		if room.Winner == "bull" {
+			p.bull++
+		}
+		p.finalPrice.add(room.FinalPrice)
+		p.finals.add(room.FinalPrice)
+
+		done()
+	}
+}
+
+/* =========================
+   REPORT
+========================= */
+
+// CandleflipReport summarizes a candleflip simulation
+type CandleflipReport struct {
+	Rooms          int64             `json:"rooms"`
+	PriceModel     int               `json:"priceModel"`
+	Payout         float64           `json:"payout"` // Multiplier paid on a won room
+	Bull           int64             `json:"bull"`
+	Bear           int64             `json:"bear"`
+	BullShare      float64           `json:"bullShare"`
+	BullCILow      float64           `json:"bullCiLow"`
+	BullCIHigh     float64           `json:"bullCiHigh"`
+	ChiSquare      float64           `json:"chiSquare"` // Against a 50/50 split, 1 degree of freedom
+	PValue         float64           `json:"pValue"`
+	BullRTP        float64           `json:"bullRtp"`
+	BearRTP        float64           `json:"bearRtp"`
+	MeanFinalPrice float64           `json:"meanFinalPrice"`
+	FinalPriceCI   [2]float64        `json:"finalPriceCi"`
+	FinalStdDev    float64           `json:"finalPriceStdDev"`
+	FinalHistogram []HistogramBucket `json:"finalPriceHistogram"`
+}
+
+func (p *candleflipPartial) report(priceModel int, payout float64) *CandleflipReport {
+	bear := p.rooms - p.bull
+	lo, hi := proportionCI95(p.bull, p.rooms)
+	priceLo, priceHi := p.finalPrice.ci95()
+
+	// Chi-square of the bull/bear counts against an even split
+	expected := float64(p.rooms) / 2
+	chi := 0.0
+	if expected > 0 {
+		chi = (math.Pow(float64(p.bull)-expected, 2) + math.Pow(float64(bear)-expected, 2)) / expected
+	}
+
+	share := ratio(p.bull, p.rooms)
+	return &CandleflipReport{
+		Rooms:          p.rooms,
+		PriceModel:     priceModel,
+		Payout:         payout,
+		Bull:           p.bull,
+		Bear:           bear,
+		BullShare:      share,
+		BullCILow:      lo,
+		BullCIHigh:     hi,
+		ChiSquare:      chi,
+		PValue:         math.Erfc(math.Sqrt(chi / 2)),
+		BullRTP:        payout * share,
+		BearRTP:        payout * (1 - share),
+		MeanFinalPrice: p.finalPrice.mean(),
+		FinalPriceCI:   [2]float64{priceLo, priceHi},
+		FinalStdDev:    p.finalPrice.stdDev(),
+		FinalHistogram: p.finals.buckets(p.rooms),
+	}
+}
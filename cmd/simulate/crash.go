@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+
+	"goLangServer/game"
+)
+
+// crashStrategy is a way of playing a crash round with a bet placed during
+// the countdown (entry 1.0x)
+type crashStrategy struct {
+	kind   string  // "auto" cashes out at target, "hold" after a number of ticks
+	target float64 // Auto-cashout multiplier
+	ticks  int     // Tick to cash out at for hold strategies
+}
+
+func (s crashStrategy) name() string {
+	if s.kind == "auto" {
+		return fmt.Sprintf("auto %.2fx", s.target)
+	}
+	return fmt.Sprintf("hold %d ticks", s.ticks)
+}
+
+// payout returns what a 1 unit bet returns in a round with the given prices
+func (s crashStrategy) payout(prices []float64) float64 {
+	if s.kind == "auto" {
+		for _, price := range prices {
+			if price >= s.target {
+				return s.target
+			}
+		}
+		return 0
+	}
+	if s.ticks < len(prices) {
+		return prices[s.ticks]
+	}
+	return 0
+}
+
+// crashPartial is one worker's share of a crash simulation
+type crashPartial struct {
+	rounds     int64
+	busts      int64
+	rugged     int64
+	maxTicks   int64
+	peak       stat
+	ticks      stat
+	strategies []stat
+	peaks      *histogram
+	tickCounts *histogram
+}
+
+func newCrashPartial(strategies int) *crashPartial {
+	return &crashPartial{
+		strategies: make([]stat, strategies),
+		peaks:      newHistogram("%.2fx", 1, 1.01, 1.5, 2, 3, 5, 10, 20, 50, 100, 200, 500, 1000),
+		tickCounts: newHistogram("%.0f", 0, 1, 10, 25, 50, 100, 200, 500, 1000, 2500, game.MaxTicks),
+	}
+}
+
+func (p *crashPartial) merge(o *crashPartial) {
+	p.rounds += o.rounds
+	p.busts += o.busts
+	p.rugged += o.rugged
+	p.maxTicks += o.maxTicks
+	p.peak.merge(o.peak)
+	p.ticks.merge(o.ticks)
+	for i := range p.strategies {
+		p.strategies[i].merge(o.strategies[i])
+	}
+	p.peaks.merge(o.peaks)
+	p.tickCounts.merge(o.tickCounts)
+}
+
+// simulateCrash plays rounds [start, end) and accumulates them into p
+func simulateCrash(opts options, strategies []crashStrategy, p *crashPartial, start, end int, done func()) {
+	prices := make([]float64, 0, 1024)
+	for i := start; i < end; i++ {
+		sim := game.NewCrashSimulator(opts.rngVersion, opts.distribution, fmt.Sprintf("%s-%d", opts.seed, i), "simulate")
+
+		prices = prices[:0]
+		for {
+			t, ok := sim.Next()
+			if !ok {
+				break
+			}
+			prices = append(prices, t.Price)
+		}
+		result := sim.Result()
+
+		p.rounds++
+		switch {
+		case sim.Bust():
+			p.busts++
+			p.rugged++
+		case result.Rugged:
+			p.rugged++
+		default:
+			p.maxTicks++
+		}
+		p.peak.add(result.PeakMultiplier)
+		p.ticks.add(float64(result.TotalTicks))
+		p.peaks.add(result.PeakMultiplier)
+		p.tickCounts.add(float64(result.TotalTicks))
+
+		for j, s := range strategies {
+			p.strategies[j].add(s.payout(prices))
+		}
+
+		done()
+	}
+}
+
+/* =========================
+   REPORT
+========================= */
+
+// CrashReport summarizes a crash simulation
+type CrashReport struct {
+	Rounds        int64                  `json:"rounds"`
+	Distribution  game.CrashDistribution `json:"distribution"`
+	InstantBusts  int64                  `json:"instantBusts"`
+	BustRate      float64                `json:"instantBustRate"`
+	ExpectedBusts float64                `json:"expectedInstantBustRate"`
+	Rugged        int64                  `json:"rugged"`
+	RugRate       float64                `json:"rugRate"`
+	MaxTickRounds int64                  `json:"maxTickRounds"` // Rounds that ended at game.MaxTicks without rugging
+	MeanPeak      float64                `json:"meanPeak"`
+	MeanTicks     float64                `json:"meanTicks"`
+	Strategies    []StrategyReport       `json:"strategies"`
+	PeakHistogram []HistogramBucket      `json:"peakHistogram"`
+	TickHistogram []HistogramBucket      `json:"tickHistogram"`
+}
+
+// StrategyReport is the return of a cashout strategy per unit bet
+type StrategyReport struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Target      float64  `json:"target,omitempty"`
+	Ticks       int      `json:"ticks,omitempty"`
+	RTP         float64  `json:"rtp"`
+	CILow       float64  `json:"ciLow"`
+	CIHigh      float64  `json:"ciHigh"`
+	StdDev      float64  `json:"stdDev"`
+	ExpectedRTP *float64 `json:"expectedRtp,omitempty"` // From the distribution, for auto strategies
+}
+
+func (p *crashPartial) report(dist game.CrashDistribution, strategies []crashStrategy) *CrashReport {
+	r := &CrashReport{
+		Rounds:        p.rounds,
+		Distribution:  dist,
+		InstantBusts:  p.busts,
+		BustRate:      ratio(p.busts, p.rounds),
+		ExpectedBusts: dist.InstantBust(),
+		Rugged:        p.rugged,
+		RugRate:       ratio(p.rugged, p.rounds),
+		MaxTickRounds: p.maxTicks,
+		MeanPeak:      p.peak.mean(),
+		MeanTicks:     p.ticks.mean(),
+		PeakHistogram: p.peaks.buckets(p.rounds),
+		TickHistogram: p.tickCounts.buckets(p.rounds),
+	}
+
+	for i, s := range strategies {
+		st := p.strategies[i]
+		lo, hi := st.ci95()
+		sr := StrategyReport{
+			Name:   s.name(),
+			Kind:   s.kind,
+			Target: s.target,
+			Ticks:  s.ticks,
+			RTP:    st.mean(),
+			CILow:  lo,
+			CIHigh: hi,
+			StdDev: st.stdDev(),
+		}
+		if s.kind == "auto" {
+			expected := dist.RTP(s.target)
+			sr.ExpectedRTP = &expected
+		}
+		r.Strategies = append(r.Strategies, sr)
+	}
+	return r
+}
+
+func ratio(k, n int64) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(k) / float64(n)
+}
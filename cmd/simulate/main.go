@@ -0,0 +1,277 @@
+// Command simulate plays large numbers of crash rounds and candleflip rooms
+// with the live game code and reports their RTP and volatility, so changes to
+// game constants can be evaluated before they ship.
+//
+//	simulate [-game crash|candleflip|both] [-rounds 1000000] [-rooms 1000000]
+//	         [-distribution <json>] [-targets 1.5,2,10] [-hold 10,50]
+//	         [-format text|json|csv] [-out report.json]
+//
+// Rounds are seeded "<seed>-<i>", so a run is reproducible from its flags.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goLangServer/game"
+)
+
+// options are the parsed command line flags
+type options struct {
+	games        string
+	rounds       int
+	rooms        int
+	workers      int
+	seed         string
+	rngVersion   int
+	distribution game.CrashDistribution
+	targets      []float64
+	holds        []int
+	priceModel   int
+	payout       float64
+	format       string
+	out          string
+}
+
+// Report is the full simulation result
+type Report struct {
+	Seed           string            `json:"seed"`
+	RNGVersion     int               `json:"rngVersion"`
+	Workers        int               `json:"workers"`
+	ElapsedSeconds float64           `json:"elapsedSeconds"`
+	Crash          *CrashReport      `json:"crash,omitempty"`
+	Candleflip     *CandleflipReport `json:"candleflip,omitempty"`
+}
+
+func main() {
+	opts := parseFlags()
+	started := time.Now()
+
+	report := &Report{
+		Seed:       opts.seed,
+		RNGVersion: opts.rngVersion,
+		Workers:    opts.workers,
+	}
+
+	if opts.games == "crash" || opts.games == "both" {
+		strategies := crashStrategies(opts)
+		partials := make([]*crashPartial, opts.workers)
+		for w := range partials {
+			partials[w] = newCrashPartial(len(strategies))
+		}
+
+		parallel("crash rounds", opts.rounds, opts.workers, func(w, start, end int, done func()) {
+			simulateCrash(opts, strategies, partials[w], start, end, done)
+		})
+
+		total := newCrashPartial(len(strategies))
+		for _, p := range partials {
+			total.merge(p)
+		}
+		report.Crash = total.report(opts.distribution, strategies)
+	}
+
+	if opts.games == "candleflip" || opts.games == "both" {
+		partials := make([]*candleflipPartial, opts.workers)
+		for w := range partials {
+			partials[w] = newCandleflipPartial()
+		}
+
+		parallel("candleflip rooms", opts.rooms, opts.workers, func(w, start, end int, done func()) {
+			simulateCandleflip(opts, partials[w], start, end, done)
+		})
+
+		total := newCandleflipPartial()
+		for _, p := range partials {
+			total.merge(p)
+		}
+		report.Candleflip = total.report(opts.priceModel, opts.payout)
+	}
+
+	report.ElapsedSeconds = time.Since(started).Seconds()
+
+	out := io.Writer(os.Stdout)
+	if opts.out != "" {
+		f, err := os.Create(opts.out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	switch opts.format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	case "csv":
+		err = writeCSV(out, report)
+	default:
+		err = writeText(out, report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags() options {
+	var opts options
+	var distribution, targets, holds string
+
+	flag.StringVar(&opts.games, "game", "both", "game to simulate: crash, candleflip or both")
+	flag.IntVar(&opts.rounds, "rounds", 1000000, "number of crash rounds")
+	flag.IntVar(&opts.rooms, "rooms", 1000000, "number of candleflip rooms")
+	flag.IntVar(&opts.workers, "workers", runtime.NumCPU(), "number of simulation goroutines")
+	flag.StringVar(&opts.seed, "seed", "simulate", "prefix of the per-round seeds")
+	flag.IntVar(&opts.rngVersion, "rng", game.CurrentRNGVersion, "RNG version to simulate")
+	flag.StringVar(&distribution, "distribution", "", "crash distribution as JSON, \"legacy\" or \"curve\" (default: current)")
+	flag.StringVar(&targets, "targets", "1.1,1.5,2,3,5,10,20,50,100", "auto-cashout targets to evaluate, comma separated")
+	flag.StringVar(&holds, "hold", "5,10,25,50,100", "manual cashouts after a number of ticks to evaluate, comma separated")
+	flag.IntVar(&opts.priceModel, "price-model", game.CurrentCandleflipPriceModel, "candleflip price model (1 = linear, 2 = symmetric)")
+	flag.Float64Var(&opts.payout, "payout", 2, "candleflip payout multiplier of a won room")
+	flag.StringVar(&opts.format, "format", "text", "report format: text, json or csv")
+	flag.StringVar(&opts.out, "out", "", "write the report to a file instead of stdout")
+	flag.Parse()
+
+	switch {
+	case opts.games != "crash" && opts.games != "candleflip" && opts.games != "both":
+		exitUsage("-game must be crash, candleflip or both")
+	case opts.rounds < 1 || opts.rooms < 1:
+		exitUsage("-rounds and -rooms must be positive")
+	case opts.workers < 1:
+		exitUsage("-workers must be positive")
+	case opts.priceModel != game.CandleflipPriceModelLinear && opts.priceModel != game.CandleflipPriceModelSymmetric:
+		exitUsage("-price-model must be %d or %d", game.CandleflipPriceModelLinear, game.CandleflipPriceModelSymmetric)
+	case opts.format != "text" && opts.format != "json" && opts.format != "csv":
+		exitUsage("-format must be text, json or csv")
+	}
+
+	var err error
+	if opts.distribution, err = parseDistribution(distribution); err != nil {
+		exitUsage("-distribution: %v", err)
+	}
+	if opts.targets, err = parseList(targets, func(s string) (float64, error) {
+		v, err := strconv.ParseFloat(s, 64)
+		if err == nil && v <= game.StartingPrice {
+			err = fmt.Errorf("target %v must be above %v", v, game.StartingPrice)
+		}
+		return v, err
+	}); err != nil {
+		exitUsage("-targets: %v", err)
+	}
+	if opts.holds, err = parseList(holds, func(s string) (int, error) {
+		v, err := strconv.Atoi(s)
+		if err == nil && (v < 0 || v >= game.MaxTicks) {
+			err = fmt.Errorf("tick %d out of range", v)
+		}
+		return v, err
+	}); err != nil {
+		exitUsage("-hold: %v", err)
+	}
+
+	return opts
+}
+
+func exitUsage(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n\n", args...)
+	flag.Usage()
+	os.Exit(2)
+}
+
+// parseDistribution reads the -distribution flag
+func parseDistribution(value string) (game.CrashDistribution, error) {
+	switch value {
+	case "":
+		return game.DefaultCrashDistribution, nil
+	case "legacy":
+		return game.LegacyCrashDistribution, nil
+	case "curve":
+		return game.CurveCrashDistribution, nil
+	}
+
+	var dist game.CrashDistribution
+	if err := json.Unmarshal([]byte(value), &dist); err != nil {
+		return dist, err
+	}
+	dist = dist.Resolved()
+	return dist, dist.Validate()
+}
+
+// parseList parses a comma separated flag value
+func parseList[T any](value string, parse func(string) (T, error)) ([]T, error) {
+	var list []T
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		v, err := parse(field)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func crashStrategies(opts options) []crashStrategy {
+	var strategies []crashStrategy
+	for _, target := range opts.targets {
+		strategies = append(strategies, crashStrategy{kind: "auto", target: target})
+	}
+	for _, ticks := range opts.holds {
+		strategies = append(strategies, crashStrategy{kind: "hold", ticks: ticks})
+	}
+	return strategies
+}
+
+// parallel splits [0, n) into one contiguous range per worker and runs them
+// concurrently, printing progress to stderr
+func parallel(what string, n, workers int, run func(w, start, end int, done func())) {
+	var completed int64
+	done := func() { atomic.AddInt64(&completed, 1) }
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c := atomic.LoadInt64(&completed)
+				fmt.Fprintf(os.Stderr, "⏳ %s: %d/%d (%.0f%%)\n", what, c, n, 100*float64(c)/float64(n))
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start, end := n*w/workers, n*(w+1)/workers
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			run(w, start, end, done)
+		}(w, start, end)
+	}
+	wg.Wait()
+	close(stop)
+
+	elapsed := time.Since(started)
+	fmt.Fprintf(os.Stderr, "✅ Simulated %d %s in %s (%.0f/s)\n", n, what, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds())
+}
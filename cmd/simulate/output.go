@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"goLangServer/game"
+)
+
+/* =========================
+   TEXT
+========================= */
+
+func writeText(w io.Writer, r *Report) error {
+	bw := bufio.NewWriter(w)
+	p := func(format string, args ...interface{}) { fmt.Fprintf(bw, format+"\n", args...) }
+
+	if c := r.Crash; c != nil {
+		p("🎰 CRASH - %d rounds (%s model)", c.Rounds, c.Distribution.Model)
+		p("   Instant busts: %.4f%% (expected %.4f%%)", c.BustRate*100, c.ExpectedBusts*100)
+		p("   Rugged:        %.4f%%, %d rounds hit %d ticks", c.RugRate*100, c.MaxTickRounds, game.MaxTicks)
+		p("   Mean peak:     %.4fx, mean ticks %.1f", c.MeanPeak, c.MeanTicks)
+		p("")
+		p("   %-16s %9s %21s %9s %9s", "strategy", "RTP", "95% CI", "std dev", "expected")
+		for _, s := range c.Strategies {
+			expected := ""
+			if s.ExpectedRTP != nil {
+				expected = fmt.Sprintf("%.4f", *s.ExpectedRTP)
+			}
+			p("   %-16s %9.4f  [%8.4f, %8.4f] %9.4f %9s", s.Name, s.RTP, s.CILow, s.CIHigh, s.StdDev, expected)
+		}
+		p("")
+		writeTextHistogram(p, "Peak", c.PeakHistogram)
+		writeTextHistogram(p, "Ticks", c.TickHistogram)
+	}
+
+	if c := r.Candleflip; c != nil {
+		p("🕯️ CANDLEFLIP - %d rooms (price model %d)", c.Rooms, c.PriceModel)
+		p("   Bull: %d (%.4f%%, 95%% CI %.4f%%-%.4f%%)", c.Bull, c.BullShare*100, c.BullCILow*100, c.BullCIHigh*100)
+		p("   Bear: %d (%.4f%%)", c.Bear, (1-c.BullShare)*100)
+		p("   Chi-square vs 50/50: %.3f (p = %.4f)", c.ChiSquare, c.PValue)
+		p("   RTP at %.2fx: bull %.4f, bear %.4f", c.Payout, c.BullRTP, c.BearRTP)
+		p("   Mean final price: %.5f (95%% CI %.5f-%.5f, std dev %.4f)", c.MeanFinalPrice, c.FinalPriceCI[0], c.FinalPriceCI[1], c.FinalStdDev)
+		p("")
+		writeTextHistogram(p, "Final price", c.FinalHistogram)
+	}
+
+	p("⏱️ %.1fs with %d workers, seed %q, RNG v%d", r.ElapsedSeconds, r.Workers, r.Seed, r.RNGVersion)
+	return bw.Flush()
+}
+
+func writeTextHistogram(p func(string, ...interface{}), title string, buckets []HistogramBucket) {
+	p("   %s histogram:", title)
+	for _, b := range buckets {
+		p("   %18s %10d %8.4f%%", b.Label, b.Count, b.Share*100)
+	}
+	p("")
+}
+
+/* =========================
+   CSV
+========================= */
+
+// writeCSV writes the report in long form: one row per value, with the
+// confidence interval where the value has one
+func writeCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"game", "metric", "key", "value", "ci_low", "ci_high"})
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	i := func(v int64) string { return strconv.FormatInt(v, 10) }
+	row := func(gameName, metric, key, value string, ci ...float64) {
+		record := []string{gameName, metric, key, value, "", ""}
+		if len(ci) == 2 {
+			record[4], record[5] = f(ci[0]), f(ci[1])
+		}
+		cw.Write(record)
+	}
+	histogram := func(gameName, metric string, buckets []HistogramBucket) {
+		for _, b := range buckets {
+			row(gameName, metric, b.Label, i(b.Count))
+		}
+	}
+
+	if c := r.Crash; c != nil {
+		row("crash", "rounds", "", i(c.Rounds))
+		row("crash", "instant_bust_rate", "", f(c.BustRate))
+		row("crash", "expected_instant_bust_rate", "", f(c.ExpectedBusts))
+		row("crash", "rug_rate", "", f(c.RugRate))
+		row("crash", "max_tick_rounds", "", i(c.MaxTickRounds))
+		row("crash", "mean_peak", "", f(c.MeanPeak))
+		row("crash", "mean_ticks", "", f(c.MeanTicks))
+		for _, s := range c.Strategies {
+			row("crash", "rtp", s.Name, f(s.RTP), s.CILow, s.CIHigh)
+			row("crash", "std_dev", s.Name, f(s.StdDev))
+			if s.ExpectedRTP != nil {
+				row("crash", "expected_rtp", s.Name, f(*s.ExpectedRTP))
+			}
+		}
+		histogram("crash", "peak_histogram", c.PeakHistogram)
+		histogram("crash", "tick_histogram", c.TickHistogram)
+	}
+
+	if c := r.Candleflip; c != nil {
+		row("candleflip", "rooms", "", i(c.Rooms))
+		row("candleflip", "price_model", "", strconv.Itoa(c.PriceModel))
+		row("candleflip", "bull", "", i(c.Bull))
+		row("candleflip", "bear", "", i(c.Bear))
+		row("candleflip", "bull_share", "", f(c.BullShare), c.BullCILow, c.BullCIHigh)
+		row("candleflip", "chi_square", "", f(c.ChiSquare))
+		row("candleflip", "p_value", "", f(c.PValue))
+		row("candleflip", "rtp", "bull", f(c.BullRTP))
+		row("candleflip", "rtp", "bear", f(c.BearRTP))
+		row("candleflip", "mean_final_price", "", f(c.MeanFinalPrice), c.FinalPriceCI[0], c.FinalPriceCI[1])
+		row("candleflip", "final_price_std_dev", "", f(c.FinalStdDev))
+		histogram("candleflip", "final_price_histogram", c.FinalHistogram)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// z95 is the normal quantile for two-sided 95% confidence intervals
+const z95 = 1.959963984540054
+
+/* =========================
+   RUNNING STATISTICS
+========================= */
+
+// stat accumulates the mean and variance of a sample
+type stat struct {
+	n     int64
+	sum   float64
+	sumSq float64
+}
+
+func (s *stat) add(x float64) {
+	s.n++
+	s.sum += x
+	s.sumSq += x * x
+}
+
+func (s *stat) merge(o stat) {
+	s.n += o.n
+	s.sum += o.sum
+	s.sumSq += o.sumSq
+}
+
+func (s stat) mean() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	return s.sum / float64(s.n)
+}
+
+func (s stat) stdDev() float64 {
+	if s.n < 2 {
+		return 0
+	}
+	mean := s.mean()
+	variance := (s.sumSq - float64(s.n)*mean*mean) / float64(s.n-1)
+	return math.Sqrt(math.Max(variance, 0))
+}
+
+// ci95 returns the 95% confidence interval of the mean
+func (s stat) ci95() (lo, hi float64) {
+	if s.n == 0 {
+		return 0, 0
+	}
+	half := z95 * s.stdDev() / math.Sqrt(float64(s.n))
+	return s.mean() - half, s.mean() + half
+}
+
+// proportionCI95 returns the Wilson score interval of k successes in n trials
+func proportionCI95(k, n int64) (lo, hi float64) {
+	if n == 0 {
+		return 0, 0
+	}
+	p := float64(k) / float64(n)
+	nf := float64(n)
+	z2 := z95 * z95
+	center := (p + z2/(2*nf)) / (1 + z2/nf)
+	half := z95 * math.Sqrt(p*(1-p)/nf+z2/(4*nf*nf)) / (1 + z2/nf)
+	return center - half, center + half
+}
+
+/* =========================
+   HISTOGRAMS
+========================= */
+
+// histogram counts values in buckets [edges[i], edges[i+1]); the last
+// bucket is unbounded
+type histogram struct {
+	edges  []float64
+	counts []int64
+	format string // Label format of an edge
+}
+
+func newHistogram(format string, edges ...float64) *histogram {
+	return &histogram{edges: edges, counts: make([]int64, len(edges)), format: format}
+}
+
+func (h *histogram) add(x float64) {
+	for i := len(h.edges) - 1; i >= 0; i-- {
+		if x >= h.edges[i] {
+			h.counts[i]++
+			return
+		}
+	}
+	h.counts[0]++ // Below the first edge
+}
+
+func (h *histogram) merge(o *histogram) {
+	for i := range h.counts {
+		h.counts[i] += o.counts[i]
+	}
+}
+
+// HistogramBucket is one bucket of a report histogram
+type HistogramBucket struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"` // nil for the unbounded last bucket
+	Count int64    `json:"count"`
+	Share float64  `json:"share"`
+}
+
+func (h *histogram) buckets(total int64) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(h.edges))
+	for i, lo := range h.edges {
+		b := HistogramBucket{
+			Label: fmt.Sprintf(">= "+h.format, lo),
+			Min:   lo,
+			Count: h.counts[i],
+		}
+		if i+1 < len(h.edges) {
+			hi := h.edges[i+1]
+			b.Max = &hi
+			b.Label = fmt.Sprintf(h.format+"-"+h.format, lo, hi)
+		}
+		if total > 0 {
+			b.Share = float64(h.counts[i]) / float64(total)
+		}
+		buckets[i] = b
+	}
+	return buckets
+}
@@ -0,0 +1,337 @@
+// Command verify recomputes crash rounds and candleflip batches offline from
+// their revealed seeds, so players and auditors can check results without
+// trusting the server.
+//
+//	verify crash -seed <serverSeed> -game <gameId> [-hash <seedHash>] [-distribution <json>] [-peak 2.5] [-rugged true]
+//	verify candleflip -seed <serverSeed> -rooms 10 -side bull [-client <clientSeed> -nonce 0] [-model 2] [-won 6]
+//
+// It exits with status 1 if any check fails or nothing was checked, and 2 on
+// invalid usage.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"goLangServer/crypto"
+	"goLangServer/game"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var r *report
+	switch os.Args[1] {
+	case "crash":
+		r = verifyCrash(os.Args[2:])
+	case "candleflip":
+		r = verifyCandleflip(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown game %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if !r.print() {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  verify crash -seed <serverSeed> -game <gameId> [flags]")
+	fmt.Fprintln(os.Stderr, "  verify candleflip -seed <serverSeed> -rooms <n> -side bull|bear [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Run 'verify crash -h' or 'verify candleflip -h' for the flags of each game.")
+}
+
+/* =========================
+   REPORT
+========================= */
+
+// report collects the facts and checks printed for a verification
+type report struct {
+	title   string
+	lines   []string
+	checked int
+	failed  int
+}
+
+func (r *report) info(format string, args ...interface{}) {
+	r.lines = append(r.lines, strings.TrimRight("   "+fmt.Sprintf(format, args...), " "))
+}
+
+// check records a comparison between a recomputed and a claimed value
+func (r *report) check(name string, ok bool, format string, args ...interface{}) {
+	r.checked++
+	mark := "✅"
+	if !ok {
+		mark = "❌"
+		r.failed++
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %s: %s", mark, name, fmt.Sprintf(format, args...)))
+}
+
+// print writes the report and returns whether every check passed.
+// A report without checks fails, so a run without claims is never mistaken
+// for a verified one.
+func (r *report) print() bool {
+	fmt.Println("🔍 " + r.title)
+	for _, line := range r.lines {
+		fmt.Println(line)
+	}
+	fmt.Println()
+	if r.failed > 0 {
+		fmt.Printf("❌ %d check(s) FAILED\n", r.failed)
+		return false
+	}
+	if r.checked == 0 {
+		fmt.Println("⚠️  Nothing was checked: pass the published hash or the claimed results to compare against")
+		return false
+	}
+	fmt.Println("✅ All checks passed")
+	return true
+}
+
+// exitUsage reports an invalid flag combination
+func exitUsage(fs *flag.FlagSet, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n\n", args...)
+	fs.Usage()
+	os.Exit(2)
+}
+
+/* =========================
+   CRASH
+========================= */
+
+func verifyCrash(args []string) *report {
+	fs := flag.NewFlagSet("crash", flag.ExitOnError)
+	seed := fs.String("seed", "", "revealed server seed (required)")
+	gameID := fs.String("game", "", "round ID the seed was combined with, e.g. 20260101-120000.000 (required)")
+	hash := fs.String("hash", "", "server seed hash published before the round")
+	clientSeed := fs.String("client", "", "combined client seed of the round's players (omit for rounds played before client seeds)")
+	rngVersion := fs.Int("rng", game.CurrentRNGVersion, "RNG version the round was played with")
+	distribution := fs.String("distribution", "", "crash distribution of the round as JSON, or \"legacy\" for rounds stored without one (default: current)")
+	peak := fs.Float64("peak", 0, "claimed peak multiplier")
+	rugged := fs.String("rugged", "", "claimed rug outcome (true or false)")
+	chainRound := fs.Uint64("chain-round", 0, "position of the seed in the hash chain")
+	terminatingHash := fs.String("terminating-hash", "", "published terminating hash of the seed chain")
+	seriesFile := fs.String("series", "", "binary tick series downloaded from /api/verify/:gameId/ticks?format=binary")
+	showTicks := fs.Bool("ticks", false, "print every tick price")
+	fs.Parse(args)
+
+	if *seed == "" || *gameID == "" {
+		exitUsage(fs, "-seed and -game are required")
+	}
+	if (*chainRound == 0) != (*terminatingHash == "") {
+		exitUsage(fs, "-chain-round and -terminating-hash must be given together")
+	}
+	if *clientSeed != "" && *chainRound == 0 {
+		exitUsage(fs, "-client needs -chain-round, the nonce it is mixed in with")
+	}
+
+	dist, err := parseDistribution(*distribution)
+	if err != nil {
+		exitUsage(fs, "-distribution: %v", err)
+	}
+
+	r := &report{title: "Crash round " + *gameID}
+
+	roundSeed := crypto.CrashRoundSeed(*seed, *clientSeed, *chainRound)
+	series := game.ReplayTickSeries(*rngVersion, dist, roundSeed, *gameID)
+	result := game.CalculateGameVersion(*rngVersion, dist, roundSeed, *gameID)
+
+	r.info("Server seed: %s", *seed)
+	r.info("Seed hash:   %s", crypto.HashSeed(*seed))
+	if *clientSeed != "" {
+		r.info("Client seed: %s", *clientSeed)
+		r.info("Round seed:  %s", roundSeed)
+	}
+	r.info("RNG version: %d", *rngVersion)
+	r.info("Model:       %s (instant bust %.2f%%)", dist.Model, dist.InstantBust()*100)
+	r.info("Peak:        %.6fx", result.PeakMultiplier)
+	r.info("Rugged:      %v", result.Rugged)
+	r.info("Ticks:       %d", result.TotalTicks)
+	r.info("")
+
+	if *hash != "" {
+		r.check("Seed hash", crypto.VerifySeed(*seed, *hash), "SHA-256(seed) = %s", crypto.HashSeed(*seed))
+	}
+	if *chainRound > 0 {
+		r.check("Seed chain", crypto.VerifyChainSeed(*seed, *chainRound, *terminatingHash),
+			"hashing the seed %d times gives the terminating hash", *chainRound)
+	}
+	if *peak > 0 {
+		r.check("Peak", pricesMatch(result.PeakMultiplier, *peak), "recomputed %.6fx, claimed %.6fx", result.PeakMultiplier, *peak)
+	}
+	if *rugged != "" {
+		claimed, err := strconv.ParseBool(*rugged)
+		if err != nil {
+			exitUsage(fs, "-rugged must be true or false")
+		}
+		r.check("Rugged", result.Rugged == claimed, "recomputed %v, claimed %v", result.Rugged, claimed)
+	}
+	if *seriesFile != "" {
+		checkSeries(r, series, *seriesFile)
+	}
+
+	if *showTicks {
+		r.info("")
+		r.info("tick  price")
+		for i, price := range series.Prices {
+			r.info("%4d  %.6f", i, price)
+		}
+	}
+
+	return r
+}
+
+// parseDistribution reads the -distribution flag
+func parseDistribution(value string) (game.CrashDistribution, error) {
+	switch value {
+	case "":
+		return game.DefaultCrashDistribution, nil
+	case "legacy":
+		return game.LegacyCrashDistribution, nil
+	}
+
+	var dist game.CrashDistribution
+	if err := json.Unmarshal([]byte(value), &dist); err != nil {
+		return dist, err
+	}
+	dist = dist.Resolved()
+	return dist, dist.Validate()
+}
+
+// checkSeries compares a downloaded tick series against the replay
+func checkSeries(r *report, replayed game.TickSeries, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.check("Tick series", false, "failed to read %s: %v", path, err)
+		return
+	}
+
+	var claimed game.TickSeries
+	if err := claimed.UnmarshalBinary(data); err != nil {
+		r.check("Tick series", false, "%v", err)
+		return
+	}
+
+	if len(claimed.Prices) != len(replayed.Prices) {
+		r.check("Tick series", false, "recomputed %d ticks, file has %d", len(replayed.Prices), len(claimed.Prices))
+		return
+	}
+	for i := range replayed.Prices {
+		if replayed.Prices[i] != claimed.Prices[i] {
+			r.check("Tick series", false, "tick %d recomputed %.9f, file has %.9f", i, replayed.Prices[i], claimed.Prices[i])
+			return
+		}
+	}
+	r.check("Tick series", true, "all %d ticks match", len(replayed.Prices))
+}
+
+/* =========================
+   CANDLEFLIP
+========================= */
+
+func verifyCandleflip(args []string) *report {
+	fs := flag.NewFlagSet("candleflip", flag.ExitOnError)
+	seed := fs.String("seed", "", "revealed server seed (required)")
+	batchID := fs.String("batch", "", "batch ID, for the report only")
+	hash := fs.String("hash", "", "server seed hash published before the batch")
+	clientSeed := fs.String("client", "", "client seed of the seed pair (omit for legacy per-batch seeds)")
+	nonce := fs.Uint64("nonce", 0, "nonce of room 1 (room N uses nonce + N - 1)")
+	rooms := fs.Int("rooms", 0, "number of rooms in the batch (required)")
+	side := fs.String("side", "", "side the player picked, bull or bear (required)")
+	rngVersion := fs.Int("rng", game.CurrentRNGVersion, "RNG version the batch was played with")
+	priceModel := fs.Int("model", game.CurrentCandleflipPriceModel, "price model the batch was played with (1 = linear, 2 = symmetric)")
+	won := fs.Int("won", -1, "claimed number of rooms the player won")
+	winners := fs.String("winners", "", "claimed winner of each room, comma separated (e.g. bull,bear,bull)")
+	showTicks := fs.Bool("ticks", false, "print every tick price of every room")
+	fs.Parse(args)
+
+	if *seed == "" || *rooms < 1 {
+		exitUsage(fs, "-seed and -rooms are required")
+	}
+	if *side != "bull" && *side != "bear" {
+		exitUsage(fs, "-side must be bull or bear")
+	}
+	if *priceModel != game.CandleflipPriceModelLinear && *priceModel != game.CandleflipPriceModelSymmetric {
+		exitUsage(fs, "-model must be %d or %d", game.CandleflipPriceModelLinear, game.CandleflipPriceModelSymmetric)
+	}
+
+	title := "CandleFlip batch"
+	if *batchID != "" {
+		title += " " + *batchID
+	}
+	r := &report{title: title}
+
+	r.info("Server seed: %s", *seed)
+	r.info("Seed hash:   %s", crypto.HashSeed(*seed))
+	if *clientSeed != "" {
+		r.info("Client seed: %s (nonces %d-%d)", *clientSeed, *nonce, *nonce+uint64(*rooms)-1)
+	}
+	r.info("RNG version: %d", *rngVersion)
+	r.info("Price model: %d", *priceModel)
+	r.info("")
+
+	if *hash != "" {
+		r.check("Seed hash", crypto.VerifySeed(*seed, *hash), "SHA-256(seed) = %s", crypto.HashSeed(*seed))
+	}
+
+	var claimedWinners []string
+	if *winners != "" {
+		claimedWinners = strings.Split(*winners, ",")
+		if len(claimedWinners) != *rooms {
+			exitUsage(fs, "-winners lists %d rooms, batch has %d", len(claimedWinners), *rooms)
+		}
+	}
+
+	wonRooms := 0
+	for i := 0; i < *rooms; i++ {
+		room := game.PlayCandleflipRoom(*rngVersion, *priceModel, crypto.CandleflipRoomSeed(*seed, *clientSeed, *nonce, i))
+		outcome := "lost"
+		if room.Winner == *side {
+			outcome = "won"
+			wonRooms++
+		}
+
+		r.info("Room %3d: final %.6f, winner %s, player %s", i+1, room.FinalPrice, room.Winner, outcome)
+		if *showTicks {
+			for tick, price := range room.Prices {
+				r.info("          tick %2d  %.6f", tick, price)
+			}
+		}
+		if claimedWinners != nil {
+			claimed := strings.TrimSpace(claimedWinners[i])
+			r.check(fmt.Sprintf("Room %d winner", i+1), claimed == room.Winner, "recomputed %s, claimed %s", room.Winner, claimed)
+		}
+	}
+
+	r.info("")
+	r.info("Player won %d/%d rooms", wonRooms, *rooms)
+	if *won >= 0 {
+		r.check("Rooms won", wonRooms == *won, "recomputed %d, claimed %d", wonRooms, *won)
+	}
+
+	return r
+}
+
+// pricesMatch compares prices allowing for rounding in published values
+func pricesMatch(a, b float64) bool {
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= 1e-6
+}
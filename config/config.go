@@ -0,0 +1,519 @@
+package config
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"goLangServer/game"
+)
+
+// Config is the runtime configuration of the server. It is built from
+// Default, then an optional JSON file, then environment variables, and is
+// validated once on startup before any package is initialized with it.
+type Config struct {
+	Server     ServerConfig     `json:"server"`
+	Chain      ChainConfig      `json:"chain"`
+	Relayer    RelayerConfig    `json:"relayer"`
+	Payouts    PayoutConfig     `json:"payouts"`
+	Redis      RedisConfig      `json:"redis"`
+	Postgres   PostgresConfig   `json:"postgres"`
+	Crash      CrashConfig      `json:"crash"`
+	Candleflip CandleflipConfig `json:"candleflip"`
+	WebSocket  WebSocketConfig  `json:"websocket"`
+	Bets       BetConfig        `json:"bets"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Addr        string `json:"addr" env:"SERVER_ADDR"`
+	AdminAPIKey string `json:"adminApiKey" env:"ADMIN_API_KEY"` // Secret, admin endpoints are disabled when empty
+}
+
+// ChainConfig configures the RPC connection and the GameHouse contract
+type ChainConfig struct {
+	RPCURL          string `json:"rpcUrl" env:"RPC_URL"`
+	ChainID         int64  `json:"chainId" env:"CHAIN_ID"`
+	ContractAddress string `json:"contractAddress" env:"CONTRACT_ADDRESS"`
+	ABIPath         string `json:"abiPath" env:"CONTRACT_ABI_PATH"`
+	PrivateKey      string `json:"privateKey" env:"SERVER_PRIVATE_KEY"` // Secret, hex server wallet key
+}
+
+// RelayerConfig limits the gasless relayer
+type RelayerConfig struct {
+	GasLimit    uint64   `json:"gasLimit" env:"RELAYER_GAS_LIMIT"`
+	MaxGasPrice Wei      `json:"maxGasPrice" env:"RELAYER_MAX_GAS_PRICE"`
+	MinBalance  Wei      `json:"minBalance" env:"RELAYER_MIN_BALANCE"`
+	TxTimeout   Duration `json:"txTimeout" env:"RELAYER_TX_TIMEOUT"`
+}
+
+// PayoutConfig configures the payout queue worker
+type PayoutConfig struct {
+	BatchSize      int      `json:"batchSize" env:"PAYOUT_BATCH_SIZE"`
+	MaxRetries     int      `json:"maxRetries" env:"PAYOUT_MAX_RETRIES"`
+	PollInterval   Duration `json:"pollInterval" env:"PAYOUT_POLL_INTERVAL"`
+	StuckTimeout   Duration `json:"stuckTimeout" env:"PAYOUT_STUCK_TIMEOUT"`
+	GasBumpPercent int64    `json:"gasBumpPercent" env:"PAYOUT_GAS_BUMP_PERCENT"`
+	MaxGasPrice    Wei      `json:"maxGasPrice" env:"PAYOUT_MAX_GAS_PRICE"`
+}
+
+// RedisConfig configures the Redis client
+type RedisConfig struct {
+	Addr         string   `json:"addr" env:"REDIS_URL"`
+	Password     string   `json:"password" env:"REDIS_PASSWORD"` // Secret
+	DB           int      `json:"db" env:"REDIS_DB"`
+	PoolSize     int      `json:"poolSize" env:"REDIS_POOL_SIZE"`
+	MinIdleConns int      `json:"minIdleConns" env:"REDIS_MIN_IDLE_CONNS"`
+	DialTimeout  Duration `json:"dialTimeout" env:"REDIS_DIAL_TIMEOUT"`
+	ReadTimeout  Duration `json:"readTimeout" env:"REDIS_READ_TIMEOUT"`
+	WriteTimeout Duration `json:"writeTimeout" env:"REDIS_WRITE_TIMEOUT"`
+}
+
+// PostgresConfig configures the PostgreSQL pool
+type PostgresConfig struct {
+	URL             string   `json:"url" env:"DATABASE_URL"` // Secret, may embed a password
+	MaxConns        int32    `json:"maxConns" env:"POSTGRES_MAX_CONNS"`
+	MinConns        int32    `json:"minConns" env:"POSTGRES_MIN_CONNS"`
+	MaxConnLifetime Duration `json:"maxConnLifetime" env:"POSTGRES_MAX_CONN_LIFETIME"`
+}
+
+// CrashConfig configures the live crash game loop
+type CrashConfig struct {
+	TickInterval         Duration               `json:"tickInterval" env:"CRASH_TICK_INTERVAL"` // Whole milliseconds, candles are cut by tick count against it
+	CandleDuration       Duration               `json:"candleDuration" env:"CRASH_CANDLE_DURATION"`
+	CandleMergeThreshold int                    `json:"candleMergeThreshold" env:"CRASH_CANDLE_MERGE_THRESHOLD"` // Completed candles that trigger a pairwise merge
+	CountdownSeconds     int                    `json:"countdownSeconds" env:"CRASH_COUNTDOWN_SECONDS"`
+	EndWait              Duration               `json:"endWait" env:"CRASH_END_WAIT"` // Pause between a round ending and the next countdown
+	HistorySize          int                    `json:"historySize" env:"CRASH_HISTORY_SIZE"`
+	SeedChainLength      uint64                 `json:"seedChainLength" env:"CRASH_SEED_CHAIN_LENGTH"`
+	SeedChainKey         string                 `json:"seedChainKey" env:"CRASH_SEED_CHAIN_KEY"` // Secret, hex AES-256 key sealing the stored chain root
+	Distribution         game.CrashDistribution `json:"distribution" env:"CRASH_DISTRIBUTION"`   // JSON, stored with every round
+}
+
+// CandleflipConfig configures candleflip batches and their odds
+type CandleflipConfig struct {
+	PriceModel   int      `json:"priceModel" env:"CANDLEFLIP_PRICE_MODEL"`
+	TickInterval Duration `json:"tickInterval" env:"CANDLEFLIP_TICK_INTERVAL"`
+	RoomInterval Duration `json:"roomInterval" env:"CANDLEFLIP_ROOM_INTERVAL"` // Pause between rooms of a batch
+	CleanupDelay Duration `json:"cleanupDelay" env:"CANDLEFLIP_CLEANUP_DELAY"` // Time a finished batch stays in memory
+	BaseOdds     float64  `json:"baseOdds" env:"CANDLEFLIP_BASE_ODDS"`
+	MinOdds      float64  `json:"minOdds" env:"CANDLEFLIP_MIN_ODDS"`
+	ReserveGames uint64   `json:"reserveGames" env:"CANDLEFLIP_RESERVE_GAMES"`
+}
+
+// WebSocketConfig configures WebSocket connections
+type WebSocketConfig struct {
+	ReadBufferSize  int      `json:"readBufferSize" env:"WS_READ_BUFFER_SIZE"`
+	WriteBufferSize int      `json:"writeBufferSize" env:"WS_WRITE_BUFFER_SIZE"`
+	ReadDeadline    Duration `json:"readDeadline" env:"WS_READ_DEADLINE"`
+	WriteDeadline   Duration `json:"writeDeadline" env:"WS_WRITE_DEADLINE"`
+	PingInterval    Duration `json:"pingInterval" env:"WS_PING_INTERVAL"`
+	MaxMessageSize  int64    `json:"maxMessageSize" env:"WS_MAX_MESSAGE_SIZE"`
+	SendBufferSize  int      `json:"sendBufferSize" env:"WS_SEND_BUFFER_SIZE"` // Messages queued per client before it is dropped as too slow
+}
+
+// BetConfig bounds what players may wager
+type BetConfig struct {
+	MinAmount           Wei     `json:"minAmount" env:"BET_MIN_AMOUNT"`
+	MaxAmount           Wei     `json:"maxAmount" env:"BET_MAX_AMOUNT"`
+	MinRooms            int     `json:"minRooms" env:"BET_MIN_ROOMS"`
+	MaxRooms            int     `json:"maxRooms" env:"BET_MAX_ROOMS"`
+	MinMultiplier       float64 `json:"minMultiplier" env:"BET_MIN_MULTIPLIER"`
+	MaxMultiplier       float64 `json:"maxMultiplier" env:"BET_MAX_MULTIPLIER"`
+	MaxClientSeedLength int     `json:"maxClientSeedLength" env:"BET_MAX_CLIENT_SEED_LENGTH"`
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr: "0.0.0.0:8080",
+		},
+		Chain: ChainConfig{
+			RPCURL:  "https://rpc.sepolia.mantle.xyz", // Mantle Sepolia testnet
+			ChainID: 5003,
+			ABIPath: "contract/GameHouseNoSig.json",
+		},
+		Relayer: RelayerConfig{
+			GasLimit:    150000,
+			MaxGasPrice: NewWei(10000000000),       // 10 Gwei
+			MinBalance:  NewWei(50000000000000000), // 0.05 MNT
+			TxTimeout:   Duration(30 * time.Second),
+		},
+		Payouts: PayoutConfig{
+			BatchSize:      20,
+			MaxRetries:     3,
+			PollInterval:   Duration(2 * time.Second),
+			StuckTimeout:   Duration(60 * time.Second), // Rebroadcast with higher gas after this long unmined
+			GasBumpPercent: 20,
+			MaxGasPrice:    NewWei(50000000000), // 50 Gwei
+		},
+		Redis: RedisConfig{
+			Addr:         "localhost:6379",
+			PoolSize:     10,
+			MinIdleConns: 5,
+			DialTimeout:  Duration(5 * time.Second),
+			ReadTimeout:  Duration(3 * time.Second),
+			WriteTimeout: Duration(3 * time.Second),
+		},
+		Postgres: PostgresConfig{
+			MaxConns:        25,
+			MinConns:        5,
+			MaxConnLifetime: Duration(5 * time.Minute),
+		},
+		Crash: CrashConfig{
+			TickInterval:         Duration(500 * time.Millisecond),
+			CandleDuration:       Duration(time.Second),
+			CandleMergeThreshold: 25,
+			CountdownSeconds:     3,
+			EndWait:              Duration(15 * time.Second),
+			HistorySize:          10,
+			SeedChainLength:      10000000,
+			Distribution:         game.DefaultCrashDistribution,
+		},
+		Candleflip: CandleflipConfig{
+			PriceModel:   game.CurrentCandleflipPriceModel,
+			TickInterval: Duration(100 * time.Millisecond),
+			RoomInterval: Duration(500 * time.Millisecond),
+			CleanupDelay: Duration(5 * time.Second),
+			BaseOdds:     2.0,
+			MinOdds:      1.2,
+			ReserveGames: 20,
+		},
+		WebSocket: WebSocketConfig{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			ReadDeadline:    Duration(60 * time.Second),
+			WriteDeadline:   Duration(10 * time.Second),
+			PingInterval:    Duration(30 * time.Second),
+			MaxMessageSize:  512 * 1024,
+			SendBufferSize:  256,
+		},
+		Bets: BetConfig{
+			MinAmount:           NewWei(1000000000000000), // 0.001 MNT
+			MaxAmount:           Wei{MNTToWei(100)},       // 100 MNT
+			MinRooms:            1,
+			MaxRooms:            10,
+			MinMultiplier:       1.0,
+			MaxMultiplier:       1000.0,
+			MaxClientSeedLength: 64,
+		},
+	}
+}
+
+/* =========================
+   LOADING
+========================= */
+
+// Load builds the configuration from the defaults, the JSON file at path
+// (skipped when path is empty) and environment overrides, and validates it
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// applyEnv overrides every field with an env tag whose variable is set
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			name := section.Type().Field(j).Tag.Get("env")
+			if name == "" {
+				continue
+			}
+			value, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			if err := setField(section.Field(j), value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setField parses an environment value into a config field
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	default:
+		// Structured values such as the crash distribution are given as JSON
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
+
+/* =========================
+   VALIDATION
+========================= */
+
+// Validate checks that every value is usable and reports all problems at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	positive := func(name string, d Duration) {
+		check(d > 0, "%s must be positive", name)
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+
+	if u, err := url.Parse(c.Chain.RPCURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("chain.rpcUrl %q is not a valid URL", c.Chain.RPCURL))
+	}
+	check(c.Chain.ChainID > 0, "chain.chainId must be positive")
+	check(c.Chain.ContractAddress == "" || isHexAddress(c.Chain.ContractAddress),
+		"chain.contractAddress %q is not a hex address", c.Chain.ContractAddress)
+	check(c.Chain.ABIPath != "", "chain.abiPath is required")
+	if key := strings.TrimPrefix(c.Chain.PrivateKey, "0x"); key != "" {
+		_, err := hex.DecodeString(key)
+		check(err == nil && len(key) == 64, "chain.privateKey must be 32 hex-encoded bytes")
+	}
+
+	check(c.Relayer.GasLimit > 0, "relayer.gasLimit must be positive")
+	check(c.Relayer.MaxGasPrice.Sign() > 0, "relayer.maxGasPrice must be positive")
+	check(c.Relayer.MinBalance.Sign() >= 0, "relayer.minBalance must not be negative")
+	positive("relayer.txTimeout", c.Relayer.TxTimeout)
+
+	check(c.Payouts.BatchSize > 0, "payouts.batchSize must be positive")
+	check(c.Payouts.MaxRetries > 0, "payouts.maxRetries must be positive")
+	positive("payouts.pollInterval", c.Payouts.PollInterval)
+	positive("payouts.stuckTimeout", c.Payouts.StuckTimeout)
+	check(c.Payouts.GasBumpPercent >= 10, "payouts.gasBumpPercent must be at least 10 for nodes to accept a replacement")
+	check(c.Payouts.MaxGasPrice.Sign() > 0, "payouts.maxGasPrice must be positive")
+
+	check(c.Redis.Addr != "", "redis.addr is required")
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+	check(c.Redis.PoolSize > 0, "redis.poolSize must be positive")
+	check(c.Redis.MinIdleConns >= 0 && c.Redis.MinIdleConns <= c.Redis.PoolSize, "redis.minIdleConns must be between 0 and redis.poolSize")
+	positive("redis.dialTimeout", c.Redis.DialTimeout)
+	positive("redis.readTimeout", c.Redis.ReadTimeout)
+	positive("redis.writeTimeout", c.Redis.WriteTimeout)
+
+	check(c.Postgres.MaxConns > 0, "postgres.maxConns must be positive")
+	check(c.Postgres.MinConns >= 0 && c.Postgres.MinConns <= c.Postgres.MaxConns, "postgres.minConns must be between 0 and postgres.maxConns")
+	positive("postgres.maxConnLifetime", c.Postgres.MaxConnLifetime)
+
+	check(c.Crash.TickInterval >= Duration(10*time.Millisecond), "crash.tickInterval must be at least 10ms")
+	check(c.Crash.TickInterval%Duration(time.Millisecond) == 0, "crash.tickInterval must be whole milliseconds")
+	if err := c.Crash.CandleLayout().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("crash candles: %w", err))
+	}
+	check(c.Crash.CountdownSeconds >= 0, "crash.countdownSeconds must not be negative")
+	check(c.Crash.EndWait >= 0, "crash.endWait must not be negative")
+	check(c.Crash.HistorySize > 0, "crash.historySize must be positive")
+	check(c.Crash.SeedChainLength > 0, "crash.seedChainLength must be positive")
+	if key := strings.TrimPrefix(c.Crash.SeedChainKey, "0x"); key != "" {
+		_, err := hex.DecodeString(key)
+		check(err == nil && len(key) == 64, "crash.seedChainKey must be 32 hex-encoded bytes")
+	}
+	if err := c.Crash.Distribution.Resolved().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("crash.distribution: %w", err))
+	}
+
+	check(c.Candleflip.PriceModel == game.CandleflipPriceModelLinear || c.Candleflip.PriceModel == game.CandleflipPriceModelSymmetric,
+		"candleflip.priceModel must be %d or %d", game.CandleflipPriceModelLinear, game.CandleflipPriceModelSymmetric)
+	positive("candleflip.tickInterval", c.Candleflip.TickInterval)
+	check(c.Candleflip.RoomInterval >= 0, "candleflip.roomInterval must not be negative")
+	check(c.Candleflip.CleanupDelay >= 0, "candleflip.cleanupDelay must not be negative")
+	check(c.Candleflip.MinOdds > 1, "candleflip.minOdds must be above 1")
+	check(c.Candleflip.BaseOdds >= c.Candleflip.MinOdds, "candleflip.baseOdds must be at least candleflip.minOdds")
+	check(c.Candleflip.ReserveGames > 0, "candleflip.reserveGames must be positive")
+
+	check(c.WebSocket.ReadBufferSize > 0 && c.WebSocket.WriteBufferSize > 0, "websocket buffer sizes must be positive")
+	positive("websocket.readDeadline", c.WebSocket.ReadDeadline)
+	positive("websocket.writeDeadline", c.WebSocket.WriteDeadline)
+	check(c.WebSocket.PingInterval > 0 && c.WebSocket.PingInterval < c.WebSocket.ReadDeadline,
+		"websocket.pingInterval must be positive and shorter than websocket.readDeadline")
+	check(c.WebSocket.MaxMessageSize > 0, "websocket.maxMessageSize must be positive")
+	check(c.WebSocket.SendBufferSize > 0, "websocket.sendBufferSize must be positive")
+
+	check(c.Bets.MinAmount.Sign() > 0, "bets.minAmount must be positive")
+	check(c.Bets.MaxAmount.Cmp(c.Bets.MinAmount.Int) >= 0, "bets.maxAmount must be at least bets.minAmount")
+	check(c.Bets.MinRooms >= 1 && c.Bets.MaxRooms >= c.Bets.MinRooms, "bets rooms must satisfy 1 <= minRooms <= maxRooms")
+	check(c.Bets.MinMultiplier >= 1 && c.Bets.MaxMultiplier > c.Bets.MinMultiplier, "bets multipliers must satisfy 1 <= minMultiplier < maxMultiplier")
+	check(c.Bets.MaxClientSeedLength > 0, "bets.maxClientSeedLength must be positive")
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// isHexAddress reports whether s is a 0x-prefixed 20 byte hex address
+func isHexAddress(s string) bool {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return false
+	}
+	b, err := hex.DecodeString(s[2:])
+	return err == nil && len(b) == 20
+}
+
+// CandleLayout returns how the crash loop groups ticks into candles,
+// stored with every round so verification replays the same groups
+func (c CrashConfig) CandleLayout() game.CandleLayout {
+	return game.CandleLayout{
+		TickIntervalMs:  c.TickInterval.D().Milliseconds(),
+		GroupDurationMs: c.CandleDuration.D().Milliseconds(),
+		MergeThreshold:  c.CandleMergeThreshold,
+	}
+}
+
+/* =========================
+   REDACTION
+========================= */
+
+const redacted = "[redacted]"
+
+// Redacted returns a copy of the configuration that is safe to expose, with
+// secrets replaced and the password stripped from the database URL
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Server.AdminAPIKey = redact(r.Server.AdminAPIKey)
+	r.Chain.PrivateKey = redact(r.Chain.PrivateKey)
+	r.Crash.SeedChainKey = redact(r.Crash.SeedChainKey)
+	r.Redis.Password = redact(r.Redis.Password)
+	if u, err := url.Parse(r.Postgres.URL); err == nil && u.Scheme != "" {
+		r.Postgres.URL = u.Redacted()
+	} else {
+		r.Postgres.URL = redact(r.Postgres.URL)
+	}
+	return &r
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+/* =========================
+   VALUE TYPES
+========================= */
+
+// Duration is a time.Duration written as a string such as "500ms" in JSON
+// and environment variables
+type Duration time.Duration
+
+// D returns the value as a time.Duration
+func (d Duration) D() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Wei is an amount in wei, written as a decimal string so values above 2^53
+// survive JSON
+type Wei struct {
+	*big.Int
+}
+
+// NewWei returns a Wei of v
+func NewWei(v int64) Wei {
+	return Wei{big.NewInt(v)}
+}
+
+func (w Wei) MarshalText() ([]byte, error) {
+	if w.Int == nil {
+		return []byte("0"), nil
+	}
+	return []byte(w.Int.String()), nil
+}
+
+func (w *Wei) UnmarshalText(text []byte) error {
+	v, ok := new(big.Int).SetString(string(text), 10)
+	if !ok {
+		return fmt.Errorf("invalid wei amount %q", text)
+	}
+	w.Int = v
+	return nil
+}
+
+// MarshalJSON and UnmarshalJSON shadow the promoted big.Int methods so the
+// amount is written as a string; plain JSON numbers are still accepted
+func (w Wei) MarshalJSON() ([]byte, error) {
+	text, _ := w.MarshalText()
+	return json.Marshal(string(text))
+}
+
+func (w *Wei) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	return w.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
@@ -6,94 +6,14 @@ import (
 )
 
 /* =========================
-   NETWORK CONFIGURATION
-========================= */
-
-const (
-	// Mantle Sepolia Testnet
-	MantleSepoliaRPC = "https://rpc.sepolia.mantle.xyz"
-	MantleChainID    = 5003
-)
-
-/* =========================
-   CONTRACT CONFIGURATION
-========================= */
-
-const (
-	// GameHouseV2 Contract
-	GameHouseV2Address = "0x43a01A18a2C947179595A7b17bDCc3d88ecF04F5"
-
-	// GameHouseV3 Contract (to be deployed)
-	// TODO: Update this once V3 is deployed
-	GameHouseV3Address = "0x0000000000000000000000000000000000000000"
-)
-
-/* =========================
-   GAME MECHANICS - CRASH
-========================= */
-
-const (
-	// Price simulation
-	StartingPrice   = 1.0
-	MaxTicks        = 5000
-	TickInterval    = 500 * time.Millisecond // 500ms per tick
-	RugProbability  = 0.01                   // 1% chance to rug each tick
-	GodCandleChance = 0.005                  // 0.5% chance for god candle
-	GodCandleMult   = 1.0                    // 1.0x multiplier on god candle
-	BigMoveChance   = 0.10                   // 10% chance of big moves
-	BigMoveMin      = 0.08                   // Minimum 8% move
-	BigMoveMax      = 0.50                   // Maximum 50% move
-	DriftMin        = -0.04                  // Minimum drift -4%
-	DriftMax        = 0.04                   // Maximum drift +4%
-
-	// Game timing
-	CountdownDuration    = 5 * time.Second  // 5 second countdown before game starts
-	GameEndWaitDuration  = 10 * time.Second // 10 second wait after game ends
-	MaxGameHistory       = 50               // Keep last 50 games in memory
-	InitialGroupDuration = 500              // Initial candle group duration in ms
-	MergeThreshold       = 100              // Number of candles before merging
-
-	// Peak distribution probabilities (sum to 1.0)
-	PeakVeryLow  = 0.40 // 40% chance: 1.0x - 1.5x
-	PeakLow      = 0.70 // 30% chance: 1.5x - 3.0x (cumulative)
-	PeakMedium   = 0.88 // 18% chance: 3.0x - 10.0x (cumulative)
-	PeakHigh     = 0.97 // 9% chance: 10.0x - 50.0x (cumulative)
-	PeakExtreme  = 1.00 // 3% chance: 50.0x - 200.0x (cumulative)
-	PeakVeryLowMax  = 1.5
-	PeakLowMax      = 3.0
-	PeakMediumMax   = 10.0
-	PeakHighMax     = 50.0
-	PeakExtremeMax  = 200.0
-)
-
-/* =========================
-   ODDS CONFIGURATION
+   DECIMAL PRECISION
 ========================= */
 
 var (
-	// Base odds for CandleFlip (2.0x in 18-decimal format)
-	BaseOdds = big.NewInt(2e18)
-
-	// Minimum odds (1.2x in 18-decimal format)
-	MinOdds = big.NewInt(1.2e18)
-
-	// Reserve games for liquidity calculation
-	ReserveGames = uint64(20)
-
-	// Decimal precision
+	// Decimal precision of on-chain amounts and odds
 	DecimalPrecision = big.NewInt(1e18)
 )
 
-// GetBaseOddsFloat returns BASE_ODDS as float64 (2.0)
-func GetBaseOddsFloat() float64 {
-	return 2.0
-}
-
-// GetMinOddsFloat returns MIN_ODDS as float64 (1.2)
-func GetMinOddsFloat() float64 {
-	return 1.2
-}
-
 /* =========================
    REDIS TTL CONFIGURATION
 ========================= */
@@ -117,108 +37,65 @@ const (
 
 	// Buyback eligibility TTL (5 minutes after cashout)
 	BuybackTTL = 5 * time.Minute
-)
-
-/* =========================
-   REDIS KEY PATTERNS
-========================= */
-
-const (
-	// Crash game keys
-	RedisCrashBetKey        = "crash:%s:%s"        // crash:{gameId}:{playerAddress}
-	RedisCrashCashedOutKey  = "crash:cashedout:%s:%s" // crash:cashedout:{gameId}:{playerAddress}
-	RedisCrashPlayersKey    = "game:crash:%s:players" // game:crash:{gameId}:players (SET)
-
-	// CandleFlip game keys
-	RedisCandleGameKey = "candle:%s:%s" // candle:{gameId}:{playerAddress}
-)
-
-/* =========================
-   POSTGRESQL CONFIGURATION
-========================= */
-
-const (
-	// Database connection settings
-	PostgresHost     = "localhost"
-	PostgresPort     = 5432
-	PostgresUser     = "postgres"
-	PostgresPassword = "password"
-	PostgresDB       = "gamehouse"
-
-	// Connection pool settings
-	MaxOpenConns    = 25
-	MaxIdleConns    = 5
-	ConnMaxLifetime = 5 * time.Minute
-)
-
-/* =========================
-   RELAYER CONFIGURATION
-========================= */
-
-const (
-	// Gas limits and pricing
-	RelayerGasLimit    = 150000           // Maximum gas for gasless transactions
-	RelayerMaxGasPrice = 10000000000      // 10 Gwei max gas price
-	RelayerMinBalance  = 50000000000000000 // 0.05 MNT minimum balance
-
-	// Retry configuration
-	MaxRetries     = 3
-	RetryDelay     = 2 * time.Second
-	TransactionTimeout = 30 * time.Second
-)
 
-/* =========================
-   API CONFIGURATION
-========================= */
-
-const (
-	// Server settings
-	ServerPort = "8080"
-	ServerHost = "0.0.0.0"
+	// Unfinished crash round TTL, matches the bets it covers (1 hour)
+	// Key: crash:round:{gameId}
+	CrashRoundTTL = 1 * time.Hour
 
-	// CORS settings
-	AllowOrigin = "*"
-
-	// Rate limiting
-	MaxRequestsPerSecond = 100
-)
-
-/* =========================
-   WEBSOCKET CONFIGURATION
-========================= */
+	// Global room TTL, refreshed on every room update (2 hours)
+	// Key: rooms:global:{roomId}
+	GlobalRoomTTL = 2 * time.Hour
 
-const (
-	// WebSocket settings
-	WSReadDeadline  = 60 * time.Second
-	WSWriteDeadline = 10 * time.Second
-	WSPingInterval  = 30 * time.Second
+	// Crash loop lease TTL, renewed by the instance running crash rounds
+	// Key: crash:leader
+	CrashLeaseTTL = 15 * time.Second
 
-	// Buffer sizes
-	WSReadBufferSize  = 1024
-	WSWriteBufferSize = 1024
+	// Candleflip batch ownership TTL, renewed by the instance running the batch
+	// Key: candleflip:owner:{batchId}
+	CandleflipLeaseTTL = 30 * time.Second
 
-	// Message size limits
-	MaxMessageSize = 512 * 1024 // 512KB
+	// Crash command queue and reply TTL, so commands nobody takes expire (1 minute)
+	// Keys: crash:commands, crash:reply:{id}
+	CrashCommandTTL = 1 * time.Minute
 )
 
 /* =========================
-   VALIDATION CONSTANTS
+   REDIS KEY PATTERNS
 ========================= */
 
 const (
-	// Minimum bet amounts (in wei, 18 decimals)
-	MinBetAmount = 1000000000000000 // 0.001 MNT
-
-	// Maximum bet amounts (in wei, 18 decimals)
-	MaxBetAmount = 100000000000000000000 // 100 MNT
-
-	// CandleFlip room limits
-	MinRooms = 1
-	MaxRooms = 10
+	// Crash game keys
+	RedisCrashBetKey        = "crash:%s:%s"           // crash:{gameId}:{playerAddress}
+	RedisCrashCashedOutKey  = "crash:cashedout:%s:%s" // crash:cashedout:{gameId}:{playerAddress}
+	RedisCrashPlayersKey    = "game:crash:%s:players" // game:crash:{gameId}:players (SET)
+	RedisCrashRoundKey      = "crash:round:%s"        // crash:round:{gameId}
+	RedisCrashOpenRoundsKey = "crash:rounds:open"     // Game IDs of unfinished rounds (SET)
+	RedisCrashLeaderKey     = "crash:leader"          // Instance running crash rounds
+	RedisCrashCommandsKey   = "crash:commands"        // Bets and cashouts for the leader (LIST)
+	RedisCrashReplyKey      = "crash:reply:%s"        // crash:reply:{id} (LIST of the leader's reply)
 
-	// Multiplier limits
-	MinMultiplier = 1.0  // 1.0x minimum
-	MaxMultiplier = 1000.0 // 1000x maximum
+	// CandleFlip game keys
+	RedisCandleGameKey         = "candle:%s:%s"        // candle:{gameId}:{playerAddress}
+	RedisCandleflipOwnerKey    = "candleflip:owner:%s" // candleflip:owner:{batchId}, instance running the batch
+	RedisCandleflipExposureKey = "candleflip:exposure" // Payout each unsettled batch could still make (HASH of batchId → wei)
+
+	// Provably fair seed keys
+	RedisSeedPairKey     = "fair:seeds:%s"    // fair:seeds:{playerAddress} (HASH)
+	RedisRevealedSeedKey = "fair:revealed:%s" // fair:revealed:{serverSeedHash}
+
+	// Gasless relayer keys
+	RedisRelayerNonceKey   = "relayer:nonce:%s"   // relayer:nonce:{playerAddress}
+	RedisRelayerDepositKey = "relayer:deposit:%s" // relayer:deposit:{txHash}
+
+	// Hub channel keys, shared by every server instance
+	RedisHubChannel    = "hub:%s"        // hub:{channel} (PUBSUB)
+	RedisHubPattern    = "hub:*"         // Every hub channel
+	RedisHubSeqKey     = "hubseq:%s"     // hubseq:{channel}, last sequence number
+	RedisHubHistoryKey = "hubhistory:%s" // hubhistory:{channel} (LIST of published messages)
+
+	// Global room keys
+	RedisGlobalRoomKey  = "rooms:global:%s" // rooms:global:{roomId}
+	RedisGlobalRoomsKey = "rooms:global"    // Room IDs of open rooms (SET)
 )
 
 /* =========================
@@ -10,25 +10,17 @@ import (
 	"os"
 	"strings"
 
+	"goLangServer/config"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-const (
-	// Mantle Sepolia RPC
-	MantleSepoliaRPC = "https://rpc.sepolia.mantle.xyz"
-
-	// Contract address (GameHouseV3)
-	ContractAddress = ""
-
-	// Chain ID for Mantle Sepolia
-	ChainID = 5003
-)
-
 // GameHouseContract wraps the contract interaction
 type GameHouseContract struct {
 	Client      *ethclient.Client
@@ -37,23 +29,63 @@ type GameHouseContract struct {
 	Address     common.Address
 	PrivateKey  *ecdsa.PrivateKey
 	FromAddress common.Address
+	ChainID     *big.Int
+	Nonces      *NonceManager // Serialises every transaction sent from FromAddress
+}
+
+// ContractHealth reports the state of the contract client
+type ContractHealth struct {
+	Status       string `json:"status"` // "ok" or "error: ..."
+	Address      string `json:"address"`
+	Server       string `json:"server"`
+	BlockNumber  uint64 `json:"blockNumber,omitempty"`
+	Balance      string `json:"balance,omitempty"` // Server wallet balance in wei
+	PendingNonce uint64 `json:"pendingNonce,omitempty"`
+}
+
+var (
+	// GameHouse is the shared contract client, set by InitGameHouse
+	GameHouse *GameHouseContract
+)
+
+// InitGameHouse creates the shared contract client used for every transaction
+func InitGameHouse(cfg config.ChainConfig) error {
+	c, err := NewGameHouseContract(cfg)
+	if err != nil {
+		return err
+	}
+	GameHouse = c
+	return nil
+}
+
+// CloseGameHouse closes the shared contract client
+func CloseGameHouse() {
+	if GameHouse != nil {
+		GameHouse.Close()
+		log.Println("✅ Contract client closed")
+	}
 }
 
 // ABIFile structure
 type ABIFile struct {
-	ABI json.RawMessage `json:"abi"`
+	ABI      json.RawMessage `json:"abi"`
+	Bytecode string          `json:"bytecode"`
 }
 
 // NewGameHouseContract creates a new contract instance
-func NewGameHouseContract() (*GameHouseContract, error) {
-	// Connect to Mantle Sepolia
-	client, err := ethclient.Dial(MantleSepoliaRPC)
+func NewGameHouseContract(cfg config.ChainConfig) (*GameHouseContract, error) {
+	if cfg.ContractAddress == "" {
+		return nil, fmt.Errorf("contract address not configured (CONTRACT_ADDRESS)")
+	}
+
+	// Connect to the configured chain
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Mantle Sepolia: %v", err)
+		return nil, fmt.Errorf("failed to connect to %s: %v", cfg.RPCURL, err)
 	}
 
 	// Load ABI from JSON file
-	abiBytes, err := os.ReadFile("contract/GameHouseNoSig.json")
+	abiBytes, err := os.ReadFile(cfg.ABIPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ABI file: %v", err)
 	}
@@ -68,10 +100,10 @@ func NewGameHouseContract() (*GameHouseContract, error) {
 		return nil, fmt.Errorf("failed to parse contract ABI: %v", err)
 	}
 
-	// Load private key from environment
-	privateKeyHex := os.Getenv("SERVER_PRIVATE_KEY")
+	// Load private key from the configuration
+	privateKeyHex := cfg.PrivateKey
 	if privateKeyHex == "" {
-		return nil, fmt.Errorf("SERVER_PRIVATE_KEY environment variable not set")
+		return nil, fmt.Errorf("server private key not configured (SERVER_PRIVATE_KEY)")
 	}
 
 	// Remove 0x prefix if present
@@ -92,10 +124,10 @@ func NewGameHouseContract() (*GameHouseContract, error) {
 
 	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 
-	contractAddress := common.HexToAddress(ContractAddress)
+	contractAddress := common.HexToAddress(cfg.ContractAddress)
 	contract := bind.NewBoundContract(contractAddress, contractABI, client, client, client)
 
-	log.Printf("✅ Contract client initialized - Address: %s, Owner: %s", ContractAddress, fromAddress.Hex())
+	log.Printf("✅ Contract client initialized - Address: %s, Owner: %s, Chain: %d", contractAddress.Hex(), fromAddress.Hex(), cfg.ChainID)
 
 	return &GameHouseContract{
 		Client:      client,
@@ -104,79 +136,126 @@ func NewGameHouseContract() (*GameHouseContract, error) {
 		Address:     contractAddress,
 		PrivateKey:  privateKey,
 		FromAddress: fromAddress,
+		ChainID:     big.NewInt(cfg.ChainID),
+		Nonces:      NewNonceManager(client, fromAddress),
 	}, nil
 }
 
-// PayPlayer calls the V3 contract's payPlayer method
-// This is the ONLY payment method - server pays gas, no retries
-func (c *GameHouseContract) PayPlayer(
+// HealthCheck reports RPC connectivity and the server wallet state
+func (c *GameHouseContract) HealthCheck(ctx context.Context) ContractHealth {
+	health := ContractHealth{
+		Address: c.Address.Hex(),
+		Server:  c.FromAddress.Hex(),
+	}
+
+	block, err := c.Client.BlockNumber(ctx)
+	if err != nil {
+		health.Status = "error: " + err.Error()
+		return health
+	}
+	health.BlockNumber = block
+
+	balance, err := c.Client.BalanceAt(ctx, c.FromAddress, nil)
+	if err != nil {
+		health.Status = "error: " + err.Error()
+		return health
+	}
+	health.Balance = balance.String()
+
+	if nonce, ok := c.Nonces.Pending(); ok {
+		health.PendingNonce = nonce
+	}
+
+	health.Status = "ok"
+	return health
+}
+
+// SignPayPlayer builds and signs a payPlayer transaction with the given nonce
+// and gas price without broadcasting it. The payout queue records the hash
+// before sending, so a restart never loses track of a transaction.
+func (c *GameHouseContract) SignPayPlayer(
 	ctx context.Context,
 	player common.Address,
 	amount *big.Int,
-) error {
+	nonce uint64,
+	gasPrice *big.Int,
+) (*types.Transaction, error) {
 	// Ensure ABI has the function
 	if _, ok := c.ABI.Methods["payPlayer"]; !ok {
-		return fmt.Errorf("abi does not contain payPlayer")
+		return nil, fmt.Errorf("abi does not contain payPlayer")
 	}
 
 	// Create transactor (server pays gas)
-	chainIDBig := big.NewInt(ChainID)
-	auth, err := bind.NewKeyedTransactorWithChainID(c.PrivateKey, chainIDBig)
+	auth, err := bind.NewKeyedTransactorWithChainID(c.PrivateKey, c.ChainID)
 	if err != nil {
-		return fmt.Errorf("failed to create transactor: %v", err)
+		return nil, fmt.Errorf("failed to create transactor: %v", err)
 	}
 	auth.Context = ctx
 	auth.Value = big.NewInt(0) // non-payable
-
-	// Nonce
-	nonce, err := c.Client.PendingNonceAt(ctx, c.FromAddress)
-	if err != nil {
-		return fmt.Errorf("failed to get nonce: %v", err)
-	}
-	auth.Nonce = big.NewInt(int64(nonce))
-
-	// Gas price
-	gasPrice, err := c.Client.SuggestGasPrice(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get gas price: %v", err)
-	}
+	auth.Nonce = new(big.Int).SetUint64(nonce)
 	auth.GasPrice = gasPrice
+	auth.NoSend = true
 
 	// Pack input for estimation
 	input, err := c.ABI.Pack("payPlayer", player, amount)
 	if err != nil {
-		return fmt.Errorf("failed to pack input: %v", err)
+		return nil, fmt.Errorf("failed to pack input: %v", err)
 	}
 
-	// Estimate gas limit
+	// Estimate gas limit - a failed estimate means the call would revert
 	gasLimit, err := c.Client.EstimateGas(ctx, ethereum.CallMsg{
 		From: c.FromAddress,
 		To:   &c.Address,
 		Data: input,
 	})
 	if err != nil {
-		log.Printf("⚠️ Gas estimation failed, using default: %v", err)
-		auth.GasLimit = uint64(200000) // safe default
-	} else {
-		auth.GasLimit = gasLimit + (gasLimit * 20 / 100) // +20% buffer
+		return nil, fmt.Errorf("gas estimation failed: %v", err)
 	}
+	auth.GasLimit = gasLimit + (gasLimit * 20 / 100) // +20% buffer
 
-	log.Printf("💸 Calling payPlayer(player=%s, amount=%s wei) with gasLimit=%d", 
-		player.Hex(), amount.String(), auth.GasLimit)
-
-	// Transact - fire and forget, no wait for confirmation
 	tx, err := c.Contract.Transact(auth, "payPlayer", player, amount)
 	if err != nil {
-		// Log failure but don't block game flow
-		log.Printf("❌ payPlayer failed: %v", err)
-		return err
+		return nil, fmt.Errorf("failed to sign payPlayer: %v", err)
 	}
 
-	log.Printf("📤 payPlayer tx sent: %s (not waiting for confirmation)", tx.Hash().Hex())
-	return nil
+	return tx, nil
+}
+
+// HouseLiquidity is the contract's bankroll and payout limits
+type HouseLiquidity struct {
+	Balance        *big.Int // houseBalance()
+	MinLiquidity   *big.Int // minLiquidity()
+	MaxPayoutPerTx *big.Int // maxPayoutPerTx(), zero for no limit
+}
+
+// HouseLiquidity reads the house bankroll and payout limits from the contract
+func (c *GameHouseContract) HouseLiquidity(ctx context.Context) (*HouseLiquidity, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	liquidity := &HouseLiquidity{}
+
+	for method, dst := range map[string]**big.Int{
+		"houseBalance":   &liquidity.Balance,
+		"minLiquidity":   &liquidity.MinLiquidity,
+		"maxPayoutPerTx": &liquidity.MaxPayoutPerTx,
+	} {
+		var out []interface{}
+		if err := c.Contract.Call(opts, &out, method); err != nil {
+			return nil, fmt.Errorf("failed to call %s: %w", method, err)
+		}
+		var value *big.Int
+		if len(out) == 1 {
+			value, _ = out[0].(*big.Int)
+		}
+		if value == nil {
+			return nil, fmt.Errorf("unexpected %s result %v", method, out)
+		}
+		*dst = value
+	}
+
+	return liquidity, nil
 }
 
 // Close closes the client connection
 func (c *GameHouseContract) Close() {
 	c.Client.Close()
-}
\ No newline at end of file
+}
@@ -0,0 +1,74 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceSource reads the pending nonce of an account from the chain
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out transaction nonces for a single sending account and
+// serialises transactions, so concurrent senders never reuse a nonce
+type NonceManager struct {
+	source  NonceSource
+	address common.Address
+
+	mu     sync.Mutex // Held from Acquire until release
+	state  sync.Mutex // Guards next/loaded for Pending
+	next   uint64
+	loaded bool
+}
+
+// NewNonceManager creates a nonce manager for address
+func NewNonceManager(source NonceSource, address common.Address) *NonceManager {
+	return &NonceManager{source: source, address: address}
+}
+
+// Acquire locks the account and returns the next nonce. The caller must call
+// release exactly once: with true if a transaction using the nonce was
+// broadcast, false if it was abandoned. After false the nonce is reloaded
+// from the chain.
+func (m *NonceManager) Acquire(ctx context.Context) (uint64, func(used bool), error) {
+	m.mu.Lock()
+
+	m.state.Lock()
+	if !m.loaded {
+		nonce, err := m.source.PendingNonceAt(ctx, m.address)
+		if err != nil {
+			m.state.Unlock()
+			m.mu.Unlock()
+			return 0, nil, fmt.Errorf("failed to get nonce: %w", err)
+		}
+		m.next, m.loaded = nonce, true
+	}
+	nonce := m.next
+	m.state.Unlock()
+
+	var once sync.Once
+	release := func(used bool) {
+		once.Do(func() {
+			m.state.Lock()
+			if used {
+				m.next = nonce + 1
+			} else {
+				m.loaded = false
+			}
+			m.state.Unlock()
+			m.mu.Unlock()
+		})
+	}
+	return nonce, release, nil
+}
+
+// Pending returns the next nonce to be handed out, if it has been loaded
+func (m *NonceManager) Pending() (uint64, bool) {
+	m.state.Lock()
+	defer m.state.Unlock()
+	return m.next, m.loaded
+}
@@ -0,0 +1,299 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/db"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PayoutWorker drains the Postgres payout queue: it signs and broadcasts
+// pending payouts, polls receipts for sent ones, rebroadcasts stuck
+// transactions with a higher gas price and retries failures up to MaxRetries.
+type PayoutWorker struct {
+	contract *GameHouseContract
+	chain    payoutChain // contract.Client
+	config   config.PayoutConfig
+
+	// OnUpdate is called after every payout state change
+	OnUpdate func(payout *db.PayoutRecord)
+}
+
+// payoutChain is the part of the chain client the payout worker uses
+type payoutChain interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// NewPayoutWorker creates a payout worker sending from the contract client's wallet
+func NewPayoutWorker(c *GameHouseContract, cfg config.PayoutConfig) *PayoutWorker {
+	return &PayoutWorker{contract: c, chain: c.Client, config: cfg}
+}
+
+// Run processes the queue every poll interval until ctx is cancelled
+func (w *PayoutWorker) Run(ctx context.Context) {
+	log.Println("💸 Payout worker started")
+
+	ticker := time.NewTicker(w.config.PollInterval.D())
+	defer ticker.Stop()
+
+	for {
+		w.processSent(ctx)
+		w.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("💸 Payout worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify reports a payout state change
+func (w *PayoutWorker) notify(p *db.PayoutRecord) {
+	if p != nil && w.OnUpdate != nil {
+		w.OnUpdate(p)
+	}
+}
+
+// processPending signs, records and broadcasts pending payouts
+func (w *PayoutWorker) processPending(ctx context.Context) {
+	payouts, err := db.GetPayoutsByStatus(ctx, db.PayoutStatusPending, w.config.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+
+	for _, p := range payouts {
+		amount, ok := new(big.Int).SetString(p.Amount, 10)
+		if !ok {
+			w.fail(ctx, p, fmt.Sprintf("invalid amount %q", p.Amount), 0)
+			continue
+		}
+
+		gasPrice, err := w.chain.SuggestGasPrice(ctx)
+		if err != nil {
+			log.Printf("⚠️ Payout worker: failed to get gas price: %v", err)
+			return
+		}
+
+		sent, err := w.send(ctx, p, amount, gasPrice)
+		if err != nil {
+			log.Printf("⚠️ Payout worker: %v", err)
+			continue
+		}
+		w.notify(sent)
+	}
+}
+
+// send signs, records and broadcasts a pending payout while holding the
+// sender's nonce. A nil record with a nil error means the attempt failed and was recorded.
+func (w *PayoutWorker) send(ctx context.Context, p *db.PayoutRecord, amount, gasPrice *big.Int) (*db.PayoutRecord, error) {
+	nonce, release, err := w.contract.Nonces.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := w.contract.SignPayPlayer(ctx, common.HexToAddress(p.PlayerAddress), amount, nonce, gasPrice)
+	if err != nil {
+		// Nothing was broadcast, so the nonce is still free
+		release(false)
+		w.fail(ctx, p, err.Error(), w.config.MaxRetries)
+		return nil, nil
+	}
+
+	sent, err := db.MarkPayoutSent(ctx, p.ID, tx.Hash().Hex(), nonce, gasPrice.String())
+	if err != nil {
+		// Not recorded, so don't broadcast; the payout stays pending
+		release(false)
+		return nil, err
+	}
+
+	if err := w.chain.SendTransaction(ctx, tx); err != nil {
+		// The receipt poller rebroadcasts with the same nonce once the
+		// payout looks stuck, so a send error can never pay twice
+		release(false)
+		log.Printf("⚠️ Payout #%d broadcast failed: %v", p.ID, err)
+		db.RecordPayoutError(ctx, p.ID, err.Error())
+		return sent, nil
+	}
+	release(true)
+
+	log.Printf("📤 Payout #%d sent: %s (nonce %d, gas price %s)", p.ID, tx.Hash().Hex(), nonce, gasPrice)
+	return sent, nil
+}
+
+// processSent polls receipts of sent payouts and rebroadcasts stuck ones
+func (w *PayoutWorker) processSent(ctx context.Context) {
+	payouts, err := db.GetPayoutsByStatus(ctx, db.PayoutStatusSent, w.config.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+
+	for _, p := range payouts {
+		receipt, txHash, err := w.findReceipt(ctx, p)
+		if err != nil {
+			log.Printf("⚠️ Payout worker: %v", err)
+			continue
+		}
+
+		if receipt != nil {
+			w.settle(ctx, p, receipt, txHash)
+			continue
+		}
+
+		if p.SentAt != nil && time.Since(*p.SentAt) > w.config.StuckTimeout.D() {
+			w.rebroadcast(ctx, p)
+		}
+	}
+}
+
+// settle records the outcome of a mined payout transaction
+func (w *PayoutWorker) settle(ctx context.Context, p *db.PayoutRecord, receipt *types.Receipt, txHash string) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		w.fail(ctx, p, fmt.Sprintf("transaction %s reverted", txHash), w.config.MaxRetries)
+		return
+	}
+
+	confirmed, err := db.MarkPayoutConfirmed(ctx, p.ID, txHash)
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+	log.Printf("✅ Payout #%d confirmed in block %s: %s", p.ID, receipt.BlockNumber, txHash)
+	w.notify(confirmed)
+}
+
+// findReceipt looks up the receipt of any transaction broadcast for the payout
+func (w *PayoutWorker) findReceipt(ctx context.Context, p *db.PayoutRecord) (*types.Receipt, string, error) {
+	hashes := append([]string{p.TxHash}, p.ReplacedTxHashes...)
+	for _, h := range hashes {
+		if h == "" {
+			continue
+		}
+		receipt, err := w.chain.TransactionReceipt(ctx, common.HexToHash(h))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get receipt for %s: %w", h, err)
+		}
+		return receipt, h, nil
+	}
+	return nil, "", nil
+}
+
+// rebroadcast replaces a stuck payout transaction with a higher gas price,
+// keeping its nonce so at most one of them can be mined
+func (w *PayoutWorker) rebroadcast(ctx context.Context, p *db.PayoutRecord) {
+	if p.TxNonce == nil {
+		w.fail(ctx, p, "sent payout has no nonce", w.config.MaxRetries)
+		return
+	}
+
+	receipt, txHash, used, err := w.checkNonce(ctx, p)
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+	if receipt != nil {
+		w.settle(ctx, p, receipt, txHash)
+		return
+	}
+	if used {
+		w.fail(ctx, p, fmt.Sprintf("nonce %d was used by another transaction", *p.TxNonce), w.config.MaxRetries)
+		return
+	}
+
+	amount, _ := new(big.Int).SetString(p.Amount, 10)
+	oldGasPrice, ok := new(big.Int).SetString(p.GasPrice, 10)
+	if !ok {
+		oldGasPrice = big.NewInt(0)
+	}
+
+	maxGasPrice := new(big.Int).Set(w.config.MaxGasPrice.Int)
+	gasPrice := new(big.Int).Mul(oldGasPrice, big.NewInt(100+w.config.GasBumpPercent))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	if suggested, err := w.chain.SuggestGasPrice(ctx); err == nil && suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	if gasPrice.Cmp(maxGasPrice) > 0 {
+		gasPrice = maxGasPrice
+	}
+	if gasPrice.Cmp(oldGasPrice) <= 0 {
+		// Already at the cap, rebroadcast the same transaction
+		gasPrice = oldGasPrice
+	}
+
+	tx, err := w.contract.SignPayPlayer(ctx, common.HexToAddress(p.PlayerAddress), amount, *p.TxNonce, gasPrice)
+	if err != nil {
+		log.Printf("⚠️ Payout #%d rebroadcast failed: %v", p.ID, err)
+		db.RecordPayoutError(ctx, p.ID, err.Error())
+		return
+	}
+
+	sent, err := db.MarkPayoutSent(ctx, p.ID, tx.Hash().Hex(), *p.TxNonce, gasPrice.String())
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+
+	if err := w.chain.SendTransaction(ctx, tx); err != nil && !strings.Contains(err.Error(), "already known") {
+		log.Printf("⚠️ Payout #%d rebroadcast failed: %v", p.ID, err)
+		db.RecordPayoutError(ctx, p.ID, err.Error())
+	} else {
+		log.Printf("⛽ Payout #%d rebroadcast: %s (nonce %d, gas price %s)", p.ID, tx.Hash().Hex(), *p.TxNonce, gasPrice)
+	}
+
+	w.notify(sent)
+}
+
+// checkNonce reports whether the chain has moved past the payout's nonce.
+// One of our transactions may have been mined since the receipts were
+// polled, so their receipts are looked up again once it has: used is only
+// set when none of them was mined and another transaction took the nonce.
+func (w *PayoutWorker) checkNonce(ctx context.Context, p *db.PayoutRecord) (receipt *types.Receipt, txHash string, used bool, err error) {
+	confirmedNonce, err := w.chain.NonceAt(ctx, w.contract.FromAddress, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if confirmedNonce <= *p.TxNonce {
+		return nil, "", false, nil
+	}
+
+	receipt, txHash, err = w.findReceipt(ctx, p)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return receipt, txHash, receipt == nil, nil
+}
+
+// fail records a failed attempt, moving the payout back to pending or to failed
+func (w *PayoutWorker) fail(ctx context.Context, p *db.PayoutRecord, reason string, maxAttempts int) {
+	updated, err := db.MarkPayoutAttemptFailed(ctx, p.ID, reason, maxAttempts)
+	if err != nil {
+		log.Printf("⚠️ Payout worker: %v", err)
+		return
+	}
+
+	if updated.Status == db.PayoutStatusFailed {
+		log.Printf("❌ Payout #%d failed after %d attempts: %s", p.ID, updated.Attempts, reason)
+	} else {
+		log.Printf("⚠️ Payout #%d attempt %d failed, retrying: %s", p.ID, updated.Attempts, reason)
+	}
+	w.notify(updated)
+}
@@ -0,0 +1,89 @@
+package contract
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"goLangServer/db"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// racingChain mines a transaction between the worker's receipt poll and its
+// nonce check: receipts of mined only appear once NonceAt has been called
+type racingChain struct {
+	mined   common.Hash
+	nonce   uint64
+	checked bool
+}
+
+func (c *racingChain) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (c *racingChain) SendTransaction(context.Context, *types.Transaction) error {
+	return nil
+}
+
+func (c *racingChain) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	if !c.checked || hash != c.mined {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: hash, BlockNumber: big.NewInt(1)}, nil
+}
+
+func (c *racingChain) NonceAt(context.Context, common.Address, *big.Int) (uint64, error) {
+	c.checked = true
+	return c.nonce, nil
+}
+
+func TestCheckNonceFindsReceiptMinedAfterPoll(t *testing.T) {
+	nonce := uint64(7)
+	p := &db.PayoutRecord{
+		ID:               1,
+		TxHash:           common.HexToHash("0x02").Hex(),
+		TxNonce:          &nonce,
+		ReplacedTxHashes: []string{common.HexToHash("0x01").Hex()},
+	}
+
+	for _, mined := range []string{p.TxHash, p.ReplacedTxHashes[0]} {
+		chain := &racingChain{mined: common.HexToHash(mined), nonce: nonce + 1}
+		w := &PayoutWorker{contract: &GameHouseContract{}, chain: chain}
+		ctx := context.Background()
+
+		receipt, _, err := w.findReceipt(ctx, p)
+		if err != nil || receipt != nil {
+			t.Fatalf("receipt before the nonce check = %v, %v", receipt, err)
+		}
+
+		receipt, txHash, used, err := w.checkNonce(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used {
+			t.Errorf("payout mined as %s reported as a lost nonce", mined)
+		}
+		if receipt == nil || txHash != mined {
+			t.Errorf("receipt of %s not found after the nonce check, got %q", mined, txHash)
+		}
+	}
+}
+
+func TestCheckNonceUsedByAnotherTransaction(t *testing.T) {
+	nonce := uint64(7)
+	p := &db.PayoutRecord{ID: 1, TxHash: common.HexToHash("0x02").Hex(), TxNonce: &nonce}
+	ctx := context.Background()
+
+	w := &PayoutWorker{contract: &GameHouseContract{}, chain: &racingChain{nonce: nonce}}
+	if receipt, _, used, err := w.checkNonce(ctx, p); err != nil || receipt != nil || used {
+		t.Errorf("pending nonce: receipt %v, used %v, err %v", receipt, used, err)
+	}
+
+	w.chain = &racingChain{mined: common.HexToHash("0x03"), nonce: nonce + 1}
+	if receipt, _, used, err := w.checkNonce(ctx, p); err != nil || receipt != nil || !used {
+		t.Errorf("nonce taken by another transaction: receipt %v, used %v, err %v", receipt, used, err)
+	}
+}
@@ -0,0 +1,426 @@
+package contract
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"goLangServer/config"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// EIP-712 domain of relayed requests
+	RelayerDomainName    = "GameHouse"
+	RelayerDomainVersion = "1"
+)
+
+// RelayerBackend is the chain access the relayer needs. Both *ethclient.Client
+// and the go-ethereum simulated backend client satisfy it.
+type RelayerBackend interface {
+	bind.ContractBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+}
+
+// NonceStore tracks per-player relayer nonces for replay protection
+type NonceStore interface {
+	// NextNonce returns the nonce the player's next signed request must carry
+	NextNonce(ctx context.Context, player common.Address) (uint64, error)
+	// UseNonce consumes nonce if it is the player's next nonce, failing otherwise
+	UseNonce(ctx context.Context, player common.Address, nonce uint64) error
+}
+
+// CashOutRequest is a player's signed authorization to cash out a crash bet
+type CashOutRequest struct {
+	PlayerAddress common.Address
+	GameID        *big.Int
+	Multiplier    *big.Int // Minimum multiplier the player accepts, 18 decimals
+	Nonce         uint64
+	Signature     []byte
+}
+
+// BuyInRequest is a player's signed authorization to register a crash bet
+// backed by an on-chain bet() deposit
+type BuyInRequest struct {
+	PlayerAddress common.Address
+	GameID        *big.Int
+	Amount        *big.Int // Wei
+	DepositTxHash common.Hash
+	Nonce         uint64
+	Signature     []byte
+}
+
+// Seed pair actions a player can sign
+const (
+	SeedPairActionSetClientSeed = "setClientSeed"
+	SeedPairActionRotate        = "rotate"
+)
+
+// SeedPairRequest is a player's signed request to change their provably fair
+// seed pair: set its client seed or reveal and rotate its server seed
+type SeedPairRequest struct {
+	PlayerAddress common.Address
+	Action        string // SeedPairActionSetClientSeed or SeedPairActionRotate
+	ClientSeed    string // New client seed, may be empty when rotating
+	Nonce         uint64
+	Signature     []byte
+}
+
+// Relayer verifies EIP-712 signed player requests and submits the resulting
+// transactions from the server wallet, so players never pay gas for them
+type Relayer struct {
+	backend  RelayerBackend
+	contract *bind.BoundContract
+	abi      abi.ABI
+	address  common.Address
+	key      *ecdsa.PrivateKey
+	from     common.Address
+	chainID  *big.Int
+	nonces   NonceStore
+	limits   config.RelayerConfig
+
+	// Transaction nonces of the relayer wallet
+	txNonces *NonceManager
+}
+
+// NewRelayer creates a relayer for the GameHouse contract at address
+func NewRelayer(backend RelayerBackend, address common.Address, contractABI abi.ABI, key *ecdsa.PrivateKey, chainID *big.Int, nonces NonceStore) *Relayer {
+	return &Relayer{
+		backend:  backend,
+		contract: bind.NewBoundContract(address, contractABI, backend, backend, backend),
+		abi:      contractABI,
+		address:  address,
+		key:      key,
+		from:     crypto.PubkeyToAddress(key.PublicKey),
+		chainID:  chainID,
+		nonces:   nonces,
+		limits:   config.Default().Relayer,
+		txNonces: NewNonceManager(backend, crypto.PubkeyToAddress(key.PublicKey)),
+	}
+}
+
+// NewRelayerFromContract creates a relayer sharing the contract client's
+// connection, key and nonce manager, enforcing the given limits
+func NewRelayerFromContract(c *GameHouseContract, limits config.RelayerConfig, nonces NonceStore) *Relayer {
+	r := NewRelayer(c.Client, c.Address, c.ABI, c.PrivateKey, c.ChainID, nonces)
+	r.txNonces = c.Nonces
+	r.limits = limits
+	return r
+}
+
+// Address returns the relayer's sending address
+func (r *Relayer) Address() common.Address {
+	return r.from
+}
+
+// Domain returns the EIP-712 domain players sign against
+func (r *Relayer) Domain() apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              RelayerDomainName,
+		Version:           RelayerDomainVersion,
+		ChainId:           (*math.HexOrDecimal256)(new(big.Int).Set(r.chainID)),
+		VerifyingContract: r.address.Hex(),
+	}
+}
+
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// CashOutTypedData returns the EIP-712 typed data a player signs to cash out
+func (r *Relayer) CashOutTypedData(req *CashOutRequest) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"CashOut": {
+				{Name: "player", Type: "address"},
+				{Name: "gameId", Type: "uint256"},
+				{Name: "multiplier", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "CashOut",
+		Domain:      r.Domain(),
+		Message: apitypes.TypedDataMessage{
+			"player":     req.PlayerAddress.Hex(),
+			"gameId":     req.GameID.String(),
+			"multiplier": req.Multiplier.String(),
+			"nonce":      strconv.FormatUint(req.Nonce, 10),
+		},
+	}
+}
+
+// BuyInTypedData returns the EIP-712 typed data a player signs to buy in
+func (r *Relayer) BuyInTypedData(req *BuyInRequest) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"BuyIn": {
+				{Name: "player", Type: "address"},
+				{Name: "gameId", Type: "uint256"},
+				{Name: "amount", Type: "uint256"},
+				{Name: "depositTxHash", Type: "bytes32"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "BuyIn",
+		Domain:      r.Domain(),
+		Message: apitypes.TypedDataMessage{
+			"player":        req.PlayerAddress.Hex(),
+			"gameId":        req.GameID.String(),
+			"amount":        req.Amount.String(),
+			"depositTxHash": req.DepositTxHash.Hex(),
+			"nonce":         strconv.FormatUint(req.Nonce, 10),
+		},
+	}
+}
+
+// SeedPairTypedData returns the EIP-712 typed data a player signs to change their seed pair
+func (r *Relayer) SeedPairTypedData(req *SeedPairRequest) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"SeedPair": {
+				{Name: "player", Type: "address"},
+				{Name: "action", Type: "string"},
+				{Name: "clientSeed", Type: "string"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SeedPair",
+		Domain:      r.Domain(),
+		Message: apitypes.TypedDataMessage{
+			"player":     req.PlayerAddress.Hex(),
+			"action":     req.Action,
+			"clientSeed": req.ClientSeed,
+			"nonce":      strconv.FormatUint(req.Nonce, 10),
+		},
+	}
+}
+
+// RecoverTypedDataSigner returns the address that signed the typed data.
+// Both 0/1 and 27/28 recovery ids are accepted.
+func RecoverTypedDataSigner(typedData apitypes.TypedData, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(signature))
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+
+	sig := make([]byte, len(signature))
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// verifySigner checks the signature and consumes the request nonce
+func (r *Relayer) verifySigner(ctx context.Context, typedData apitypes.TypedData, player common.Address, nonce uint64, signature []byte) error {
+	signer, err := RecoverTypedDataSigner(typedData, signature)
+	if err != nil {
+		return err
+	}
+	if signer != player {
+		return fmt.Errorf("signature does not match player %s", player.Hex())
+	}
+
+	return r.nonces.UseNonce(ctx, player, nonce)
+}
+
+// AuthorizeCashOut verifies a signed cashout request and consumes its nonce
+func (r *Relayer) AuthorizeCashOut(ctx context.Context, req *CashOutRequest) error {
+	if req.GameID == nil || req.Multiplier == nil {
+		return fmt.Errorf("incomplete cashout request")
+	}
+	return r.verifySigner(ctx, r.CashOutTypedData(req), req.PlayerAddress, req.Nonce, req.Signature)
+}
+
+// AuthorizeSeedPair verifies a signed seed pair request and consumes its nonce
+func (r *Relayer) AuthorizeSeedPair(ctx context.Context, req *SeedPairRequest) error {
+	if req.Action != SeedPairActionSetClientSeed && req.Action != SeedPairActionRotate {
+		return fmt.Errorf("unknown seed pair action %q", req.Action)
+	}
+	return r.verifySigner(ctx, r.SeedPairTypedData(req), req.PlayerAddress, req.Nonce, req.Signature)
+}
+
+// AuthorizeBuyIn verifies a signed buy-in request and its deposit, then consumes its nonce
+func (r *Relayer) AuthorizeBuyIn(ctx context.Context, req *BuyInRequest) error {
+	if req.GameID == nil || req.Amount == nil {
+		return fmt.Errorf("incomplete buy-in request")
+	}
+
+	signer, err := RecoverTypedDataSigner(r.BuyInTypedData(req), req.Signature)
+	if err != nil {
+		return err
+	}
+	if signer != req.PlayerAddress {
+		return fmt.Errorf("signature does not match player %s", req.PlayerAddress.Hex())
+	}
+
+	if err := r.VerifyDeposit(ctx, req.PlayerAddress, req.Amount, req.DepositTxHash); err != nil {
+		return err
+	}
+
+	return r.nonces.UseNonce(ctx, req.PlayerAddress, req.Nonce)
+}
+
+// VerifyDeposit checks that txHash is a successful bet() call from player
+// sending exactly amount to the contract
+func (r *Relayer) VerifyDeposit(ctx context.Context, player common.Address, amount *big.Int, txHash common.Hash) error {
+	tx, pending, err := r.backend.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("deposit transaction not found: %w", err)
+	}
+	if pending {
+		return fmt.Errorf("deposit transaction %s is still pending", txHash.Hex())
+	}
+
+	if tx.To() == nil || *tx.To() != r.address {
+		return fmt.Errorf("deposit transaction is not sent to the game contract")
+	}
+	if tx.Value().Cmp(amount) != 0 {
+		return fmt.Errorf("deposit value %s does not match bet amount %s", tx.Value(), amount)
+	}
+	if method, err := r.abi.MethodById(tx.Data()); err != nil || method.Name != "bet" {
+		return fmt.Errorf("deposit transaction is not a bet() call")
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(r.chainID), tx)
+	if err != nil {
+		return fmt.Errorf("failed to recover deposit sender: %w", err)
+	}
+	if sender != player {
+		return fmt.Errorf("deposit was sent by %s, not %s", sender.Hex(), player.Hex())
+	}
+
+	receipt, err := r.backend.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get deposit receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("deposit transaction %s reverted", txHash.Hex())
+	}
+
+	return nil
+}
+
+// RelayPayout sends payPlayer(player, amount) from the relayer wallet,
+// enforcing the relayer balance, gas price and gas limit policy
+func (r *Relayer) RelayPayout(ctx context.Context, player common.Address, amount *big.Int) (*types.Transaction, error) {
+	balance, err := r.backend.BalanceAt(ctx, r.from, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relayer balance: %w", err)
+	}
+	if balance.Cmp(r.limits.MinBalance.Int) < 0 {
+		return nil, fmt.Errorf("relayer balance %s wei below minimum %s wei", balance, r.limits.MinBalance)
+	}
+
+	gasPrice, err := r.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	if gasPrice.Cmp(r.limits.MaxGasPrice.Int) > 0 {
+		return nil, fmt.Errorf("gas price %s wei above relayer maximum %s wei", gasPrice, r.limits.MaxGasPrice)
+	}
+
+	input, err := r.abi.Pack("payPlayer", player, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack input: %w", err)
+	}
+
+	gasLimit, err := r.backend.EstimateGas(ctx, ethereum.CallMsg{
+		From: r.from,
+		To:   &r.address,
+		Data: input,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gas estimation failed: %w", err)
+	}
+	if gasLimit > r.limits.GasLimit {
+		return nil, fmt.Errorf("estimated gas %d above relayer limit %d", gasLimit, r.limits.GasLimit)
+	}
+	// +20% buffer, capped at the relayer limit
+	gasLimit = gasLimit + gasLimit*20/100
+	if gasLimit > r.limits.GasLimit {
+		gasLimit = r.limits.GasLimit
+	}
+
+	auth, err := bind.NewKeyedTransactorWithChainID(r.key, r.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+
+	nonce, release, err := r.txNonces.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth.Context = ctx
+	auth.Nonce = new(big.Int).SetUint64(nonce)
+	auth.GasPrice = gasPrice
+	auth.GasLimit = gasLimit
+
+	tx, err := r.contract.Transact(auth, "payPlayer", player, amount)
+	release(err == nil)
+	if err != nil {
+		return nil, fmt.Errorf("payPlayer failed: %w", err)
+	}
+
+	log.Printf("📤 Relayed payPlayer(player=%s, amount=%s wei) - TX: %s", player.Hex(), amount, tx.Hash().Hex())
+	return tx, nil
+}
+
+// MemoryNonceStore is an in-process NonceStore, for tests and deployments without Redis
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[common.Address]uint64
+}
+
+// NewMemoryNonceStore creates an empty in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[common.Address]uint64)}
+}
+
+// NextNonce implements NonceStore
+func (s *MemoryNonceStore) NextNonce(ctx context.Context, player common.Address) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nonces[player], nil
+}
+
+// UseNonce implements NonceStore
+func (s *MemoryNonceStore) UseNonce(ctx context.Context, player common.Address, nonce uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next := s.nonces[player]; nonce != next {
+		return fmt.Errorf("invalid nonce %d, expected %d", nonce, next)
+	}
+	s.nonces[player] = nonce + 1
+	return nil
+}
@@ -0,0 +1,273 @@
+package contract
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// relayerTestEnv is a GameHouse contract deployed on a simulated chain
+type relayerTestEnv struct {
+	backend   *simulated.Backend
+	relayer   *Relayer
+	serverKey *ecdsa.PrivateKey
+	playerKey *ecdsa.PrivateKey
+	player    common.Address
+	address   common.Address
+	abi       abi.ABI
+	chainID   *big.Int
+}
+
+func newRelayerTestEnv(t *testing.T) *relayerTestEnv {
+	t.Helper()
+
+	abiBytes, err := os.ReadFile("GameHouseNoSig.json")
+	if err != nil {
+		t.Fatalf("failed to read ABI file: %v", err)
+	}
+	var abiFile ABIFile
+	if err := json.Unmarshal(abiBytes, &abiFile); err != nil {
+		t.Fatalf("failed to parse ABI JSON: %v", err)
+	}
+	contractABI, err := abi.JSON(strings.NewReader(string(abiFile.ABI)))
+	if err != nil {
+		t.Fatalf("failed to parse contract ABI: %v", err)
+	}
+
+	serverKey, _ := crypto.GenerateKey()
+	playerKey, _ := crypto.GenerateKey()
+	server := crypto.PubkeyToAddress(serverKey.PublicKey)
+	player := crypto.PubkeyToAddress(playerKey.PublicKey)
+
+	funds := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		server: {Balance: funds},
+		player: {Balance: funds},
+	})
+	t.Cleanup(func() { backend.Close() })
+	client := backend.Client()
+
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get chain ID: %v", err)
+	}
+
+	auth, _ := bind.NewKeyedTransactorWithChainID(serverKey, chainID)
+	address, _, house, err := bind.DeployContract(auth, contractABI, common.FromHex(abiFile.Bytecode), client,
+		server, big.NewInt(0), new(big.Int).Mul(big.NewInt(10), big.NewInt(params.Ether)))
+	if err != nil {
+		t.Fatalf("failed to deploy contract: %v", err)
+	}
+	backend.Commit()
+
+	auth.Value = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	if _, err := house.Transact(auth, "fundHouse"); err != nil {
+		t.Fatalf("failed to fund house: %v", err)
+	}
+	backend.Commit()
+
+	return &relayerTestEnv{
+		backend:   backend,
+		relayer:   NewRelayer(client, address, contractABI, serverKey, chainID, NewMemoryNonceStore()),
+		serverKey: serverKey,
+		playerKey: playerKey,
+		player:    player,
+		address:   address,
+		abi:       contractABI,
+		chainID:   chainID,
+	}
+}
+
+// signTypedData signs like a wallet's eth_signTypedData_v4 (27/28 recovery id)
+func signTypedData(t *testing.T, key *ecdsa.PrivateKey, typedData apitypes.TypedData) []byte {
+	t.Helper()
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig
+}
+
+func TestAuthorizeCashOut(t *testing.T) {
+	env := newRelayerTestEnv(t)
+	ctx := context.Background()
+
+	req := &CashOutRequest{
+		PlayerAddress: env.player,
+		GameID:        big.NewInt(1700000000),
+		Multiplier:    new(big.Int).Mul(big.NewInt(2), big.NewInt(params.Ether)),
+		Nonce:         0,
+	}
+	req.Signature = signTypedData(t, env.playerKey, env.relayer.CashOutTypedData(req))
+
+	if err := env.relayer.AuthorizeCashOut(ctx, req); err != nil {
+		t.Fatalf("valid cashout rejected: %v", err)
+	}
+	if err := env.relayer.AuthorizeCashOut(ctx, req); err == nil {
+		t.Error("replayed cashout accepted")
+	}
+
+	// Signed by someone other than the player
+	forged := *req
+	forged.Nonce = 1
+	forged.Signature = signTypedData(t, env.serverKey, env.relayer.CashOutTypedData(&forged))
+	if err := env.relayer.AuthorizeCashOut(ctx, &forged); err == nil {
+		t.Error("cashout signed by another key accepted")
+	}
+
+	// Multiplier changed after signing
+	tampered := *req
+	tampered.Nonce = 1
+	tampered.Signature = signTypedData(t, env.playerKey, env.relayer.CashOutTypedData(&tampered))
+	tampered.Multiplier = new(big.Int).Mul(big.NewInt(5), big.NewInt(params.Ether))
+	if err := env.relayer.AuthorizeCashOut(ctx, &tampered); err == nil {
+		t.Error("tampered cashout accepted")
+	}
+}
+
+func TestAuthorizeSeedPair(t *testing.T) {
+	env := newRelayerTestEnv(t)
+	ctx := context.Background()
+
+	req := &SeedPairRequest{
+		PlayerAddress: env.player,
+		Action:        SeedPairActionSetClientSeed,
+		ClientSeed:    "lucky",
+		Nonce:         0,
+	}
+	req.Signature = signTypedData(t, env.playerKey, env.relayer.SeedPairTypedData(req))
+
+	if err := env.relayer.AuthorizeSeedPair(ctx, req); err != nil {
+		t.Fatalf("valid seed pair request rejected: %v", err)
+	}
+	if err := env.relayer.AuthorizeSeedPair(ctx, req); err == nil {
+		t.Error("replayed seed pair request accepted")
+	}
+
+	// A signed client seed change cannot be turned into a rotation
+	tampered := *req
+	tampered.Nonce = 1
+	tampered.Signature = signTypedData(t, env.playerKey, env.relayer.SeedPairTypedData(&tampered))
+	tampered.Action = SeedPairActionRotate
+	if err := env.relayer.AuthorizeSeedPair(ctx, &tampered); err == nil {
+		t.Error("tampered seed pair request accepted")
+	}
+
+	forged := *req
+	forged.Nonce = 1
+	forged.Signature = signTypedData(t, env.serverKey, env.relayer.SeedPairTypedData(&forged))
+	if err := env.relayer.AuthorizeSeedPair(ctx, &forged); err == nil {
+		t.Error("seed pair request signed by another key accepted")
+	}
+
+	unknown := *req
+	unknown.Action = "reveal"
+	unknown.Nonce = 1
+	unknown.Signature = signTypedData(t, env.playerKey, env.relayer.SeedPairTypedData(&unknown))
+	if err := env.relayer.AuthorizeSeedPair(ctx, &unknown); err == nil {
+		t.Error("unknown action accepted")
+	}
+}
+
+func TestRelayPayout(t *testing.T) {
+	env := newRelayerTestEnv(t)
+	ctx := context.Background()
+	client := env.backend.Client()
+
+	before, err := client.BalanceAt(ctx, env.player, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount := big.NewInt(params.Ether)
+	tx, err := env.relayer.RelayPayout(ctx, env.player, amount)
+	if err != nil {
+		t.Fatalf("RelayPayout failed: %v", err)
+	}
+	if tx.Gas() > 150000 {
+		t.Errorf("gas limit %d above relayer limit", tx.Gas())
+	}
+	env.backend.Commit()
+
+	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("payPlayer reverted")
+	}
+
+	after, err := client.BalanceAt(ctx, env.player, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := new(big.Int).Sub(after, before); got.Cmp(amount) != 0 {
+		t.Errorf("player received %s wei, want %s", got, amount)
+	}
+}
+
+func TestRelayPayoutRequiresMinBalance(t *testing.T) {
+	env := newRelayerTestEnv(t)
+
+	emptyKey, _ := crypto.GenerateKey()
+	broke := NewRelayer(env.backend.Client(), env.address, env.abi, emptyKey, env.chainID, NewMemoryNonceStore())
+
+	if _, err := broke.RelayPayout(context.Background(), env.player, big.NewInt(1)); err == nil {
+		t.Error("payout relayed from a wallet below the minimum balance")
+	}
+}
+
+func TestAuthorizeBuyIn(t *testing.T) {
+	env := newRelayerTestEnv(t)
+	ctx := context.Background()
+	client := env.backend.Client()
+
+	amount := new(big.Int).Div(big.NewInt(params.Ether), big.NewInt(10))
+	auth, _ := bind.NewKeyedTransactorWithChainID(env.playerKey, env.chainID)
+	auth.Value = amount
+	house := bind.NewBoundContract(env.address, env.abi, client, client, client)
+	deposit, err := house.Transact(auth, "bet")
+	if err != nil {
+		t.Fatalf("bet() failed: %v", err)
+	}
+	env.backend.Commit()
+
+	req := &BuyInRequest{
+		PlayerAddress: env.player,
+		GameID:        big.NewInt(1700000000),
+		Amount:        amount,
+		DepositTxHash: deposit.Hash(),
+	}
+
+	// Claiming more than was deposited must fail
+	inflated := *req
+	inflated.Amount = new(big.Int).Mul(amount, big.NewInt(2))
+	inflated.Signature = signTypedData(t, env.playerKey, env.relayer.BuyInTypedData(&inflated))
+	if err := env.relayer.AuthorizeBuyIn(ctx, &inflated); err == nil {
+		t.Error("buy-in above the deposit accepted")
+	}
+
+	req.Signature = signTypedData(t, env.playerKey, env.relayer.BuyInTypedData(req))
+	if err := env.relayer.AuthorizeBuyIn(ctx, req); err != nil {
+		t.Fatalf("valid buy-in rejected: %v", err)
+	}
+}
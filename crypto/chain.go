@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// ChainCheckpointInterval is the distance between links kept in memory.
+// Any other link is recomputed from the checkpoint below it.
+const ChainCheckpointInterval = 10000
+
+// HashChain is a reverse SHA-256 hash chain of pre-committed round seeds.
+//
+// Links are generated forward from a secret root: link[0] = root and
+// link[i+1] = hex(SHA-256(link[i])). The last link is the terminating hash,
+// published before any round is played. Rounds consume the chain backwards:
+// round n (1-based) uses link[length-n], so hashing a round's seed gives the
+// seed of the round before it, and hashing it n times gives the terminating hash.
+type HashChain struct {
+	length          uint64
+	checkpoints     []string
+	terminatingHash string
+}
+
+// GenerateChainRoot creates a random secret root for a new hash chain
+func GenerateChainRoot() string {
+	seed, _ := GenerateServerSeed()
+	return seed
+}
+
+// NewHashChain builds the chain of the given length from its root
+func NewHashChain(root string, length uint64) *HashChain {
+	checkpoints := make([]string, 0, length/ChainCheckpointInterval+1)
+
+	link := root
+	for i := uint64(0); i < length; i++ {
+		if i%ChainCheckpointInterval == 0 {
+			checkpoints = append(checkpoints, link)
+		}
+		link = HashSeed(link)
+	}
+
+	return &HashChain{
+		length:          length,
+		checkpoints:     checkpoints,
+		terminatingHash: link,
+	}
+}
+
+// Length returns the number of rounds the chain can seed
+func (c *HashChain) Length() uint64 {
+	return c.length
+}
+
+// TerminatingHash returns the public commitment for the whole chain
+func (c *HashChain) TerminatingHash() string {
+	return c.terminatingHash
+}
+
+// Seed returns the seed for round n (1-based)
+func (c *HashChain) Seed(round uint64) (string, error) {
+	if round == 0 || round > c.length {
+		return "", fmt.Errorf("round %d out of chain range 1-%d", round, c.length)
+	}
+
+	index := c.length - round
+	checkpoint := index / ChainCheckpointInterval
+
+	link := c.checkpoints[checkpoint]
+	for i := checkpoint * ChainCheckpointInterval; i < index; i++ {
+		link = HashSeed(link)
+	}
+	return link, nil
+}
+
+// VerifyChainSeed checks that hashing seed round times reaches the terminating hash.
+// It costs one hash per round, so servers check single links with VerifyChainLink.
+func VerifyChainSeed(seed string, round uint64, terminatingHash string) bool {
+	if round == 0 {
+		return false
+	}
+
+	link := seed
+	for i := uint64(0); i < round; i++ {
+		link = HashSeed(link)
+	}
+	return link == terminatingHash
+}
+
+// VerifyChainLink checks a round's seed against the round before it: round 1
+// must hash to the terminating hash, any later round to the previous seed.
+func VerifyChainLink(seed string, round uint64, previousSeed, terminatingHash string) bool {
+	switch round {
+	case 0:
+		return false
+	case 1:
+		return HashSeed(seed) == terminatingHash
+	default:
+		return previousSeed != "" && HashSeed(seed) == previousSeed
+	}
+}
+
+// SealChainRoot encrypts a chain root with AES-256-GCM for storage. The
+// terminating hash is authenticated with it, so a sealed root cannot be
+// swapped onto another chain. Returns hex(nonce || ciphertext).
+func SealChainRoot(key []byte, root, terminatingHash string) (string, error) {
+	gcm, err := chainRootCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(root), []byte(terminatingHash))
+	return hex.EncodeToString(sealed), nil
+}
+
+// OpenChainRoot decrypts a root sealed by SealChainRoot
+func OpenChainRoot(key []byte, sealed, terminatingHash string) (string, error) {
+	gcm, err := chainRootCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := hex.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed chain root is malformed")
+	}
+
+	root, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(terminatingHash))
+	if err != nil {
+		return "", fmt.Errorf("failed to open chain root: %w", err)
+	}
+	return string(root), nil
+}
+
+func chainRootCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("chain root key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHashChain(t *testing.T) {
+	const length = 2*ChainCheckpointInterval + 17
+	chain := NewHashChain("test-root", length)
+
+	if chain.Length() != length {
+		t.Fatalf("length %d, want %d", chain.Length(), length)
+	}
+
+	// Round 1 hashes to the terminating hash, and every round hashes to the one before it
+	first, err := chain.Seed(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HashSeed(first) != chain.TerminatingHash() {
+		t.Fatal("round 1 does not hash to the terminating hash")
+	}
+	for _, round := range []uint64{2, ChainCheckpointInterval, ChainCheckpointInterval + 1, length - 1, length} {
+		seed, err := chain.Seed(round)
+		if err != nil {
+			t.Fatal(err)
+		}
+		previous, _ := chain.Seed(round - 1)
+		if HashSeed(seed) != previous {
+			t.Fatalf("round %d does not hash to round %d", round, round-1)
+		}
+	}
+
+	// The last round is the root itself
+	if last, _ := chain.Seed(length); last != "test-root" {
+		t.Fatalf("round %d = %s, want the root", length, last)
+	}
+
+	for _, round := range []uint64{0, length + 1} {
+		if _, err := chain.Seed(round); err == nil {
+			t.Errorf("expected error for round %d", round)
+		}
+	}
+}
+
+func TestVerifyChainSeed(t *testing.T) {
+	chain := NewHashChain("verify-root", 100)
+	seed, _ := chain.Seed(42)
+
+	if !VerifyChainSeed(seed, 42, chain.TerminatingHash()) {
+		t.Fatal("round 42 seed did not verify")
+	}
+	if VerifyChainSeed(seed, 41, chain.TerminatingHash()) {
+		t.Fatal("seed verified at the wrong round")
+	}
+	if VerifyChainSeed(seed, 0, chain.TerminatingHash()) {
+		t.Fatal("round 0 verified")
+	}
+}
+
+func TestVerifyChainLink(t *testing.T) {
+	chain := NewHashChain("link-root", 100)
+	first, _ := chain.Seed(1)
+	seed, _ := chain.Seed(42)
+	previous, _ := chain.Seed(41)
+
+	if !VerifyChainLink(first, 1, "", chain.TerminatingHash()) {
+		t.Fatal("round 1 seed did not verify against the terminating hash")
+	}
+	if !VerifyChainLink(seed, 42, previous, chain.TerminatingHash()) {
+		t.Fatal("round 42 seed did not verify against round 41")
+	}
+	if VerifyChainLink(previous, 42, seed, chain.TerminatingHash()) {
+		t.Fatal("link verified in the wrong direction")
+	}
+	if VerifyChainLink(seed, 42, "", chain.TerminatingHash()) {
+		t.Fatal("link verified without the previous seed")
+	}
+	if VerifyChainLink(seed, 0, previous, chain.TerminatingHash()) {
+		t.Fatal("round 0 verified")
+	}
+}
+
+func TestSealChainRoot(t *testing.T) {
+	key := make([]byte, 32)
+	key[0] = 1
+	chain := NewHashChain("sealed-root", 10)
+
+	sealed, err := SealChainRoot(key, "sealed-root", chain.TerminatingHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, hex.EncodeToString([]byte("sealed-root"))) {
+		t.Fatal("sealed root contains the plaintext")
+	}
+
+	root, err := OpenChainRoot(key, sealed, chain.TerminatingHash())
+	if err != nil || root != "sealed-root" {
+		t.Fatalf("OpenChainRoot = %q, %v", root, err)
+	}
+
+	if _, err := OpenChainRoot(key, sealed, "other-chain"); err == nil {
+		t.Error("sealed root opened for another chain")
+	}
+	otherKey := make([]byte, 32)
+	if _, err := OpenChainRoot(otherKey, sealed, chain.TerminatingHash()); err == nil {
+		t.Error("sealed root opened with the wrong key")
+	}
+	if _, err := OpenChainRoot(key, "sealed-root", chain.TerminatingHash()); err == nil {
+		t.Error("plaintext root opened")
+	}
+	if _, err := SealChainRoot(key[:16], "sealed-root", chain.TerminatingHash()); err == nil {
+		t.Error("expected error for a short key")
+	}
+}
+
+func BenchmarkHashChainSeed(b *testing.B) {
+	chain := NewHashChain("bench-root", 4*ChainCheckpointInterval)
+	for i := 0; i < b.N; i++ {
+		chain.Seed(uint64(i%int(chain.Length())) + 1)
+	}
+}
@@ -1,9 +1,14 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 func GenerateServerSeed() (seed string, hash string) {
@@ -11,14 +16,67 @@ func GenerateServerSeed() (seed string, hash string) {
 	rand.Read(bytes)
 
 	seed = hex.EncodeToString(bytes)
-
-	h := sha256.Sum256([]byte(seed))
-	hash = hex.EncodeToString(h[:])
+	hash = HashSeed(seed)
 
 	return
 }
 
-func VerifySeed(seed, hash string) bool {
+// HashSeed returns the public commitment for a seed: hex(SHA-256(seed))
+func HashSeed(seed string) string {
 	h := sha256.Sum256([]byte(seed))
-	return hex.EncodeToString(h[:]) == hash
+	return hex.EncodeToString(h[:])
+}
+
+func VerifySeed(seed, hash string) bool {
+	return HashSeed(seed) == hash
+}
+
+// GenerateClientSeed creates a random default client seed for players who have not set one
+func GenerateClientSeed() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// RoundSeed derives the seed for a single round from a committed server seed,
+// the player's client seed and the round nonce:
+// hex(HMAC-SHA256(key=serverSeed, message=clientSeed + ":" + nonce))
+func RoundSeed(serverSeed, clientSeed string, nonce uint64) string {
+	mac := hmac.New(sha256.New, []byte(serverSeed))
+	mac.Write([]byte(clientSeed + ":" + strconv.FormatUint(nonce, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CandleflipRoomSeed derives the seed of batch room i (0-based). Seed pair
+// batches use RoundSeed with nonce + i, legacy batches without a client seed
+// use "serverSeed-room-i".
+func CandleflipRoomSeed(serverSeed, clientSeed string, nonce uint64, i int) string {
+	if clientSeed == "" {
+		return fmt.Sprintf("%s-room-%d", serverSeed, i)
+	}
+	return RoundSeed(serverSeed, clientSeed, nonce+uint64(i))
+}
+
+// CrashClientSeed combines the client seeds of a crash round's players into
+// the round's client seed: hex(SHA-256) of their "address:clientSeed" lines,
+// sorted by lowercase address and newline terminated
+func CrashClientSeed(clientSeeds map[string]string) string {
+	lines := make([]string, 0, len(clientSeeds))
+	for address, clientSeed := range clientSeeds {
+		lines = append(lines, strings.ToLower(address)+":"+clientSeed+"\n")
+	}
+	sort.Strings(lines)
+
+	h := sha256.Sum256([]byte(strings.Join(lines, "")))
+	return hex.EncodeToString(h[:])
+}
+
+// CrashRoundSeed derives the seed a crash round is played with from its chain
+// seed: RoundSeed with the round's client seed and the chain round as nonce.
+// Rounds played before client seeds used the chain seed itself.
+func CrashRoundSeed(serverSeed, clientSeed string, chainRound uint64) string {
+	if clientSeed == "" {
+		return serverSeed
+	}
+	return RoundSeed(serverSeed, clientSeed, chainRound)
 }
@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashSeed(t *testing.T) {
+	// SHA-256 test vector from FIPS 180-2
+	if got, want := HashSeed("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Fatalf("HashSeed(abc) = %s, want %s", got, want)
+	}
+	if !VerifySeed("abc", HashSeed("abc")) || VerifySeed("abd", HashSeed("abc")) {
+		t.Fatal("VerifySeed does not match HashSeed")
+	}
+}
+
+func TestGenerateServerSeed(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		seed, hash := GenerateServerSeed()
+		if len(seed) != 64 {
+			t.Fatalf("seed %q is not 32 hex bytes", seed)
+		}
+		if !VerifySeed(seed, hash) {
+			t.Fatalf("hash %s does not commit to seed %s", hash, seed)
+		}
+		if seen[seed] {
+			t.Fatalf("seed %s generated twice", seed)
+		}
+		seen[seed] = true
+	}
+}
+
+// TestRoundSeedVector pins the seed pair derivation so third-party verifiers
+// can check their implementation against the same value.
+func TestRoundSeedVector(t *testing.T) {
+	want := "171447286fd2e39c612481fcd000c294235454673c6b0ac5a97051dd51db50a7"
+	if got := RoundSeed("server", "client", 7); got != want {
+		t.Fatalf("RoundSeed(server, client, 7) = %s, want %s", got, want)
+	}
+	if RoundSeed("server", "client", 7) == RoundSeed("server", "client", 8) {
+		t.Fatal("different nonces produced the same round seed")
+	}
+}
+
+func TestCandleflipRoomSeed(t *testing.T) {
+	if got := CandleflipRoomSeed("server", "", 0, 3); got != "server-room-3" {
+		t.Errorf("legacy room seed = %s, want server-room-3", got)
+	}
+	if got, want := CandleflipRoomSeed("server", "client", 5, 2), RoundSeed("server", "client", 7); got != want {
+		t.Errorf("seed pair room seed = %s, want %s", got, want)
+	}
+}
+
+func TestCrashRoundSeed(t *testing.T) {
+	if got := CrashRoundSeed("server", "", 9); got != "server" {
+		t.Errorf("round without a client seed = %s, want the chain seed", got)
+	}
+	if got, want := CrashRoundSeed("server", "client", 9), RoundSeed("server", "client", 9); got != want {
+		t.Errorf("CrashRoundSeed = %s, want %s", got, want)
+	}
+
+	// The combined client seed does not depend on map order or address case
+	seeds := map[string]string{"0xAbC": "one", "0xdef": "two"}
+	lower := map[string]string{"0xabc": "one", "0xdef": "two"}
+	if CrashClientSeed(seeds) != CrashClientSeed(lower) {
+		t.Error("client seed depends on address case")
+	}
+	if CrashClientSeed(seeds) == CrashClientSeed(map[string]string{"0xabc": "one", "0xdef": "three"}) {
+		t.Error("client seed ignores a player's seed")
+	}
+	if got, want := CrashClientSeed(nil), HashSeed(""); got != want {
+		t.Errorf("empty round client seed = %s, want %s", got, want)
+	}
+}
+
+func BenchmarkRoundSeed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		RoundSeed("server", "client", uint64(i))
+	}
+}
+
+func BenchmarkHashSeed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		HashSeed(strconv.Itoa(i))
+	}
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"goLangServer/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/* =========================
+   HUB CHANNEL FUNCTIONS
+========================= */
+
+// HubEnvelope is a hub channel message as published through Redis
+type HubEnvelope struct {
+	Channel string          `json:"-"`
+	Seq     uint64          `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// publishHubScript assigns the channel's next sequence number and publishes
+// the message in one step, so every subscriber sees a channel's messages in
+// sequence order whichever instance published them. With a history key the
+// message is also kept in the channel's history list, trimmed to ARGV[3].
+var publishHubScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+local envelope = '{"seq":' .. seq .. ',"payload":' .. ARGV[2] .. '}'
+redis.call('PUBLISH', ARGV[1], envelope)
+if KEYS[2] then
+	redis.call('RPUSH', KEYS[2], envelope)
+	redis.call('LTRIM', KEYS[2], -tonumber(ARGV[3]), -1)
+end
+return seq
+`)
+
+// PublishHubMessage publishes a JSON payload on a hub channel and returns the
+// sequence number it was given. A positive historyLen keeps the message in the
+// channel's history, which holds the last historyLen messages.
+func PublishHubMessage(ctx context.Context, channel string, payload []byte, historyLen int) (uint64, error) {
+	keys := []string{fmt.Sprintf(config.RedisHubSeqKey, channel)}
+	if historyLen > 0 {
+		keys = append(keys, fmt.Sprintf(config.RedisHubHistoryKey, channel))
+	}
+
+	seq, err := publishHubScript.Run(ctx, RedisClient, keys,
+		fmt.Sprintf(config.RedisHubChannel, channel), string(payload), historyLen).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish hub message on %s: %w", channel, err)
+	}
+	return seq, nil
+}
+
+// SubscribeHub subscribes to every hub channel
+func SubscribeHub(ctx context.Context) *redis.PubSub {
+	return RedisClient.PSubscribe(ctx, config.RedisHubPattern)
+}
+
+// ParseHubMessage decodes a message received from SubscribeHub
+func ParseHubMessage(msg *redis.Message) (*HubEnvelope, error) {
+	prefix := strings.TrimSuffix(config.RedisHubPattern, "*")
+	if !strings.HasPrefix(msg.Channel, prefix) {
+		return nil, fmt.Errorf("unexpected hub channel %s", msg.Channel)
+	}
+
+	envelope, err := parseHubEnvelope(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Channel = strings.TrimPrefix(msg.Channel, prefix)
+	return envelope, nil
+}
+
+// GetHubHistory returns the messages kept in a channel's history, oldest first
+func GetHubHistory(ctx context.Context, channel string) ([]*HubEnvelope, error) {
+	entries, err := RedisClient.LRange(ctx, fmt.Sprintf(config.RedisHubHistoryKey, channel), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s history: %w", channel, err)
+	}
+
+	history := make([]*HubEnvelope, 0, len(entries))
+	for _, entry := range entries {
+		envelope, err := parseHubEnvelope(entry)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Channel = channel
+		history = append(history, envelope)
+	}
+	return history, nil
+}
+
+func parseHubEnvelope(data string) (*HubEnvelope, error) {
+	var envelope HubEnvelope
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal hub message: %w", err)
+	}
+	return &envelope, nil
+}
+
+/* =========================
+   GLOBAL ROOM FUNCTIONS
+========================= */
+
+// StoreGlobalRoom stores a room of the global room list as JSON
+func StoreGlobalRoom(ctx context.Context, roomID string, data []byte) error {
+	if err := RedisClient.Set(ctx, fmt.Sprintf(config.RedisGlobalRoomKey, roomID), data, config.GlobalRoomTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store global room: %w", err)
+	}
+	if err := RedisClient.SAdd(ctx, config.RedisGlobalRoomsKey, roomID).Err(); err != nil {
+		return fmt.Errorf("failed to add global room: %w", err)
+	}
+	return nil
+}
+
+// GetGlobalRoom returns a room's JSON, or nil if the room does not exist
+func GetGlobalRoom(ctx context.Context, roomID string) ([]byte, error) {
+	data, err := RedisClient.Get(ctx, fmt.Sprintf(config.RedisGlobalRoomKey, roomID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global room: %w", err)
+	}
+	return data, nil
+}
+
+// GetGlobalRooms returns the JSON of every room in the global room list.
+// Rooms whose record has expired are dropped from the list.
+func GetGlobalRooms(ctx context.Context) ([][]byte, error) {
+	roomIDs, err := RedisClient.SMembers(ctx, config.RedisGlobalRoomsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global rooms: %w", err)
+	}
+	if len(roomIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(roomIDs))
+	for i, roomID := range roomIDs {
+		keys[i] = fmt.Sprintf(config.RedisGlobalRoomKey, roomID)
+	}
+	values, err := RedisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global rooms: %w", err)
+	}
+
+	rooms := make([][]byte, 0, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			RedisClient.SRem(ctx, config.RedisGlobalRoomsKey, roomIDs[i])
+			continue
+		}
+		rooms = append(rooms, []byte(data))
+	}
+	return rooms, nil
+}
+
+// DeleteGlobalRoom removes a room from the global room list
+func DeleteGlobalRoom(ctx context.Context, roomID string) error {
+	if err := RedisClient.SRem(ctx, config.RedisGlobalRoomsKey, roomID).Err(); err != nil {
+		return fmt.Errorf("failed to remove global room: %w", err)
+	}
+	RedisClient.Del(ctx, fmt.Sprintf(config.RedisGlobalRoomKey, roomID))
+	return nil
+}
+
+/* =========================
+   QUEUE FUNCTIONS
+========================= */
+
+// PushQueue appends a message to a queue, which expires after ttl unless
+// another message is pushed
+func PushQueue(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	if err := RedisClient.RPush(ctx, key, data).Err(); err != nil {
+		return fmt.Errorf("failed to push to %s: %w", key, err)
+	}
+	RedisClient.PExpire(ctx, key, ttl)
+	return nil
+}
+
+// PopQueue waits up to timeout for the next message of a queue. Returns nil
+// if none arrived.
+func PopQueue(ctx context.Context, key string, timeout time.Duration) ([]byte, error) {
+	result, err := RedisClient.BLPop(ctx, timeout, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop from %s: %w", key, err)
+	}
+	return []byte(result[1]), nil
+}
+
+/* =========================
+   LEASE FUNCTIONS
+========================= */
+
+// acquireLeaseScript takes the lease if it is free, or renews it if ARGV[1]
+// already holds it. Returns 1 if ARGV[1] holds the lease.
+var acquireLeaseScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder == false or holder == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// AcquireLease takes or renews the lease at key for owner, for ttl.
+// It reports whether owner holds the lease.
+func AcquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	held, err := acquireLeaseScript.Run(ctx, RedisClient, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", key, err)
+	}
+	return held == 1, nil
+}
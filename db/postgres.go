@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
+	"math/big"
+	"strings"
 	"time"
 
+	"goLangServer/config"
 	"goLangServer/game"
 
 	"github.com/jackc/pgx/v5"
@@ -21,23 +23,102 @@ var (
 
 // CrashHistoryRecord represents a crash game history record
 type CrashHistoryRecord struct {
-	GameID             string             `json:"gameId"`
-	ServerSeed         string             `json:"serverSeed"`
-	ServerSeedHash     string             `json:"serverSeedHash"`
-	Peak               float64            `json:"peak"`
-	CandlestickHistory []game.CandleGroup `json:"candlestickHistory"`
-	Rugged             bool               `json:"rugged"`
-	CreatedAt          time.Time          `json:"createdAt"`
+	GameID             string                 `json:"gameId"`
+	ServerSeed         string                 `json:"serverSeed"`
+	ServerSeedHash     string                 `json:"serverSeedHash"`
+	ClientSeed         string                 `json:"clientSeed"` // Combined client seed of the round's players, empty for older rounds
+	Peak               float64                `json:"peak"`
+	CandlestickHistory []game.CandleGroup     `json:"candlestickHistory"`
+	Rugged             bool                   `json:"rugged"`
+	RNGVersion         int                    `json:"rngVersion"`
+	Distribution       game.CrashDistribution `json:"distribution"`    // Zero for rounds played before distributions were stored
+	CandleLayout       game.CandleLayout      `json:"candleLayout"`    // Zero for rounds played before candle layouts were stored
+	ChainRound         uint64                 `json:"chainRound"`      // 0 for rounds not seeded from the hash chain
+	TerminatingHash    string                 `json:"terminatingHash"` // Commitment of the chain the seed belongs to
+	Recovered          bool                   `json:"recovered"`       // Settled by startup recovery after a restart
+	CreatedAt          time.Time              `json:"createdAt"`
+}
+
+// CandleflipBatchRecord represents a persisted candleflip batch and its rooms
+type CandleflipBatchRecord struct {
+	BatchID        string                  `json:"batchId"`
+	PlayerAddress  string                  `json:"playerAddress"`
+	AmountPerRoom  string                  `json:"amountPerRoom"` // Wei as string
+	TotalRooms     int                     `json:"totalRooms"`
+	PlayerSide     string                  `json:"playerSide"`
+	ServerSeed     string                  `json:"-"`
+	ServerSeedHash string                  `json:"serverSeedHash"`
+	ClientSeed     string                  `json:"clientSeed"`
+	Nonce          uint64                  `json:"nonce"`
+	RNGVersion     int                     `json:"rngVersion"`
+	PriceModel     int                     `json:"priceModel"`
+	Odds           string                  `json:"odds"` // Payout multiplier with 18 decimals
+	Status         string                  `json:"status"`
+	WonRooms       int                     `json:"wonRooms"`
+	PayoutAmount   string                  `json:"payoutAmount"` // Wei as string, empty until calculated
+	PayoutTxHash   string                  `json:"payoutTxHash"`
+	PayoutError    string                  `json:"payoutError"`
+	CreatedAt      time.Time               `json:"createdAt"`
+	CompletedAt    *time.Time              `json:"completedAt,omitempty"`
+	Rooms          []*CandleflipRoomRecord `json:"rooms"`
+}
+
+// CandleflipRoomRecord represents a persisted candleflip room
+type CandleflipRoomRecord struct {
+	RoomNumber int        `json:"roomNumber"`
+	Status     string     `json:"status"`
+	FinalPrice float64    `json:"finalPrice"`
+	Winner     string     `json:"winner"`
+	PlayerWon  bool       `json:"playerWon"`
+	StartTime  *time.Time `json:"startTime,omitempty"`
+	EndTime    *time.Time `json:"endTime,omitempty"`
+}
+
+// Payout queue states
+const (
+	PayoutStatusPending   = "pending"   // Waiting to be submitted
+	PayoutStatusSent      = "sent"      // Signed and broadcast, waiting for a receipt
+	PayoutStatusConfirmed = "confirmed" // Mined successfully
+	PayoutStatusFailed    = "failed"    // Gave up after MaxRetries attempts
+)
+
+// PayoutRecord represents a queued on-chain payPlayer payout
+type PayoutRecord struct {
+	ID               int64      `json:"id"`
+	Kind             string     `json:"kind"`      // "candleflip" or "crash"
+	Reference        string     `json:"reference"` // Batch ID or crash game ID
+	PlayerAddress    string     `json:"playerAddress"`
+	Amount           string     `json:"amount"` // Wei as string
+	Status           string     `json:"status"`
+	TxHash           string     `json:"txHash,omitempty"`
+	ReplacedTxHashes []string   `json:"replacedTxHashes,omitempty"` // Earlier broadcasts of the same nonce
+	TxNonce          *uint64    `json:"txNonce,omitempty"`
+	GasPrice         string     `json:"gasPrice,omitempty"`
+	Attempts         int        `json:"attempts"`
+	LastError        string     `json:"lastError,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	SentAt           *time.Time `json:"sentAt,omitempty"`
+	ConfirmedAt      *time.Time `json:"confirmedAt,omitempty"`
+}
+
+// SeedChainRecord represents a pre-committed crash seed hash chain
+type SeedChainRecord struct {
+	ID              int64     `json:"id"`
+	SealedRoot      string    `json:"-"` // Chain root encrypted with crypto.SealChainRoot
+	ChainLength     uint64    `json:"chainLength"`
+	TerminatingHash string    `json:"terminatingHash"`
+	RoundsUsed      uint64    `json:"roundsUsed"`
+	CreatedAt       time.Time `json:"createdAt"`
 }
 
 // InitPostgres initializes the PostgreSQL connection pool
-func InitPostgres() error {
+func InitPostgres(cfg config.PostgresConfig) error {
 	log.Println("🔌 Connecting to PostgreSQL (Supabase)...")
 
-	// Get DATABASE_URL from environment
-	databaseURL := os.Getenv("DATABASE_URL")
+	databaseURL := cfg.URL
 	if databaseURL == "" {
-		return fmt.Errorf("DATABASE_URL environment variable not set")
+		return fmt.Errorf("database URL not configured (DATABASE_URL)")
 	}
 
 	// Create connection pool
@@ -50,9 +131,9 @@ func InitPostgres() error {
 	}
 
 	// Configure pool settings
-	poolConfig.MaxConns = 25
-	poolConfig.MinConns = 5
-	poolConfig.MaxConnLifetime = 5 * time.Minute
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime.D()
 
 	// Create pool
 	PostgresPool, err = pgxpool.NewWithConfig(ctx, poolConfig)
@@ -105,12 +186,135 @@ func InitSchema(ctx context.Context) error {
 
 	-- Index on created_at for time-based queries
 	CREATE INDEX IF NOT EXISTS idx_crash_history_created_at ON crash_history(created_at DESC);
+
+	-- RNG version used to play the round (1 = legacy math/rand)
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS rng_version INTEGER NOT NULL DEFAULT 1;
+
+	-- Hash chain position of the round's seed (0 = not chain seeded)
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS chain_round BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS terminating_hash TEXT NOT NULL DEFAULT '';
+
+	-- Index on the chain position for verifying a round against the one before it
+	CREATE INDEX IF NOT EXISTS idx_crash_history_chain ON crash_history(terminating_hash, chain_round);
+
+	-- Combined client seed of the round's players ('' = played with the chain seed itself)
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS client_seed TEXT NOT NULL DEFAULT '';
+
+	-- Rounds interrupted by a restart and settled by startup recovery
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS recovered BOOLEAN NOT NULL DEFAULT FALSE;
+
+	-- Crash point distribution the round was drawn from ('{}' = legacy buckets)
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS distribution JSONB NOT NULL DEFAULT '{}';
+
+	-- Candle grouping the round was stored with ('{}' = 500ms ticks, 1s candles, merged at 25)
+	ALTER TABLE crash_history ADD COLUMN IF NOT EXISTS candle_layout JSONB NOT NULL DEFAULT '{}';
 	`
 
 	if _, err := PostgresPool.Exec(ctx, crashHistorySchema); err != nil {
 		return fmt.Errorf("failed to create crash_history table: %w", err)
 	}
 
+	// Create crash_seed_chain table
+	seedChainSchema := `
+	CREATE TABLE IF NOT EXISTS crash_seed_chain (
+		id SERIAL PRIMARY KEY,
+		root_seed TEXT NOT NULL,
+		chain_length BIGINT NOT NULL,
+		terminating_hash TEXT NOT NULL UNIQUE,
+		rounds_used BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- root_seed holds the root sealed with crash.seedChainKey. Plaintext roots
+	-- written by older versions fail to open and their chain is replaced.
+	`
+
+	if _, err := PostgresPool.Exec(ctx, seedChainSchema); err != nil {
+		return fmt.Errorf("failed to create crash_seed_chain table: %w", err)
+	}
+
+	// Create candleflip tables
+	candleflipSchema := `
+	CREATE TABLE IF NOT EXISTS candleflip_batches (
+		batch_id TEXT PRIMARY KEY,
+		player_address TEXT NOT NULL,
+		amount_per_room TEXT NOT NULL,
+		total_rooms INTEGER NOT NULL,
+		player_side TEXT NOT NULL,
+		server_seed TEXT NOT NULL,
+		server_seed_hash TEXT NOT NULL,
+		client_seed TEXT NOT NULL DEFAULT '',
+		nonce BIGINT NOT NULL DEFAULT 0,
+		rng_version INTEGER NOT NULL DEFAULT 1,
+		status TEXT NOT NULL,
+		won_rooms INTEGER NOT NULL DEFAULT 0,
+		payout_amount TEXT NOT NULL DEFAULT '',
+		payout_tx_hash TEXT NOT NULL DEFAULT '',
+		payout_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		completed_at TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- Index on player for per-address history
+	CREATE INDEX IF NOT EXISTS idx_candleflip_batches_player ON candleflip_batches(player_address, created_at DESC);
+
+	-- Index on status for resuming unfinished batches
+	CREATE INDEX IF NOT EXISTS idx_candleflip_batches_status ON candleflip_batches(status);
+
+	CREATE TABLE IF NOT EXISTS candleflip_rooms (
+		batch_id TEXT NOT NULL REFERENCES candleflip_batches(batch_id) ON DELETE CASCADE,
+		room_number INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		final_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+		winner TEXT NOT NULL DEFAULT '',
+		player_won BOOLEAN NOT NULL DEFAULT FALSE,
+		start_time TIMESTAMP,
+		end_time TIMESTAMP,
+		PRIMARY KEY (batch_id, room_number)
+	);
+
+	-- Candleflip price model the rooms are played with (1 = linear)
+	ALTER TABLE candleflip_batches ADD COLUMN IF NOT EXISTS price_model INTEGER NOT NULL DEFAULT 1;
+
+	-- Payout per won room with 18 decimals (batches before dynamic odds paid 2x)
+	ALTER TABLE candleflip_batches ADD COLUMN IF NOT EXISTS odds TEXT NOT NULL DEFAULT '2000000000000000000';
+	`
+
+	if _, err := PostgresPool.Exec(ctx, candleflipSchema); err != nil {
+		return fmt.Errorf("failed to create candleflip tables: %w", err)
+	}
+
+	// Create payouts table
+	payoutsSchema := `
+	CREATE TABLE IF NOT EXISTS payouts (
+		id BIGSERIAL PRIMARY KEY,
+		kind TEXT NOT NULL,
+		reference TEXT NOT NULL,
+		player_address TEXT NOT NULL,
+		amount TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		tx_hash TEXT NOT NULL DEFAULT '',
+		replaced_tx_hashes TEXT[] NOT NULL DEFAULT '{}',
+		tx_nonce BIGINT,
+		gas_price TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		sent_at TIMESTAMP,
+		confirmed_at TIMESTAMP,
+		UNIQUE (kind, reference, player_address)
+	);
+
+	-- Index on status for the payout worker
+	CREATE INDEX IF NOT EXISTS idx_payouts_status ON payouts(status, id);
+	`
+
+	if _, err := PostgresPool.Exec(ctx, payoutsSchema); err != nil {
+		return fmt.Errorf("failed to create payouts table: %w", err)
+	}
+
 	log.Println("✅ Database schema initialized")
 	return nil
 }
@@ -126,11 +330,19 @@ func StoreCrashHistory(ctx context.Context, record *CrashHistoryRecord) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal candlestick history: %w", err)
 	}
+	distributionJSON, err := json.Marshal(record.Distribution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal crash distribution: %w", err)
+	}
+	layoutJSON, err := json.Marshal(record.CandleLayout)
+	if err != nil {
+		return fmt.Errorf("failed to marshal candle layout: %w", err)
+	}
 
 	query := `
 		INSERT INTO crash_history
-		(game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		(game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, rng_version, distribution, candle_layout, chain_round, terminating_hash, client_seed, recovered, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		ON CONFLICT (game_id) DO NOTHING
 	`
 
@@ -143,6 +355,13 @@ func StoreCrashHistory(ctx context.Context, record *CrashHistoryRecord) error {
 		record.Peak,
 		candlestickJSON,
 		record.Rugged,
+		record.RNGVersion,
+		distributionJSON,
+		layoutJSON,
+		record.ChainRound,
+		record.TerminatingHash,
+		record.ClientSeed,
+		record.Recovered,
 		record.CreatedAt,
 	)
 
@@ -158,13 +377,13 @@ func StoreCrashHistory(ctx context.Context, record *CrashHistoryRecord) error {
 // GetCrashHistory retrieves a crash game history by game ID
 func GetCrashHistory(ctx context.Context, gameID string) (*CrashHistoryRecord, error) {
 	query := `
-		SELECT game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, created_at
+		SELECT game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, rng_version, distribution, candle_layout, chain_round, terminating_hash, client_seed, recovered, created_at
 		FROM crash_history
 		WHERE game_id = $1
 	`
 
 	var record CrashHistoryRecord
-	var candlestickJSON []byte
+	var candlestickJSON, distributionJSON, layoutJSON []byte
 
 	err := PostgresPool.QueryRow(ctx, query, gameID).Scan(
 		&record.GameID,
@@ -173,6 +392,13 @@ func GetCrashHistory(ctx context.Context, gameID string) (*CrashHistoryRecord, e
 		&record.Peak,
 		&candlestickJSON,
 		&record.Rugged,
+		&record.RNGVersion,
+		&distributionJSON,
+		&layoutJSON,
+		&record.ChainRound,
+		&record.TerminatingHash,
+		&record.ClientSeed,
+		&record.Recovered,
 		&record.CreatedAt,
 	)
 
@@ -187,6 +413,12 @@ func GetCrashHistory(ctx context.Context, gameID string) (*CrashHistoryRecord, e
 	if err := json.Unmarshal(candlestickJSON, &record.CandlestickHistory); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal candlestick history: %w", err)
 	}
+	if err := json.Unmarshal(distributionJSON, &record.Distribution); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal crash distribution: %w", err)
+	}
+	if err := json.Unmarshal(layoutJSON, &record.CandleLayout); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal candle layout: %w", err)
+	}
 
 	return &record, nil
 }
@@ -194,7 +426,7 @@ func GetCrashHistory(ctx context.Context, gameID string) (*CrashHistoryRecord, e
 // GetRecentCrashHistory retrieves the N most recent crash games
 func GetRecentCrashHistory(ctx context.Context, limit int) ([]*CrashHistoryRecord, error) {
 	query := `
-		SELECT game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, created_at
+		SELECT game_id, server_seed, server_seed_hash, peak, candlestick_history, rugged, rng_version, distribution, candle_layout, chain_round, terminating_hash, client_seed, recovered, created_at
 		FROM crash_history
 		ORDER BY created_at DESC
 		LIMIT $1
@@ -209,7 +441,7 @@ func GetRecentCrashHistory(ctx context.Context, limit int) ([]*CrashHistoryRecor
 	var records []*CrashHistoryRecord
 	for rows.Next() {
 		var record CrashHistoryRecord
-		var candlestickJSON []byte
+		var candlestickJSON, distributionJSON, layoutJSON []byte
 
 		if err := rows.Scan(
 			&record.GameID,
@@ -218,6 +450,13 @@ func GetRecentCrashHistory(ctx context.Context, limit int) ([]*CrashHistoryRecor
 			&record.Peak,
 			&candlestickJSON,
 			&record.Rugged,
+			&record.RNGVersion,
+			&distributionJSON,
+			&layoutJSON,
+			&record.ChainRound,
+			&record.TerminatingHash,
+			&record.ClientSeed,
+			&record.Recovered,
 			&record.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
@@ -227,6 +466,12 @@ func GetRecentCrashHistory(ctx context.Context, limit int) ([]*CrashHistoryRecor
 		if err := json.Unmarshal(candlestickJSON, &record.CandlestickHistory); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal candlestick history: %w", err)
 		}
+		if err := json.Unmarshal(distributionJSON, &record.Distribution); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal crash distribution: %w", err)
+		}
+		if err := json.Unmarshal(layoutJSON, &record.CandleLayout); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal candle layout: %w", err)
+		}
 
 		records = append(records, &record)
 	}
@@ -238,6 +483,670 @@ func GetRecentCrashHistory(ctx context.Context, limit int) ([]*CrashHistoryRecor
 	return records, nil
 }
 
+/* =========================
+   CRASH SEED CHAIN
+========================= */
+
+// GetChainRoundSeed returns the revealed seed of a chain round, or "" if no
+// round at that position has been stored
+func GetChainRoundSeed(ctx context.Context, terminatingHash string, round uint64) (string, error) {
+	query := `
+		SELECT server_seed
+		FROM crash_history
+		WHERE terminating_hash = $1 AND chain_round = $2
+		LIMIT 1
+	`
+
+	var seed string
+	err := PostgresPool.QueryRow(ctx, query, terminatingHash, round).Scan(&seed)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain round seed: %w", err)
+	}
+
+	return seed, nil
+}
+
+// GetActiveSeedChain returns the newest seed chain that still has unused rounds
+func GetActiveSeedChain(ctx context.Context) (*SeedChainRecord, error) {
+	query := `
+		SELECT id, root_seed, chain_length, terminating_hash, rounds_used, created_at
+		FROM crash_seed_chain
+		WHERE rounds_used < chain_length
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	var record SeedChainRecord
+	err := PostgresPool.QueryRow(ctx, query).Scan(
+		&record.ID,
+		&record.SealedRoot,
+		&record.ChainLength,
+		&record.TerminatingHash,
+		&record.RoundsUsed,
+		&record.CreatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil // No usable chain
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed chain: %w", err)
+	}
+
+	return &record, nil
+}
+
+// StoreSeedChain stores a newly generated seed chain and sets its ID
+func StoreSeedChain(ctx context.Context, record *SeedChainRecord) error {
+	query := `
+		INSERT INTO crash_seed_chain (root_seed, chain_length, terminating_hash, rounds_used, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	err := PostgresPool.QueryRow(
+		ctx,
+		query,
+		record.SealedRoot,
+		record.ChainLength,
+		record.TerminatingHash,
+		record.RoundsUsed,
+		record.CreatedAt,
+	).Scan(&record.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to store seed chain: %w", err)
+	}
+
+	log.Printf("✅ Stored seed chain #%d - Length: %d, Terminating hash: %s",
+		record.ID, record.ChainLength, record.TerminatingHash)
+	return nil
+}
+
+// UseSeedChainRound atomically claims the next round of a seed chain and returns its number
+func UseSeedChainRound(ctx context.Context, chainID int64) (uint64, error) {
+	query := `
+		UPDATE crash_seed_chain
+		SET rounds_used = rounds_used + 1
+		WHERE id = $1 AND rounds_used < chain_length
+		RETURNING rounds_used
+	`
+
+	var round uint64
+	err := PostgresPool.QueryRow(ctx, query, chainID).Scan(&round)
+	if err == pgx.ErrNoRows {
+		return 0, fmt.Errorf("seed chain %d is exhausted", chainID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim seed chain round: %w", err)
+	}
+
+	return round, nil
+}
+
+/* =========================
+   CANDLEFLIP BATCHES
+========================= */
+
+// SaveCandleflipBatch inserts or updates a batch together with all its rooms
+func SaveCandleflipBatch(ctx context.Context, record *CandleflipBatchRecord) error {
+	batchQuery := `
+		INSERT INTO candleflip_batches (batch_id, player_address, amount_per_room, total_rooms, player_side,
+			server_seed, server_seed_hash, client_seed, nonce, rng_version, price_model, odds, status, won_rooms,
+			payout_amount, payout_tx_hash, payout_error, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (batch_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			won_rooms = EXCLUDED.won_rooms,
+			payout_amount = EXCLUDED.payout_amount,
+			payout_tx_hash = EXCLUDED.payout_tx_hash,
+			payout_error = EXCLUDED.payout_error,
+			completed_at = EXCLUDED.completed_at,
+			updated_at = NOW()
+	`
+
+	roomQuery := `
+		INSERT INTO candleflip_rooms (batch_id, room_number, status, final_price, winner, player_won, start_time, end_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (batch_id, room_number) DO UPDATE SET
+			status = EXCLUDED.status,
+			final_price = EXCLUDED.final_price,
+			winner = EXCLUDED.winner,
+			player_won = EXCLUDED.player_won,
+			start_time = EXCLUDED.start_time,
+			end_time = EXCLUDED.end_time
+	`
+
+	batch := &pgx.Batch{}
+	batch.Queue(batchQuery,
+		record.BatchID,
+		record.PlayerAddress,
+		record.AmountPerRoom,
+		record.TotalRooms,
+		record.PlayerSide,
+		record.ServerSeed,
+		record.ServerSeedHash,
+		record.ClientSeed,
+		record.Nonce,
+		record.RNGVersion,
+		record.PriceModel,
+		record.Odds,
+		record.Status,
+		record.WonRooms,
+		record.PayoutAmount,
+		record.PayoutTxHash,
+		record.PayoutError,
+		record.CreatedAt,
+		record.CompletedAt,
+	)
+	for _, room := range record.Rooms {
+		batch.Queue(roomQuery,
+			record.BatchID,
+			room.RoomNumber,
+			room.Status,
+			room.FinalPrice,
+			room.Winner,
+			room.PlayerWon,
+			room.StartTime,
+			room.EndTime,
+		)
+	}
+
+	// Run in one transaction so a batch is never stored without its rooms
+	tx, err := PostgresPool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save candleflip batch: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit candleflip batch: %w", err)
+	}
+
+	return nil
+}
+
+const candleflipBatchColumns = `batch_id, player_address, amount_per_room, total_rooms, player_side,
+		server_seed, server_seed_hash, client_seed, nonce, rng_version, price_model, odds, status, won_rooms,
+		payout_amount, payout_tx_hash, payout_error, created_at, completed_at`
+
+// scanCandleflipBatch scans a row selected with candleflipBatchColumns
+func scanCandleflipBatch(row pgx.Row) (*CandleflipBatchRecord, error) {
+	var record CandleflipBatchRecord
+	err := row.Scan(
+		&record.BatchID,
+		&record.PlayerAddress,
+		&record.AmountPerRoom,
+		&record.TotalRooms,
+		&record.PlayerSide,
+		&record.ServerSeed,
+		&record.ServerSeedHash,
+		&record.ClientSeed,
+		&record.Nonce,
+		&record.RNGVersion,
+		&record.PriceModel,
+		&record.Odds,
+		&record.Status,
+		&record.WonRooms,
+		&record.PayoutAmount,
+		&record.PayoutTxHash,
+		&record.PayoutError,
+		&record.CreatedAt,
+		&record.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// loadCandleflipRooms fills in the rooms of the given batches
+func loadCandleflipRooms(ctx context.Context, records []*CandleflipBatchRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*CandleflipBatchRecord, len(records))
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		record.Rooms = []*CandleflipRoomRecord{}
+		byID[record.BatchID] = record
+		ids = append(ids, record.BatchID)
+	}
+
+	query := `
+		SELECT batch_id, room_number, status, final_price, winner, player_won, start_time, end_time
+		FROM candleflip_rooms
+		WHERE batch_id = ANY($1)
+		ORDER BY batch_id, room_number
+	`
+
+	rows, err := PostgresPool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to query candleflip rooms: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var batchID string
+		var room CandleflipRoomRecord
+		err := rows.Scan(
+			&batchID,
+			&room.RoomNumber,
+			&room.Status,
+			&room.FinalPrice,
+			&room.Winner,
+			&room.PlayerWon,
+			&room.StartTime,
+			&room.EndTime,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan candleflip room: %w", err)
+		}
+		byID[batchID].Rooms = append(byID[batchID].Rooms, &room)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating candleflip rooms: %w", err)
+	}
+	return nil
+}
+
+// GetCandleflipBatch retrieves a batch and its rooms
+func GetCandleflipBatch(ctx context.Context, batchID string) (*CandleflipBatchRecord, error) {
+	query := `SELECT ` + candleflipBatchColumns + ` FROM candleflip_batches WHERE batch_id = $1`
+
+	record, err := scanCandleflipBatch(PostgresPool.QueryRow(ctx, query, batchID))
+	if err == pgx.ErrNoRows {
+		return nil, nil // Batch not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candleflip batch: %w", err)
+	}
+
+	if err := loadCandleflipRooms(ctx, []*CandleflipBatchRecord{record}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetCandleflipBatchesByStatus returns batches in any of the given states, oldest first
+func GetCandleflipBatchesByStatus(ctx context.Context, statuses []string) ([]*CandleflipBatchRecord, error) {
+	query := `SELECT ` + candleflipBatchColumns + ` FROM candleflip_batches WHERE status = ANY($1) ORDER BY created_at`
+
+	rows, err := PostgresPool.Query(ctx, query, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candleflip batches: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*CandleflipBatchRecord
+	for rows.Next() {
+		record, err := scanCandleflipBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan candleflip batch: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating candleflip batches: %w", err)
+	}
+	rows.Close()
+
+	if err := loadCandleflipRooms(ctx, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CandleflipHistoryFilter selects finished batches of one player
+type CandleflipHistoryFilter struct {
+	PlayerAddress string
+	Side          string     // "bull", "bear" or empty for both
+	Outcome       string     // "win", "loss", "push" or empty for all
+	From          *time.Time // Created at or after
+	To            *time.Time // Created before
+	Limit         int
+	Offset        int
+}
+
+// CandleflipStats aggregates a player's finished batches. Amounts are wei strings.
+type CandleflipStats struct {
+	Batches        int    `json:"batches"`
+	WinningBatches int    `json:"winningBatches"`
+	LosingBatches  int    `json:"losingBatches"`
+	PushBatches    int    `json:"pushBatches"`
+	Rooms          int    `json:"rooms"`
+	WonRooms       int    `json:"wonRooms"`
+	LostRooms      int    `json:"lostRooms"`
+	TotalWagered   string `json:"totalWagered"`
+	TotalReturned  string `json:"totalReturned"`
+	NetProfit      string `json:"netProfit"` // Negative when the player lost
+}
+
+// whereClause builds the WHERE clause and arguments for the filter.
+// A batch is a win when it returned more than was wagered (2 × wonRooms > totalRooms).
+func (f *CandleflipHistoryFilter) whereClause() (string, []interface{}) {
+	conditions := []string{
+		"player_address = $1",
+		"status IN ('completed', 'paying', 'paid', 'payout_failed')",
+	}
+	args := []interface{}{f.PlayerAddress}
+
+	if f.Side != "" {
+		args = append(args, f.Side)
+		conditions = append(conditions, fmt.Sprintf("player_side = $%d", len(args)))
+	}
+
+	switch f.Outcome {
+	case "win":
+		conditions = append(conditions, "won_rooms * 2 > total_rooms")
+	case "loss":
+		conditions = append(conditions, "won_rooms * 2 < total_rooms")
+	case "push":
+		conditions = append(conditions, "won_rooms * 2 = total_rooms")
+	}
+
+	if f.From != nil {
+		args = append(args, *f.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetCandleflipHistory returns a page of a player's finished batches, newest
+// first, with their rooms and the total number of matching batches
+func GetCandleflipHistory(ctx context.Context, filter CandleflipHistoryFilter) ([]*CandleflipBatchRecord, int, error) {
+	where, args := filter.whereClause()
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM candleflip_batches ` + where
+	if err := PostgresPool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count candleflip history: %w", err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT %s FROM candleflip_batches %s ORDER BY created_at DESC, batch_id LIMIT $%d OFFSET $%d`,
+		candleflipBatchColumns, where, len(args)-1, len(args))
+
+	rows, err := PostgresPool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query candleflip history: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*CandleflipBatchRecord{}
+	for rows.Next() {
+		record, err := scanCandleflipBatch(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan candleflip batch: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating candleflip history: %w", err)
+	}
+	rows.Close()
+
+	if err := loadCandleflipRooms(ctx, records); err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// GetCandleflipStats aggregates wins, losses and net profit over a player's
+// finished batches matching the filter (Limit and Offset are ignored)
+func GetCandleflipStats(ctx context.Context, filter CandleflipHistoryFilter) (*CandleflipStats, error) {
+	where, args := filter.whereClause()
+
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE won_rooms * 2 > total_rooms),
+			COUNT(*) FILTER (WHERE won_rooms * 2 < total_rooms),
+			COUNT(*) FILTER (WHERE won_rooms * 2 = total_rooms),
+			COALESCE(SUM(total_rooms), 0),
+			COALESCE(SUM(won_rooms), 0),
+			COALESCE(SUM(amount_per_room::NUMERIC * total_rooms), 0)::TEXT,
+			COALESCE(SUM(amount_per_room::NUMERIC * won_rooms * 2), 0)::TEXT,
+			COALESCE(SUM(amount_per_room::NUMERIC * (won_rooms * 2 - total_rooms)), 0)::TEXT
+		FROM candleflip_batches
+	` + where
+
+	var stats CandleflipStats
+	err := PostgresPool.QueryRow(ctx, query, args...).Scan(
+		&stats.Batches,
+		&stats.WinningBatches,
+		&stats.LosingBatches,
+		&stats.PushBatches,
+		&stats.Rooms,
+		&stats.WonRooms,
+		&stats.TotalWagered,
+		&stats.TotalReturned,
+		&stats.NetProfit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candleflip stats: %w", err)
+	}
+	stats.LostRooms = stats.Rooms - stats.WonRooms
+
+	return &stats, nil
+}
+
+/* =========================
+   PAYOUT QUEUE
+========================= */
+
+const payoutColumns = `id, kind, reference, player_address, amount, status, tx_hash, replaced_tx_hashes,
+		tx_nonce, gas_price, attempts, last_error, created_at, updated_at, sent_at, confirmed_at`
+
+// scanPayout scans a row selected with payoutColumns
+func scanPayout(row pgx.Row) (*PayoutRecord, error) {
+	var p PayoutRecord
+	err := row.Scan(
+		&p.ID,
+		&p.Kind,
+		&p.Reference,
+		&p.PlayerAddress,
+		&p.Amount,
+		&p.Status,
+		&p.TxHash,
+		&p.ReplacedTxHashes,
+		&p.TxNonce,
+		&p.GasPrice,
+		&p.Attempts,
+		&p.LastError,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.SentAt,
+		&p.ConfirmedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// EnqueuePayout adds a pending payout. Enqueuing the same kind, reference and
+// player twice returns the existing payout instead of paying twice.
+func EnqueuePayout(ctx context.Context, kind, reference, playerAddress, amount string) (*PayoutRecord, error) {
+	query := `
+		INSERT INTO payouts (kind, reference, player_address, amount)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (kind, reference, player_address) DO UPDATE SET updated_at = payouts.updated_at
+		RETURNING ` + payoutColumns
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, kind, reference, playerAddress, amount))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue payout: %w", err)
+	}
+
+	log.Printf("✅ Queued payout #%d - %s %s, Player: %s, Amount: %s wei",
+		p.ID, kind, reference, playerAddress, amount)
+	return p, nil
+}
+
+// GetPayout retrieves a payout by ID
+func GetPayout(ctx context.Context, id int64) (*PayoutRecord, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts WHERE id = $1`
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil // Payout not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout: %w", err)
+	}
+	return p, nil
+}
+
+// GetPayoutsByStatus returns the oldest payouts in the given state
+func GetPayoutsByStatus(ctx context.Context, status string, limit int) ([]*PayoutRecord, error) {
+	query := `SELECT ` + payoutColumns + ` FROM payouts WHERE status = $1 ORDER BY id LIMIT $2`
+
+	rows, err := PostgresPool.Query(ctx, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payouts: %w", err)
+	}
+	defer rows.Close()
+
+	var payouts []*PayoutRecord
+	for rows.Next() {
+		p, err := scanPayout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout: %w", err)
+		}
+		payouts = append(payouts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payouts: %w", err)
+	}
+
+	return payouts, nil
+}
+
+// GetOutstandingPayoutTotal returns the total wei of payouts that are owed
+// but not confirmed yet, including failed payouts awaiting a retry
+func GetOutstandingPayoutTotal(ctx context.Context) (*big.Int, error) {
+	query := `SELECT COALESCE(SUM(amount::NUMERIC), 0)::TEXT FROM payouts WHERE status = ANY($1)`
+	statuses := []string{PayoutStatusPending, PayoutStatusSent, PayoutStatusFailed}
+
+	var total string
+	if err := PostgresPool.QueryRow(ctx, query, statuses).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to sum outstanding payouts: %w", err)
+	}
+
+	amount, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid outstanding payout total %q", total)
+	}
+	return amount, nil
+}
+
+// MarkPayoutSent records a signed transaction for the payout. It is called
+// before broadcasting, so a restart never loses track of a sent nonce.
+// A previous transaction hash is kept in replaced_tx_hashes.
+func MarkPayoutSent(ctx context.Context, id int64, txHash string, nonce uint64, gasPrice string) (*PayoutRecord, error) {
+	query := `
+		UPDATE payouts
+		SET status = 'sent',
+			replaced_tx_hashes = CASE WHEN tx_hash <> '' AND tx_hash <> $2
+				THEN array_append(replaced_tx_hashes, tx_hash) ELSE replaced_tx_hashes END,
+			tx_hash = $2,
+			tx_nonce = $3,
+			gas_price = $4,
+			sent_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + payoutColumns
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, id, txHash, nonce, gasPrice))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark payout sent: %w", err)
+	}
+	return p, nil
+}
+
+// RecordPayoutError stores the latest error without changing the payout state
+func RecordPayoutError(ctx context.Context, id int64, reason string) error {
+	query := `UPDATE payouts SET last_error = $2, updated_at = NOW() WHERE id = $1`
+
+	if _, err := PostgresPool.Exec(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("failed to record payout error: %w", err)
+	}
+	return nil
+}
+
+// MarkPayoutConfirmed records the transaction that paid the payout
+func MarkPayoutConfirmed(ctx context.Context, id int64, txHash string) (*PayoutRecord, error) {
+	query := `
+		UPDATE payouts
+		SET status = 'confirmed', tx_hash = $2, last_error = '', confirmed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + payoutColumns
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, id, txHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark payout confirmed: %w", err)
+	}
+	return p, nil
+}
+
+// MarkPayoutAttemptFailed records a failed attempt. The payout goes back to
+// pending with a fresh nonce, or to failed once maxAttempts is reached.
+func MarkPayoutAttemptFailed(ctx context.Context, id int64, reason string, maxAttempts int) (*PayoutRecord, error) {
+	query := `
+		UPDATE payouts
+		SET attempts = attempts + 1,
+			status = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE 'pending' END,
+			last_error = $2,
+			replaced_tx_hashes = CASE WHEN tx_hash <> ''
+				THEN array_append(replaced_tx_hashes, tx_hash) ELSE replaced_tx_hashes END,
+			tx_hash = '',
+			tx_nonce = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + payoutColumns
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, id, reason, maxAttempts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark payout attempt failed: %w", err)
+	}
+	return p, nil
+}
+
+// RetryPayout moves a failed payout back to pending with a fresh attempt budget
+func RetryPayout(ctx context.Context, id int64) (*PayoutRecord, error) {
+	query := `
+		UPDATE payouts
+		SET status = 'pending', attempts = 0, updated_at = NOW()
+		WHERE id = $1 AND status = 'failed'
+		RETURNING ` + payoutColumns
+
+	p, err := scanPayout(PostgresPool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("payout %d not found or not failed", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry payout: %w", err)
+	}
+
+	log.Printf("🔁 Payout #%d moved back to pending", id)
+	return p, nil
+}
+
 /* =========================
    HEALTH CHECK
 ========================= */
@@ -3,13 +3,17 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"math/big"
 	"strconv"
+	"strings"
 	"time"
 
 	"goLangServer/config"
+	"goLangServer/crypto"
+	"goLangServer/game"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -25,20 +29,40 @@ type CrashBetData struct {
 	GameID          string    `json:"gameId"`
 	BetAmount       string    `json:"betAmount"` // Wei as string
 	EntryMultiplier float64   `json:"entryMultiplier"`
+	EntryTick       int       `json:"entryTick"` // Tick the bet was placed at, -1 during the countdown
 	Timestamp       time.Time `json:"timestamp"`
-	TxHash          string    `json:"txHash"` // Transaction hash for verification
+	TxHash          string    `json:"txHash"`                // Transaction hash for verification
+	AutoCashout     float64   `json:"autoCashout,omitempty"` // Target multiplier, 0 for manual cashout
 }
 
 // CrashCashedOutData represents the Redis structure for a cashed out bet
 type CrashCashedOutData struct {
-	PlayerAddress    string    `json:"playerAddress"`
-	GameID           string    `json:"gameId"`
-	BetAmount        string    `json:"betAmount"` // Wei as string
-	EntryMultiplier  float64   `json:"entryMultiplier"`
+	PlayerAddress     string    `json:"playerAddress"`
+	GameID            string    `json:"gameId"`
+	BetAmount         string    `json:"betAmount"` // Wei as string
+	EntryMultiplier   float64   `json:"entryMultiplier"`
 	CashoutMultiplier float64   `json:"cashoutMultiplier"`
-	Payout           string    `json:"payout"` // Wei as string
-	CashoutTimestamp time.Time `json:"cashoutTimestamp"`
-	BuybackEligible  bool      `json:"buybackEligible"`
+	Payout            string    `json:"payout"` // Wei as string
+	CashoutTimestamp  time.Time `json:"cashoutTimestamp"`
+	BuybackEligible   bool      `json:"buybackEligible"`
+}
+
+// CrashRoundData tracks a crash round until it has been settled, so a
+// round interrupted by a restart can be recovered
+type CrashRoundData struct {
+	GameID          string                 `json:"gameId"`  // Contract game ID, the key of the round's bets
+	RoundID         string                 `json:"roundId"` // Seed game ID used by the simulator
+	ServerSeed      string                 `json:"serverSeed"`
+	ServerSeedHash  string                 `json:"serverSeedHash"`
+	ClientSeed      string                 `json:"clientSeed"` // Set when the countdown ends, see crypto.CrashRoundSeed
+	ChainRound      uint64                 `json:"chainRound"`
+	TerminatingHash string                 `json:"terminatingHash"`
+	RNGVersion      int                    `json:"rngVersion"`
+	Distribution    game.CrashDistribution `json:"distribution"`
+	CandleLayout    game.CandleLayout      `json:"candleLayout"`
+	Status          string                 `json:"status"` // "countdown", "running", "crashed"
+	StartedAt       time.Time              `json:"startedAt"`
+	UpdatedAt       time.Time              `json:"updatedAt"` // Refreshed while the round runs
 }
 
 // CandleFlipGameData represents the Redis structure for a candleflip game
@@ -53,34 +77,31 @@ type CandleFlipGameData struct {
 	TxHash        string    `json:"txHash"`
 }
 
+// SeedPairData represents a player's active provably fair seed pair.
+// The server seed stays secret until the pair is rotated.
+type SeedPairData struct {
+	PlayerAddress  string    `json:"playerAddress"`
+	ServerSeed     string    `json:"-"`
+	ServerSeedHash string    `json:"serverSeedHash"`
+	ClientSeed     string    `json:"clientSeed"`
+	Nonce          uint64    `json:"nonce"` // Next unused nonce
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
 // InitRedis initializes the Redis client connection
-func InitRedis() error {
+func InitRedis(cfg config.RedisConfig) error {
 	log.Println("🔌 Connecting to Redis...")
 
-	// Get Redis configuration from environment
-	redisURL := os.Getenv("REDIS_URL")
-	if redisURL == "" {
-		redisURL = "localhost:6379"
-	}
-
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	redisDB := 0
-	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
-		if db, err := strconv.Atoi(dbStr); err == nil {
-			redisDB = db
-		}
-	}
-
 	// Create Redis client
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:         redisURL,
-		Password:     redisPassword,
-		DB:           redisDB,
-		DialTimeout:  5 * time.Second,
-		ReadTimeout:  3 * time.Second,
-		WriteTimeout: 3 * time.Second,
-		PoolSize:     10,
-		MinIdleConns: 5,
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.DialTimeout.D(),
+		ReadTimeout:  cfg.ReadTimeout.D(),
+		WriteTimeout: cfg.WriteTimeout.D(),
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
 	})
 
 	// Test connection
@@ -91,7 +112,7 @@ func InitRedis() error {
 		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	log.Printf("✅ Redis connected successfully - URL: %s", redisURL)
+	log.Printf("✅ Redis connected successfully - URL: %s", cfg.Addr)
 	return nil
 }
 
@@ -227,6 +248,79 @@ func GetActivePlayers(ctx context.Context, gameID string) ([]string, error) {
 	return players, nil
 }
 
+// GetCashedOutPlayers returns the players who cashed out of a crash game
+func GetCashedOutPlayers(ctx context.Context, gameID string) ([]string, error) {
+	prefix := fmt.Sprintf(config.RedisCrashCashedOutKey, gameID, "")
+
+	var players []string
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		players = append(players, strings.TrimPrefix(iter.Val(), prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan cashed out players: %w", err)
+	}
+
+	return players, nil
+}
+
+// StoreCrashRound records a crash round as unfinished, or updates its status
+func StoreCrashRound(ctx context.Context, round *CrashRoundData) error {
+	key := fmt.Sprintf(config.RedisCrashRoundKey, round.GameID)
+
+	data, err := json.Marshal(round)
+	if err != nil {
+		return fmt.Errorf("failed to marshal crash round: %w", err)
+	}
+
+	if err := RedisClient.Set(ctx, key, data, config.CrashRoundTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store crash round: %w", err)
+	}
+	if err := RedisClient.SAdd(ctx, config.RedisCrashOpenRoundsKey, round.GameID).Err(); err != nil {
+		return fmt.Errorf("failed to add open crash round: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenCrashRounds returns every crash round that was not closed.
+// Rounds whose record has expired are dropped from the open set.
+func GetOpenCrashRounds(ctx context.Context) ([]*CrashRoundData, error) {
+	gameIDs, err := RedisClient.SMembers(ctx, config.RedisCrashOpenRoundsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open crash rounds: %w", err)
+	}
+
+	var rounds []*CrashRoundData
+	for _, gameID := range gameIDs {
+		data, err := RedisClient.Get(ctx, fmt.Sprintf(config.RedisCrashRoundKey, gameID)).Result()
+		if err == redis.Nil {
+			RedisClient.SRem(ctx, config.RedisCrashOpenRoundsKey, gameID)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get crash round: %w", err)
+		}
+
+		var round CrashRoundData
+		if err := json.Unmarshal([]byte(data), &round); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal crash round: %w", err)
+		}
+		rounds = append(rounds, &round)
+	}
+
+	return rounds, nil
+}
+
+// CloseCrashRound marks a crash round as fully settled
+func CloseCrashRound(ctx context.Context, gameID string) error {
+	if err := RedisClient.SRem(ctx, config.RedisCrashOpenRoundsKey, gameID).Err(); err != nil {
+		return fmt.Errorf("failed to close crash round: %w", err)
+	}
+	RedisClient.Del(ctx, fmt.Sprintf(config.RedisCrashRoundKey, gameID))
+	return nil
+}
+
 // CleanupCrashGame removes all active bets for a crashed/rugged game
 func CleanupCrashGame(ctx context.Context, gameID string) error {
 	// Get all active players
@@ -304,6 +398,390 @@ func DeleteCandleFlipGame(ctx context.Context, gameID, playerAddress string) err
 	return nil
 }
 
+// ErrExposureContended is returned when other instances kept changing the
+// outstanding exposure while a batch was being reserved
+var ErrExposureContended = errors.New("candleflip exposure changed concurrently, try again")
+
+// exposureReserveAttempts bounds the optimistic retries of ReserveCandleflipExposure
+const exposureReserveAttempts = 5
+
+// sumExposure adds up the exposure hash
+func sumExposure(fields map[string]string) (*big.Int, error) {
+	total := big.NewInt(0)
+	for batchID, value := range fields {
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid exposure %q for batch %s", value, batchID)
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// GetCandleflipExposure returns what unsettled batches of every instance could still pay out
+func GetCandleflipExposure(ctx context.Context) (*big.Int, error) {
+	fields, err := RedisClient.HGetAll(ctx, config.RedisCandleflipExposureKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candleflip exposure: %w", err)
+	}
+	return sumExposure(fields)
+}
+
+// ReserveCandleflipExposure records what batchID could pay out. reserve is
+// given the outstanding exposure of every instance and returns the batch's
+// own, or an error to reject it. The hash is watched, so a concurrent
+// reservation makes this one start over instead of both being accepted
+// against the same total.
+func ReserveCandleflipExposure(ctx context.Context, batchID string, reserve func(outstanding *big.Int) (*big.Int, error)) error {
+	key := config.RedisCandleflipExposureKey
+
+	txf := func(tx *redis.Tx) error {
+		fields, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get candleflip exposure: %w", err)
+		}
+		outstanding, err := sumExposure(fields)
+		if err != nil {
+			return err
+		}
+
+		exposure, err := reserve(outstanding)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, batchID, exposure.String())
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < exposureReserveAttempts; i++ {
+		err := RedisClient.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrExposureContended
+}
+
+// ReleaseCandleflipExposure drops a batch's exposure once its payout is
+// queued (and counted by the payout queue) or it has nothing to pay
+func ReleaseCandleflipExposure(ctx context.Context, batchID string) error {
+	if err := RedisClient.HDel(ctx, config.RedisCandleflipExposureKey, batchID).Err(); err != nil {
+		return fmt.Errorf("failed to release candleflip exposure: %w", err)
+	}
+	return nil
+}
+
+/* =========================
+   PROVABLY FAIR SEED FUNCTIONS
+========================= */
+
+func seedPairKey(playerAddress string) string {
+	return fmt.Sprintf(config.RedisSeedPairKey, strings.ToLower(playerAddress))
+}
+
+// parseSeedPair converts a Redis hash into a seed pair (nil if the hash is empty)
+func parseSeedPair(playerAddress string, fields map[string]string) (*SeedPairData, error) {
+	if len(fields) == 0 || fields["server_seed"] == "" {
+		return nil, nil
+	}
+
+	nonce, err := strconv.ParseUint(fields["nonce"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce in seed pair: %w", err)
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, fields["created_at"])
+
+	return &SeedPairData{
+		PlayerAddress:  playerAddress,
+		ServerSeed:     fields["server_seed"],
+		ServerSeedHash: fields["server_seed_hash"],
+		ClientSeed:     fields["client_seed"],
+		Nonce:          nonce,
+		CreatedAt:      createdAt,
+	}, nil
+}
+
+// ErrSeedPairContended is returned when rounds kept claiming nonces from a
+// seed pair while it was being rotated
+var ErrSeedPairContended = errors.New("seed pair changed concurrently, try again")
+
+// seedPairAttempts bounds the optimistic retries of seed pair updates
+const seedPairAttempts = 5
+
+// newSeedPair generates a fresh server seed committed for a new rotation period
+func newSeedPair(playerAddress, clientSeed string, nonce uint64) *SeedPairData {
+	serverSeed, seedHash := crypto.GenerateServerSeed()
+	return &SeedPairData{
+		PlayerAddress:  playerAddress,
+		ServerSeed:     serverSeed,
+		ServerSeedHash: seedHash,
+		ClientSeed:     clientSeed,
+		Nonce:          nonce,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// seedPairFields is the Redis hash form of a seed pair
+func seedPairFields(pair *SeedPairData) map[string]interface{} {
+	return map[string]interface{}{
+		"server_seed":      pair.ServerSeed,
+		"server_seed_hash": pair.ServerSeedHash,
+		"client_seed":      pair.ClientSeed,
+		"nonce":            pair.Nonce,
+		"created_at":       pair.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// updateSeedPair runs update against the player's current pair (nil if there
+// is none) inside a transaction on the pair's key. The key is watched, so a
+// nonce reserved or a pair written in between makes the update start over
+// instead of acting on a stale pair.
+func updateSeedPair(ctx context.Context, playerAddress string, update func(tx *redis.Tx, current *SeedPairData) error) error {
+	key := seedPairKey(playerAddress)
+
+	txf := func(tx *redis.Tx) error {
+		fields, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get seed pair: %w", err)
+		}
+		current, err := parseSeedPair(playerAddress, fields)
+		if err != nil {
+			return err
+		}
+		return update(tx, current)
+	}
+
+	for i := 0; i < seedPairAttempts; i++ {
+		err := RedisClient.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrSeedPairContended
+}
+
+// GetSeedPair returns the player's active seed pair, creating one with a
+// random client seed if the player has none yet
+func GetSeedPair(ctx context.Context, playerAddress string) (*SeedPairData, error) {
+	fields, err := RedisClient.HGetAll(ctx, seedPairKey(playerAddress)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed pair: %w", err)
+	}
+
+	pair, err := parseSeedPair(playerAddress, fields)
+	if err != nil {
+		return nil, err
+	}
+	if pair != nil {
+		return pair, nil
+	}
+
+	// Only create the pair if no concurrent request created one first, so a
+	// server seed that rounds were already played with is never overwritten
+	err = updateSeedPair(ctx, playerAddress, func(tx *redis.Tx, current *SeedPairData) error {
+		if current != nil {
+			pair = current
+			return nil
+		}
+
+		created := newSeedPair(playerAddress, crypto.GenerateClientSeed(), 0)
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, seedPairKey(playerAddress), seedPairFields(created))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Printf("🔐 Created seed pair for %s", playerAddress)
+		pair = created
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create seed pair: %w", err)
+	}
+
+	return pair, nil
+}
+
+// SetClientSeed changes the client seed of the player's active pair.
+// The nonce keeps counting so no (serverSeed, clientSeed, nonce) triple repeats.
+func SetClientSeed(ctx context.Context, playerAddress, clientSeed string) (*SeedPairData, error) {
+	if _, err := GetSeedPair(ctx, playerAddress); err != nil {
+		return nil, err
+	}
+
+	if err := RedisClient.HSet(ctx, seedPairKey(playerAddress), "client_seed", clientSeed).Err(); err != nil {
+		return nil, fmt.Errorf("failed to set client seed: %w", err)
+	}
+
+	log.Printf("🔐 Client seed updated for %s", playerAddress)
+	return GetSeedPair(ctx, playerAddress)
+}
+
+// RotateSeedPair reveals the player's current server seed and commits a new one.
+// It returns the revealed pair and the new active pair. An empty clientSeed keeps
+// the current client seed. The reveal and the replacement are written in one
+// transaction, so ReserveNonces sees either the old pair or the new one, and
+// the revealed nonce counts every round played with the old seed.
+func RotateSeedPair(ctx context.Context, playerAddress, clientSeed string) (*SeedPairData, *SeedPairData, error) {
+	// Make sure there is a pair to reveal
+	if _, err := GetSeedPair(ctx, playerAddress); err != nil {
+		return nil, nil, err
+	}
+
+	var revealed, active *SeedPairData
+	err := updateSeedPair(ctx, playerAddress, func(tx *redis.Tx, current *SeedPairData) error {
+		if current == nil {
+			return fmt.Errorf("seed pair for %s disappeared", playerAddress)
+		}
+
+		nextClientSeed := clientSeed
+		if nextClientSeed == "" {
+			nextClientSeed = current.ClientSeed
+		}
+		next := newSeedPair(playerAddress, nextClientSeed, 0)
+
+		// Publish the old seed so every round played with it can be verified
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, fmt.Sprintf(config.RedisRevealedSeedKey, current.ServerSeedHash), current.ServerSeed, 0)
+			pipe.HSet(ctx, seedPairKey(playerAddress), seedPairFields(next))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		revealed, active = current, next
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rotate seed pair: %w", err)
+	}
+
+	log.Printf("🔄 Rotated seed pair for %s (revealed %s)", playerAddress, revealed.ServerSeedHash)
+	return revealed, active, nil
+}
+
+// ReserveNonces atomically claims count consecutive nonces from the player's
+// active pair. It returns the pair they belong to and the first reserved nonce.
+func ReserveNonces(ctx context.Context, playerAddress string, count int) (*SeedPairData, uint64, error) {
+	// Make sure the pair exists before incrementing its nonce
+	if _, err := GetSeedPair(ctx, playerAddress); err != nil {
+		return nil, 0, err
+	}
+
+	key := seedPairKey(playerAddress)
+
+	var incr *redis.IntCmd
+	var fields *redis.MapStringStringCmd
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.HIncrBy(ctx, key, "nonce", int64(count))
+		fields = pipe.HGetAll(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to reserve nonces: %w", err)
+	}
+
+	pair, err := parseSeedPair(playerAddress, fields.Val())
+	if err != nil {
+		return nil, 0, err
+	}
+	if pair == nil {
+		return nil, 0, fmt.Errorf("seed pair for %s disappeared", playerAddress)
+	}
+
+	start := uint64(incr.Val()) - uint64(count)
+	return pair, start, nil
+}
+
+// GetRevealedSeed returns the server seed published for a hash, or "" if it
+// has not been revealed yet
+func GetRevealedSeed(ctx context.Context, serverSeedHash string) (string, error) {
+	seed, err := RedisClient.Get(ctx, fmt.Sprintf(config.RedisRevealedSeedKey, serverSeedHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get revealed seed: %w", err)
+	}
+	return seed, nil
+}
+
+/* =========================
+   RELAYER FUNCTIONS
+========================= */
+
+// useRelayerNonceScript increments the player's nonce only if ARGV[1] is the
+// next expected nonce. Returns {1, next} on success or {0, expected} on mismatch.
+var useRelayerNonceScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current ~= tonumber(ARGV[1]) then
+	return {0, current}
+end
+redis.call('SET', KEYS[1], current + 1)
+return {1, current + 1}
+`)
+
+// relayerNonceKey returns the nonce key for a player (addresses are case-insensitive)
+func relayerNonceKey(playerAddress string) string {
+	return fmt.Sprintf(config.RedisRelayerNonceKey, strings.ToLower(playerAddress))
+}
+
+// GetRelayerNonce returns the nonce the player's next relayed request must carry
+func GetRelayerNonce(ctx context.Context, playerAddress string) (uint64, error) {
+	val, err := RedisClient.Get(ctx, relayerNonceKey(playerAddress)).Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get relayer nonce: %w", err)
+	}
+	return val, nil
+}
+
+// UseRelayerNonce atomically consumes nonce if it is the player's next nonce
+func UseRelayerNonce(ctx context.Context, playerAddress string, nonce uint64) error {
+	res, err := useRelayerNonceScript.Run(ctx, RedisClient,
+		[]string{relayerNonceKey(playerAddress)}, nonce).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("failed to use relayer nonce: %w", err)
+	}
+	if len(res) != 2 {
+		return fmt.Errorf("unexpected relayer nonce script result %v", res)
+	}
+	if res[0] != 1 {
+		return fmt.Errorf("invalid nonce %d, expected %d", nonce, res[1])
+	}
+	return nil
+}
+
+// ClaimRelayerDeposit marks a deposit transaction as used for a bet.
+// Returns false if it was already claimed.
+func ClaimRelayerDeposit(ctx context.Context, txHash string) (bool, error) {
+	key := fmt.Sprintf(config.RedisRelayerDepositKey, strings.ToLower(txHash))
+	claimed, err := RedisClient.SetNX(ctx, key, time.Now().Unix(), 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim deposit: %w", err)
+	}
+	return claimed, nil
+}
+
+// ReleaseRelayerDeposit frees a claimed deposit whose bet could not be stored
+func ReleaseRelayerDeposit(ctx context.Context, txHash string) error {
+	key := fmt.Sprintf(config.RedisRelayerDepositKey, strings.ToLower(txHash))
+	if err := RedisClient.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to release deposit: %w", err)
+	}
+	return nil
+}
+
 /* =========================
    HEALTH CHECK
 ========================= */
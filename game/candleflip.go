@@ -1,40 +1,51 @@
-//game/candleflip.go
+// game/candleflip.go
 package game
 
 import (
 	"math"
-	"math/rand"
 )
 
 const (
-	CandleflipStartingPrice    = 1.0
-	CandleflipTotalTicks       = 40
-	CandleflipBigMoveChance    = 0.01
-	CandleflipBigMovePct       = 0.20  // ±20% for big moves
-	CandleflipSmallMovePctMin  = 0.01  // ±1% minimum
-	CandleflipSmallMovePctMax  = 0.05  // ±5% maximum
+	CandleflipStartingPrice   = 1.0
+	CandleflipTotalTicks      = 40
+	CandleflipBigMoveChance   = 0.01
+	CandleflipBigMovePct      = 0.20 // ±20% for big moves
+	CandleflipSmallMovePctMin = 0.01 // ±1% minimum
+	CandleflipSmallMovePctMax = 0.05 // ±5% maximum
 )
 
-// GenerateCandleflipPrice generates next price based on percentage changes (fair)
-func GenerateCandleflipPrice(rng *rand.Rand, lastPrice float64) float64 {
+// Candleflip price models. The model is stored next to every batch so rooms
+// played with an older model stay verifiable.
+const (
+	// Moves are applied as ±pct, so a down move undoes more than an equal up
+	// move and about 54% of rooms finish below the starting price
+	CandleflipPriceModelLinear = 1
+	// Up moves multiply by 1+pct and down moves divide by it, so the log price
+	// is a symmetric walk and bull and bear are equally likely
+	CandleflipPriceModelSymmetric = 2
+	CurrentCandleflipPriceModel   = CandleflipPriceModelSymmetric
+)
+
+// candleflipMove draws the size and direction of the next price move
+func candleflipMove(rng RNG) (magnitude float64, up bool) {
 	chance := rng.Float64()
-	var percentChange float64
 
 	if chance < CandleflipBigMoveChance {
 		// 1% chance for big move (±20%)
-		if rng.Float64() < 0.5 {
-			percentChange = -CandleflipBigMovePct
-		} else {
-			percentChange = CandleflipBigMovePct
-		}
-	} else {
-		// 99% chance for small move (±1% to ±5%)
-		magnitude := CandleflipSmallMovePctMin + rng.Float64()*(CandleflipSmallMovePctMax-CandleflipSmallMovePctMin)
-		if rng.Float64() < 0.5 {
-			percentChange = -magnitude
-		} else {
-			percentChange = magnitude
-		}
+		return CandleflipBigMovePct, rng.Float64() >= 0.5
+	}
+
+	// 99% chance for small move (±1% to ±5%)
+	magnitude = CandleflipSmallMovePctMin + rng.Float64()*(CandleflipSmallMovePctMax-CandleflipSmallMovePctMin)
+	return magnitude, rng.Float64() >= 0.5
+}
+
+// GenerateCandleflipPrice generates the next price with the linear price model
+func GenerateCandleflipPrice(rng RNG, lastPrice float64) float64 {
+	magnitude, up := candleflipMove(rng)
+	percentChange := magnitude
+	if !up {
+		percentChange = -magnitude
 	}
 
 	newPrice := lastPrice * (1 + percentChange)
@@ -45,10 +56,24 @@ func GenerateCandleflipPrice(rng *rand.Rand, lastPrice float64) float64 {
 	return newPrice
 }
 
-// VerifyCandleflip runs the complete game simulation and returns the winner
-func VerifyCandleflip(serverSeed string) string {
+// nextCandleflipPrice generates the next price with the given price model
+func nextCandleflipPrice(priceModel int, rng RNG, lastPrice float64) float64 {
+	if priceModel == CandleflipPriceModelLinear {
+		return GenerateCandleflipPrice(rng, lastPrice)
+	}
+
+	magnitude, up := candleflipMove(rng)
+	if up {
+		return lastPrice * (1 + magnitude)
+	}
+	return lastPrice / (1 + magnitude)
+}
+
+// VerifyCandleflip runs the complete game simulation with the given RNG
+// version and returns the winner
+func VerifyCandleflip(rngVersion int, serverSeed string) string {
 	combined := serverSeed + "-candleflip"
-	rng := NewSeededRNG(combined)
+	rng := NewRNG(rngVersion, combined)
 
 	currentPrice := CandleflipStartingPrice
 
@@ -64,10 +89,11 @@ func VerifyCandleflip(serverSeed string) string {
 	return "GREEN"
 }
 
-// SimulateCandleflipGame runs the game and returns price history and winner
-func SimulateCandleflipGame(serverSeed string) ([]float64, string) {
+// SimulateCandleflipGame runs the game with the given RNG version and returns
+// price history and winner
+func SimulateCandleflipGame(rngVersion int, serverSeed string) ([]float64, string) {
 	combined := serverSeed + "-candleflip"
-	rng := NewSeededRNG(combined)
+	rng := NewRNG(rngVersion, combined)
 
 	priceHistory := make([]float64, CandleflipTotalTicks+1)
 	priceHistory[0] = CandleflipStartingPrice
@@ -92,6 +118,43 @@ func SimulateCandleflipGame(serverSeed string) ([]float64, string) {
 	return priceHistory, winner
 }
 
+// CandleflipRoom is the outcome of a single batch room
+type CandleflipRoom struct {
+	Prices     []float64 // Starting price followed by one price per tick
+	FinalPrice float64
+	Winner     string // "bull" or "bear"
+}
+
+// PlayCandleflipRoom plays a batch room from its seed. The live batch runner
+// and offline verification both use it, so a room replayed from its seed
+// yields exactly the prices players saw.
+func PlayCandleflipRoom(rngVersion, priceModel int, roomSeed string) CandleflipRoom {
+	rng := NewRNG(rngVersion, roomSeed)
+
+	prices := make([]float64, 0, CandleflipTotalTicks+1)
+	currentPrice := CandleflipStartingPrice
+	prices = append(prices, currentPrice)
+
+	for tick := 0; tick < CandleflipTotalTicks; tick++ {
+		currentPrice = nextCandleflipPrice(priceModel, rng, currentPrice)
+		if currentPrice < 0 {
+			currentPrice = 0
+		}
+		prices = append(prices, currentPrice)
+	}
+
+	winner := "bear"
+	if currentPrice >= CandleflipStartingPrice {
+		winner = "bull"
+	}
+
+	return CandleflipRoom{
+		Prices:     prices,
+		FinalPrice: currentPrice,
+		Winner:     winner,
+	}
+}
+
 // CalculateCandleflipPayout calculates payout for a bet
 func CalculateCandleflipPayout(betAmount float64, trend string, winner string) float64 {
 	// trend: "bullish" or "bearish"
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCandleflipGoldenVectors pins room outcomes so a change to the price
+// generator cannot silently alter the replay of stored batches.
+func TestCandleflipGoldenVectors(t *testing.T) {
+	rooms := []struct {
+		rngVersion int
+		priceModel int
+		seed       string
+		final      float64
+		winner     string
+	}{
+		{RNGVersionHMAC, CandleflipPriceModelSymmetric, "golden-1", 1.2767871671600735, "bull"},
+		{RNGVersionHMAC, CandleflipPriceModelSymmetric, "golden-9", 0.96708374147131, "bear"},
+		{RNGVersionHMAC, CandleflipPriceModelSymmetric, "golden-13", 0.6036589844664483, "bear"},
+		{RNGVersionHMAC, CandleflipPriceModelLinear, "golden-1", 1.2604392802290045, "bull"},
+		{RNGVersionHMAC, CandleflipPriceModelLinear, "golden-3", 1.048223502304465, "bull"},
+		{RNGVersionLegacy, CandleflipPriceModelLinear, "golden-1", 0.8204344952772326, "bear"},
+		{RNGVersionLegacy, CandleflipPriceModelLinear, "golden-3", 1.1985813278061297, "bull"},
+	}
+	for _, v := range rooms {
+		room := PlayCandleflipRoom(v.rngVersion, v.priceModel, v.seed)
+		if room.FinalPrice != v.final || room.Winner != v.winner {
+			t.Errorf("room %s (rng %d, model %d) = %v %s, want %v %s",
+				v.seed, v.rngVersion, v.priceModel, room.FinalPrice, room.Winner, v.final, v.winner)
+		}
+		if len(room.Prices) != CandleflipTotalTicks+1 || room.Prices[0] != CandleflipStartingPrice {
+			t.Errorf("room %s has %d prices starting at %v", v.seed, len(room.Prices), room.Prices[0])
+		}
+	}
+
+	games := []struct {
+		rngVersion int
+		seed       string
+		final      float64
+		winner     string
+	}{
+		{RNGVersionHMAC, "golden-1", 0.6932683001452946, "RED"},
+		{RNGVersionHMAC, "golden-3", 1.2065143979180795, "GREEN"},
+		{RNGVersionHMAC, "golden-7", 1.0836132923830422, "GREEN"},
+		{RNGVersionHMAC, "golden-8", 0.8275977389256325, "RED"},
+		// Games played before the HMAC generator
+		{RNGVersionLegacy, "golden-1", 0.7575614810319797, "RED"},
+		{RNGVersionLegacy, "golden-2", 1.0067487082029427, "GREEN"},
+		{RNGVersionLegacy, "golden-4", 0.6784116815123876, "RED"},
+	}
+	for _, v := range games {
+		prices, winner := SimulateCandleflipGame(v.rngVersion, v.seed)
+		if final := prices[len(prices)-1]; final != v.final || winner != v.winner {
+			t.Errorf("SimulateCandleflipGame(%d, %s) = %v %s, want %v %s", v.rngVersion, v.seed, final, winner, v.final, v.winner)
+		}
+		if got := VerifyCandleflip(v.rngVersion, v.seed); got != v.winner {
+			t.Errorf("VerifyCandleflip(%d, %s) = %s, want %s", v.rngVersion, v.seed, got, v.winner)
+		}
+	}
+}
+
+// TestCandleflipBullBearFairness checks with a chi-square test that the
+// current price model splits rooms evenly between bull and bear.
+func TestCandleflipBullBearFairness(t *testing.T) {
+	const rooms = 20000
+	const critical = 10.828 // Chi-square, 1 degree of freedom, p = 0.001
+
+	bull := 0
+	for i := 0; i < rooms; i++ {
+		if PlayCandleflipRoom(CurrentRNGVersion, CurrentCandleflipPriceModel, fmt.Sprintf("fairness-%d", i)).Winner == "bull" {
+			bull++
+		}
+	}
+
+	expected := float64(rooms) / 2
+	bear := rooms - bull
+	chi := ((float64(bull)-expected)*(float64(bull)-expected) + (float64(bear)-expected)*(float64(bear)-expected)) / expected
+	if chi > critical {
+		t.Fatalf("bull %d / bear %d: chi-square %.2f exceeds %.3f", bull, bear, chi, critical)
+	}
+}
+
+func BenchmarkPlayCandleflipRoom(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		PlayCandleflipRoom(CurrentRNGVersion, CurrentCandleflipPriceModel, fmt.Sprintf("bench-%d", i))
+	}
+}
@@ -0,0 +1,270 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+// CandleLayout controls how crash ticks are grouped into candles.
+// It is stored with every round so replays cut the same groups.
+type CandleLayout struct {
+	TickIntervalMs  int64 `json:"tickIntervalMs"`  // Nominal tick length groups are cut against
+	GroupDurationMs int64 `json:"groupDurationMs"` // Duration of the first groups
+	MergeThreshold  int   `json:"mergeThreshold"`  // Merge pairwise at this many completed groups
+}
+
+// DefaultCandleLayout groups 500ms ticks into 1 second candles, merged at 25
+// groups. Rounds stored before layouts were have the zero layout, which
+// resolves to this one.
+var DefaultCandleLayout = CandleLayout{
+	TickIntervalMs:  500,
+	GroupDurationMs: 1000,
+	MergeThreshold:  25,
+}
+
+// Resolved returns the layout a round was played with, mapping the zero
+// layout of older rounds to DefaultCandleLayout
+func (l CandleLayout) Resolved() CandleLayout {
+	if l == (CandleLayout{}) {
+		return DefaultCandleLayout
+	}
+	return l
+}
+
+// Validate checks that the layout cuts at least one tick per group
+func (l CandleLayout) Validate() error {
+	l = l.Resolved()
+	if l.TickIntervalMs <= 0 {
+		return fmt.Errorf("tick interval must be positive, got %dms", l.TickIntervalMs)
+	}
+	if l.GroupDurationMs < l.TickIntervalMs {
+		return fmt.Errorf("group duration %dms is shorter than the %dms tick interval", l.GroupDurationMs, l.TickIntervalMs)
+	}
+	if l.MergeThreshold < 2 {
+		return fmt.Errorf("merge threshold must be at least 2, got %d", l.MergeThreshold)
+	}
+	return nil
+}
+
+// CandleBuilder groups crash ticks into candlesticks.
+// Groups are cut by tick count (DurationMs / TickIntervalMs ticks per group)
+// rather than wall-clock time, so replaying a round rebuilds the same candles.
+type CandleBuilder struct {
+	layout   CandleLayout
+	groups   []CandleGroup
+	current  *CandleGroup
+	duration int64
+}
+
+// NewCandleBuilder creates an empty candle builder
+func NewCandleBuilder(layout CandleLayout) *CandleBuilder {
+	layout = layout.Resolved()
+	return &CandleBuilder{
+		layout:   layout,
+		duration: layout.GroupDurationMs,
+	}
+}
+
+// ticksPerGroup returns how many ticks fit in a group of the given duration
+func (b *CandleBuilder) ticksPerGroup(durationMs int64) int {
+	n := int(durationMs / b.layout.TickIntervalMs)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// CandleDelta is what a single tick changed in a CandleBuilder. Applying
+// the deltas of a round in order rebuilds the same candles, so clients can
+// follow a round without being resent every candle on every tick.
+type CandleDelta struct {
+	Price      float64      // Tick price
+	Time       int64        // Tick time (unix ms)
+	Completed  *CandleGroup // Group completed by this tick, if any
+	Merged     bool         // Completed groups were merged pairwise after Completed was appended
+	DurationMs int64        // Duration of the group started by this tick, 0 if none started
+}
+
+// Add appends a tick price at time now (unix ms).
+// It reports whether the previous group was completed by this tick.
+func (b *CandleBuilder) Add(price float64, now int64) bool {
+	return b.AddDelta(price, now).Completed != nil
+}
+
+// AddDelta appends a tick price at time now (unix ms) and returns what it changed
+func (b *CandleBuilder) AddDelta(price float64, now int64) CandleDelta {
+	delta := CandleDelta{Price: price, Time: now}
+
+	if b.current == nil {
+		b.startGroup(price, now)
+		delta.DurationMs = b.duration
+		return delta
+	}
+
+	if len(b.current.ValueList) < b.ticksPerGroup(b.current.DurationMs) {
+		// Update current group
+		b.extendGroup(price)
+		return delta
+	}
+
+	// Complete current group and start a new one
+	completed := completeGroup(*b.current, *b.current.Close)
+	b.groups = append(b.groups, completed)
+	delta.Completed = &completed
+
+	// Check if we need to merge
+	if len(b.groups) >= b.layout.MergeThreshold {
+		b.groups, b.duration = MergeGroups(b.groups, b.duration)
+		delta.Merged = true
+	}
+
+	b.startGroup(price, now)
+	delta.DurationMs = b.duration
+	return delta
+}
+
+// Apply replays a delta produced by another builder's AddDelta
+func (b *CandleBuilder) Apply(d CandleDelta) {
+	if d.Completed != nil {
+		b.groups = append(b.groups, *d.Completed)
+		if d.Merged {
+			b.groups, b.duration = MergeGroups(b.groups, b.duration)
+		}
+	}
+
+	if d.DurationMs == 0 && b.current != nil {
+		b.extendGroup(d.Price)
+		return
+	}
+	if d.DurationMs != 0 {
+		b.duration = d.DurationMs
+	}
+	b.startGroup(d.Price, d.Time)
+}
+
+// RestoreCandleBuilder creates a builder from a snapshot of completed groups
+// and the in-progress group, e.g. to apply the deltas that follow it
+func RestoreCandleBuilder(layout CandleLayout, completed []CandleGroup, current *CandleGroup) *CandleBuilder {
+	b := NewCandleBuilder(layout)
+	b.groups = append(b.groups, completed...)
+	if current != nil {
+		b.current = &CandleGroup{}
+		*b.current = *current
+		closeVal := *current.Close
+		b.current.Close = &closeVal
+		b.current.ValueList = append([]float64(nil), current.ValueList...)
+		b.duration = current.DurationMs
+	}
+	return b
+}
+
+func (b *CandleBuilder) extendGroup(price float64) {
+	b.current.ValueList = append(b.current.ValueList, price)
+	closeVal := price
+	b.current.Close = &closeVal
+	b.current.Max = math.Max(b.current.Max, price)
+	b.current.Min = math.Min(b.current.Min, price)
+}
+
+func (b *CandleBuilder) startGroup(price float64, now int64) {
+	closeVal := price
+	b.current = &CandleGroup{
+		Open:       price,
+		Close:      &closeVal,
+		Max:        price,
+		Min:        price,
+		ValueList:  []float64{price},
+		StartTime:  now,
+		DurationMs: b.duration,
+		IsComplete: false,
+	}
+}
+
+// Completed returns a copy of the completed groups (never nil)
+func (b *CandleBuilder) Completed() []CandleGroup {
+	groups := make([]CandleGroup, len(b.groups))
+	copy(groups, b.groups)
+	return groups
+}
+
+// Current returns a copy of the in-progress group, or nil before the first tick
+func (b *CandleBuilder) Current() *CandleGroup {
+	if b.current == nil {
+		return nil
+	}
+	group := *b.current
+	closeVal := *b.current.Close
+	group.Close = &closeVal
+	group.ValueList = append([]float64(nil), b.current.ValueList...)
+	return &group
+}
+
+// Finish completes the in-progress group and returns all groups.
+// A rugged round closes its final candle at zero.
+func (b *CandleBuilder) Finish(rugged bool) []CandleGroup {
+	if b.current != nil {
+		finalCloseValue := *b.current.Close
+		if rugged {
+			finalCloseValue = 0.0
+			b.current.Min = 0.0
+		}
+		b.groups = append(b.groups, completeGroup(*b.current, finalCloseValue))
+		b.current = nil
+	}
+	return b.Completed()
+}
+
+// completeGroup copies a group with its final close value.
+// Completed candles drop their valueList to save bandwidth.
+func completeGroup(g CandleGroup, closeVal float64) CandleGroup {
+	return CandleGroup{
+		Open:       g.Open,
+		Close:      &closeVal,
+		Max:        g.Max,
+		Min:        g.Min,
+		ValueList:  []float64{},
+		StartTime:  g.StartTime,
+		DurationMs: g.DurationMs,
+		IsComplete: true,
+	}
+}
+
+// MergeGroups merges candlestick groups pairwise when the threshold is reached
+func MergeGroups(groups []CandleGroup, currentDuration int64) ([]CandleGroup, int64) {
+	merged := make([]CandleGroup, 0, len(groups)/2+1)
+	newDuration := currentDuration * 2
+
+	for i := 0; i < len(groups); i += 2 {
+		if i+1 < len(groups) {
+			// Merge two groups
+			g1, g2 := groups[i], groups[i+1]
+			closeVal := *g2.Close
+			merged = append(merged, CandleGroup{
+				Open:       g1.Open,
+				Close:      &closeVal,
+				Max:        math.Max(g1.Max, g2.Max),
+				Min:        math.Min(g1.Min, g2.Min),
+				ValueList:  []float64{},
+				StartTime:  g1.StartTime,
+				DurationMs: newDuration,
+				IsComplete: true,
+			})
+		} else {
+			// Odd one out
+			merged = append(merged, groups[i])
+		}
+	}
+
+	return merged, newDuration
+}
+
+// BuildCandles replays tick prices into the candles stored for a round.
+// Start times are derived from the tick index, so only OHLC values are
+// comparable with the live candles.
+func BuildCandles(layout CandleLayout, prices []float64, rugged bool) []CandleGroup {
+	b := NewCandleBuilder(layout)
+	for i, price := range prices {
+		b.Add(price, int64(i)*b.layout.TickIntervalMs)
+	}
+	return b.Finish(rugged)
+}
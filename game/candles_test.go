@@ -0,0 +1,102 @@
+package game
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestCandleDeltasRebuildCandles follows a round long enough to merge twice
+// from a snapshot plus deltas, as a delta protocol client does, and checks it
+// ends with the candles of the builder that produced the deltas.
+func TestCandleDeltasRebuildCandles(t *testing.T) {
+	const ticks = 400
+	prices := make([]float64, ticks)
+	for i := range prices {
+		prices[i] = 1 + float64(i)/100 + 0.3*math.Sin(float64(i)/7)
+	}
+
+	for _, snapshotAt := range []int{0, 1, 37, 120, ticks - 1} {
+		b := NewCandleBuilder(DefaultCandleLayout)
+		var replica *CandleBuilder
+		merges := 0
+
+		for i, price := range prices {
+			if i == snapshotAt {
+				replica = RestoreCandleBuilder(DefaultCandleLayout, b.Completed(), b.Current())
+			}
+			delta := b.AddDelta(price, int64(i)*DefaultCandleLayout.TickIntervalMs)
+			if delta.Merged {
+				merges++
+			}
+			if replica != nil {
+				replica.Apply(delta)
+			}
+		}
+
+		if merges < 2 {
+			t.Fatalf("expected the round to merge at least twice, got %d", merges)
+		}
+		if got, want := replica.Completed(), b.Completed(); !reflect.DeepEqual(got, want) {
+			t.Errorf("snapshot at tick %d: completed groups differ\n got %+v\nwant %+v", snapshotAt, got, want)
+		}
+		if got, want := replica.Current(), b.Current(); !reflect.DeepEqual(got, want) {
+			t.Errorf("snapshot at tick %d: current group = %+v, want %+v", snapshotAt, got, want)
+		}
+	}
+}
+
+// TestAddReportsCompletion checks Add still reports completed groups
+func TestAddReportsCompletion(t *testing.T) {
+	b := NewCandleBuilder(CandleLayout{})
+	completed := 0
+	for i := 0; i < 9; i++ {
+		if b.Add(1, int64(i)*DefaultCandleLayout.TickIntervalMs) {
+			completed++
+		}
+	}
+	// Two ticks per 1s group: the third, fifth, seventh and ninth tick each close one
+	if completed != 4 || len(b.Completed()) != 4 {
+		t.Errorf("completed %d groups (%d stored), want 4", completed, len(b.Completed()))
+	}
+}
+
+// TestCandleLayout checks a configured layout cuts and merges groups by its
+// own tick interval, duration and threshold
+func TestCandleLayout(t *testing.T) {
+	layout := CandleLayout{TickIntervalMs: 250, GroupDurationMs: 1000, MergeThreshold: 4}
+	if err := layout.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	prices := make([]float64, 17)
+	for i := range prices {
+		prices[i] = 1 + float64(i)/10
+	}
+	candles := BuildCandles(layout, prices, false)
+
+	// Four ticks per 1s group: the 17th tick completes the fourth group, which
+	// merges them into two 2s groups and starts a 2s group of its own
+	if len(candles) != 3 {
+		t.Fatalf("got %d candles, want 3: %+v", len(candles), candles)
+	}
+	for i, want := range []int64{2000, 2000, 2000} {
+		if candles[i].DurationMs != want {
+			t.Errorf("candle %d lasts %dms, want %dms", i, candles[i].DurationMs, want)
+		}
+	}
+	if *candles[0].Close != prices[7] || *candles[1].Close != prices[15] {
+		t.Errorf("merged candles span the wrong ticks: %+v", candles[:2])
+	}
+
+	invalid := []CandleLayout{
+		{TickIntervalMs: 500, GroupDurationMs: 250, MergeThreshold: 25},
+		{TickIntervalMs: 500, GroupDurationMs: 1000, MergeThreshold: 1},
+		{TickIntervalMs: -1, GroupDurationMs: 1000, MergeThreshold: 25},
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", l)
+		}
+	}
+}
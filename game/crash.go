@@ -0,0 +1,206 @@
+package game
+
+import (
+	"math"
+)
+
+// CrashTick is a single price step of a crash round
+type CrashTick struct {
+	Index int     `json:"tick"`
+	Price float64 `json:"price"`
+}
+
+// CrashSimulator steps through a crash round one tick at a time.
+// The live game loop and every verification path consume the same simulator,
+// so a round replayed from its serverSeed and gameID yields exactly the ticks
+// players saw.
+type CrashSimulator struct {
+	rng         RNG
+	rngVersion  int
+	targetPeak  float64
+	bust        bool
+	price       float64
+	peak        float64
+	tick        int
+	peakReached bool
+	rugged      bool
+	done        bool
+}
+
+// NewCrashSimulator creates a simulator for the round identified by serverSeed
+// and gameID, drawing randomness from the given RNG version and its target
+// peak from dist
+func NewCrashSimulator(rngVersion int, dist CrashDistribution, serverSeed, gameID string) *CrashSimulator {
+	combined := serverSeed + "-" + gameID
+	rng := NewRNG(rngVersion, combined)
+
+	// Determine target peak upfront
+	targetPeak, bust := dist.Sample(rng)
+
+	return &CrashSimulator{
+		rng:         rng,
+		rngVersion:  rngVersion,
+		targetPeak:  targetPeak,
+		bust:        bust,
+		price:       StartingPrice,
+		peak:        StartingPrice,
+		peakReached: StartingPrice >= targetPeak, // Handle peak=1.0 case
+	}
+}
+
+// Next advances the round by one tick. It returns false once the round has
+// ended, either by rugging or by reaching MaxTicks.
+func (s *CrashSimulator) Next() (CrashTick, bool) {
+	if s.done {
+		return CrashTick{}, false
+	}
+	if s.tick >= MaxTicks {
+		s.done = true
+		return CrashTick{}, false
+	}
+	if s.bust {
+		// Instant bust: the round rugs before its first tick
+		s.rugged = true
+		s.done = true
+		return CrashTick{}, false
+	}
+
+	if !s.peakReached {
+		s.growthStep()
+	} else {
+		// Rug check - can happen any time after peak is reached
+		if s.rng.Float64() < RugProb {
+			s.rugged = true
+			s.done = true
+			return CrashTick{}, false
+		}
+		s.declineStep()
+	}
+
+	if s.price > s.peak {
+		s.peak = s.price
+	}
+
+	t := CrashTick{Index: s.tick, Price: s.price}
+	s.tick++
+	return t, true
+}
+
+// growthStep moves the price upward until the target peak is reached
+func (s *CrashSimulator) growthStep() {
+	rng := s.rng
+	price := s.price
+
+	// Only upward or neutral movements during growth
+	var change float64
+
+	// God candle during growth phase
+	if rng.Float64() < GodCandleChance && price <= 100 {
+		change = GodCandleMult - 1.0 // Convert multiplier to change
+	} else if rng.Float64() < BigMoveChance {
+		// Big upward move
+		move := BigMoveMin + rng.Float64()*(BigMoveMax-BigMoveMin)
+		change = move // Only positive during growth
+	} else {
+		// Normal upward drift
+		drift := rng.Float64() * DriftMax // 0 to DriftMax (upward)
+		volatility := 0.015 * math.Min(15, math.Sqrt(price))
+		noise := volatility * rng.Float64() // 0 to volatility (positive bias)
+		change = drift + noise
+	}
+
+	price = price * (1 + change)
+
+	// Check if peak reached
+	if price >= s.targetPeak {
+		price = s.targetPeak
+		s.peakReached = true
+	}
+
+	s.price = price
+}
+
+// declineStep moves the price randomly while keeping it at or below the peak
+func (s *CrashSimulator) declineStep() {
+	rng := s.rng
+	price := s.price
+
+	// Price movement (can go up or down, but constrained to <= peak)
+	var change float64
+
+	if rng.Float64() < BigMoveChance {
+		move := BigMoveMin + rng.Float64()*(BigMoveMax-BigMoveMin)
+		if rng.Float64() > 0.5 {
+			change = move
+		} else {
+			change = -move
+		}
+	} else {
+		// Normal drift
+		drift := DriftMin + rng.Float64()*(DriftMax-DriftMin)
+		volatility := 0.015 * math.Min(15, math.Sqrt(price))
+		noise := volatility * (2*rng.Float64() - 1)
+		change = drift + noise
+	}
+
+	price = price * (1 + change)
+
+	// Enforce constraints
+	if price < 0 {
+		price = 0
+	}
+	if price > s.targetPeak {
+		price = s.targetPeak // Hard cap at peak
+	}
+
+	s.price = price
+}
+
+// Bust reports whether the round was drawn to rug before its first tick
+func (s *CrashSimulator) Bust() bool {
+	return s.bust
+}
+
+// TargetPeak returns the peak multiplier the round was seeded with
+func (s *CrashSimulator) TargetPeak() float64 {
+	return s.targetPeak
+}
+
+// Price returns the price after the most recent tick
+func (s *CrashSimulator) Price() float64 {
+	return s.price
+}
+
+// Done reports whether the round has ended
+func (s *CrashSimulator) Done() bool {
+	return s.done
+}
+
+// Result returns the outcome of the ticks simulated so far.
+// Call it after Next returns false to get the final result.
+func (s *CrashSimulator) Result() GameResult {
+	return GameResult{
+		PeakMultiplier: s.peak,
+		FinalPrice:     s.price,
+		Rugged:         s.rugged,
+		TotalTicks:     s.tick,
+		RNGVersion:     s.rngVersion,
+	}
+}
+
+// ReplayCrashGame runs a round to completion and returns every tick price
+// together with the final result
+func ReplayCrashGame(rngVersion int, dist CrashDistribution, serverSeed, gameID string) ([]float64, GameResult) {
+	sim := NewCrashSimulator(rngVersion, dist, serverSeed, gameID)
+
+	var prices []float64
+	for {
+		t, ok := sim.Next()
+		if !ok {
+			break
+		}
+		prices = append(prices, t.Price)
+	}
+
+	return prices, sim.Result()
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// crashHistoryRow mirrors the JSON form of a crash_history row (db.CrashHistoryRecord)
+type crashHistoryRow struct {
+	GameID             string            `json:"gameId"`
+	ServerSeed         string            `json:"serverSeed"`
+	ServerSeedHash     string            `json:"serverSeedHash"`
+	Peak               float64           `json:"peak"`
+	CandlestickHistory []CandleGroup     `json:"candlestickHistory"`
+	Rugged             bool              `json:"rugged"`
+	RNGVersion         int               `json:"rngVersion"`
+	Distribution       CrashDistribution `json:"distribution"`
+	CandleLayout       CandleLayout      `json:"candleLayout"`
+}
+
+// TestReplayStoredCrashHistory replays exported crash_history rows tick-for-tick
+// and checks the rebuilt candles against the ones stored by the live loop.
+// The rows were produced by the pre-simulator code (e98dd21): its
+// CalculateGame, recording every tick, with its live loop's candle grouping
+// on a 500ms clock, so they pin the simulator to the original engine.
+func TestReplayStoredCrashHistory(t *testing.T) {
+	data, err := os.ReadFile("testdata/crash_history.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	var rows []crashHistoryRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("failed to parse fixture: %v", err)
+	}
+	if len(rows) == 0 {
+		t.Fatal("fixture has no rows")
+	}
+
+	for _, row := range rows {
+		t.Run(row.GameID, func(t *testing.T) {
+			if err := VerifyCrashHistory(row.RNGVersion, row.Distribution, row.CandleLayout, row.ServerSeed, row.GameID, row.Peak, row.Rugged, row.CandlestickHistory); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestVerifyCrashHistoryDetectsTampering(t *testing.T) {
+	prices, result := ReplayCrashGame(CurrentRNGVersion, LegacyCrashDistribution, "tamper-seed", "20260101-000000.000")
+	candles := BuildCandles(DefaultCandleLayout, prices, result.Rugged)
+
+	if err := VerifyCrashHistory(CurrentRNGVersion, LegacyCrashDistribution, DefaultCandleLayout, "tamper-seed", "20260101-000000.000", result.PeakMultiplier, result.Rugged, candles); err != nil {
+		t.Fatalf("untampered round failed verification: %v", err)
+	}
+
+	if err := VerifyCrashHistory(CurrentRNGVersion, LegacyCrashDistribution, DefaultCandleLayout, "tamper-seed", "20260101-000000.000", result.PeakMultiplier*1.1, result.Rugged, candles); err == nil {
+		t.Error("expected peak mismatch")
+	}
+
+	candles[0].Max += 0.5
+	if err := VerifyCrashHistory(CurrentRNGVersion, LegacyCrashDistribution, DefaultCandleLayout, "tamper-seed", "20260101-000000.000", result.PeakMultiplier, result.Rugged, candles); err == nil {
+		t.Error("expected candle mismatch")
+	}
+}
+
+// TestSimulatorMatchesCalculateGame checks that stepping the simulator by hand
+// ends in the same result CalculateGame reports.
+func TestSimulatorMatchesCalculateGame(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		seed := fmt.Sprintf("seed-%d", i)
+		gameID := fmt.Sprintf("game-%d", i)
+
+		sim := NewCrashSimulator(CurrentRNGVersion, DefaultCrashDistribution, seed, gameID)
+		ticks := 0
+		for {
+			tick, ok := sim.Next()
+			if !ok {
+				break
+			}
+			if tick.Index != ticks {
+				t.Fatalf("tick index %d, want %d", tick.Index, ticks)
+			}
+			if tick.Price > sim.TargetPeak() {
+				t.Fatalf("price %.6f above target peak %.6f", tick.Price, sim.TargetPeak())
+			}
+			ticks++
+		}
+
+		want := CalculateGame(seed, gameID)
+		got := sim.Result()
+		if got != want {
+			t.Fatalf("seed %s: simulator result %+v, CalculateGame %+v", seed, got, want)
+		}
+		if got.TotalTicks != ticks {
+			t.Fatalf("seed %s: TotalTicks %d, stepped %d", seed, got.TotalTicks, ticks)
+		}
+	}
+}
+
+// TestCalculateGameGoldenVectors pins round outcomes so a change to the
+// simulator cannot silently alter the replay of stored rounds.
+func TestCalculateGameGoldenVectors(t *testing.T) {
+	const gameID = "20260101-000000.000"
+
+	vectors := []struct {
+		seed string
+		want GameResult
+	}{
+		{"golden-1", GameResult{PeakMultiplier: 2.011947474578161, FinalPrice: 1.9380718556630219, Rugged: true, TotalTicks: 109, RNGVersion: RNGVersionHMAC}},
+		{"golden-2", GameResult{PeakMultiplier: 2.794303701103884, FinalPrice: 2.794303701103884, Rugged: true, TotalTicks: 23, RNGVersion: RNGVersionHMAC}},
+		{"golden-3", GameResult{PeakMultiplier: 2.0469980347622787, FinalPrice: 1.186279908826405, Rugged: true, TotalTicks: 59, RNGVersion: RNGVersionHMAC}},
+	}
+	for _, v := range vectors {
+		if got := CalculateGame(v.seed, gameID); got != v.want {
+			t.Errorf("CalculateGame(%s) = %+v, want %+v", v.seed, got, v.want)
+		}
+	}
+
+	// Rounds played before the HMAC generator and stored distributions
+	legacy := []struct {
+		seed string
+		want GameResult
+	}{
+		{"golden-1", GameResult{PeakMultiplier: 9.086518075461754, FinalPrice: 2.4319440151494125, Rugged: true, TotalTicks: 73, RNGVersion: RNGVersionLegacy}},
+		{"golden-3", GameResult{PeakMultiplier: 1.5498509635657223, FinalPrice: 0.08413598277117325, Rugged: true, TotalTicks: 239, RNGVersion: RNGVersionLegacy}},
+	}
+	for _, v := range legacy {
+		if got := CalculateGameVersion(RNGVersionLegacy, CrashDistribution{}, v.seed, gameID); got != v.want {
+			t.Errorf("legacy CalculateGameVersion(%s) = %+v, want %+v", v.seed, got, v.want)
+		}
+	}
+}
+
+func BenchmarkCalculateGame(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		CalculateGame(fmt.Sprintf("bench-%d", i), "20260101-000000.000")
+	}
+}
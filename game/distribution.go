@@ -0,0 +1,243 @@
+package game
+
+import (
+	"fmt"
+	"math"
+)
+
+// Crash point distribution models
+const (
+	DistributionBuckets = "buckets" // Weighted uniform peak ranges
+	DistributionCurve   = "curve"   // Classic peak = RTP / (1 - u) curve
+)
+
+// PeakBucket is a range of target peaks drawn uniformly. Cumulative is the
+// running total of bucket probabilities, so the last bucket's is 1.
+type PeakBucket struct {
+	Cumulative float64 `json:"cumulative"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+}
+
+// CrashDistribution describes how the target peak of a crash round is drawn.
+//
+// TargetRTP is the return of the best fixed auto-cashout target: a bet that
+// cashes out at m wins iff the round's peak reaches m, so its RTP is
+// m * P(peak >= m). Rounds that bust instantly rug before the first tick.
+//
+//   - curve: peak = TargetRTP / (1 - u), busting when below 1 and capped at
+//     MaxPeak, so every target up to MaxPeak returns exactly TargetRTP and
+//     1 - TargetRTP of rounds bust instantly.
+//   - buckets: peaks are drawn from Buckets after an instant bust chance
+//     chosen so the best target returns TargetRTP. A zero TargetRTP disables
+//     the bust, leaving the raw buckets.
+//
+// The zero value is LegacyCrashDistribution, which rounds played before
+// distributions were stored replay with.
+type CrashDistribution struct {
+	Model     string       `json:"model"`
+	TargetRTP float64      `json:"targetRtp,omitempty"`
+	MaxPeak   float64      `json:"maxPeak,omitempty"`
+	Buckets   []PeakBucket `json:"buckets,omitempty"`
+}
+
+// LegacyCrashDistribution is the original bucket model with no house edge
+var LegacyCrashDistribution = CrashDistribution{
+	Model: DistributionBuckets,
+	Buckets: []PeakBucket{
+		{Cumulative: 0.40, Min: 1.0, Max: 1.5},    // 40% chance: very low peaks
+		{Cumulative: 0.70, Min: 1.5, Max: 3.0},    // 30% chance: low peaks
+		{Cumulative: 0.88, Min: 3.0, Max: 10.0},   // 18% chance: medium peaks
+		{Cumulative: 0.97, Min: 10.0, Max: 50.0},  // 9% chance: high peaks
+		{Cumulative: 1.00, Min: 50.0, Max: 200.0}, // 3% chance: extreme peaks
+	},
+}
+
+// DefaultCrashDistribution is used for new rounds unless the config selects
+// another one, e.g. CurveCrashDistribution
+var DefaultCrashDistribution = LegacyCrashDistribution
+
+// CurveCrashDistribution is the opt-in curve model with a 3% house edge:
+// every auto-cashout target returns 97% and 3% of rounds bust instantly
+var CurveCrashDistribution = CrashDistribution{
+	Model:     DistributionCurve,
+	TargetRTP: 0.97,
+	MaxPeak:   200,
+}
+
+// Resolved returns the distribution a round was played with, mapping the
+// zero value to LegacyCrashDistribution
+func (d CrashDistribution) Resolved() CrashDistribution {
+	if d.Model == "" {
+		return LegacyCrashDistribution
+	}
+	return d
+}
+
+// Validate checks that the distribution can be sampled
+func (d CrashDistribution) Validate() error {
+	d = d.Resolved()
+
+	switch d.Model {
+	case DistributionCurve:
+		if d.TargetRTP <= 0 || d.TargetRTP > 1 {
+			return fmt.Errorf("curve target RTP must be in (0, 1], got %v", d.TargetRTP)
+		}
+		if d.MaxPeak <= StartingPrice {
+			return fmt.Errorf("curve max peak must be above 1, got %v", d.MaxPeak)
+		}
+
+	case DistributionBuckets:
+		if len(d.Buckets) == 0 {
+			return fmt.Errorf("bucket model needs at least one bucket")
+		}
+		previous := 0.0
+		for i, b := range d.Buckets {
+			if b.Cumulative <= previous || b.Cumulative > 1 {
+				return fmt.Errorf("bucket %d cumulative probability %v must increase up to 1", i, b.Cumulative)
+			}
+			if b.Min < StartingPrice || b.Max <= b.Min {
+				return fmt.Errorf("bucket %d range [%v, %v] is invalid", i, b.Min, b.Max)
+			}
+			previous = b.Cumulative
+		}
+		if previous != 1 {
+			return fmt.Errorf("bucket probabilities sum to %v, want 1", previous)
+		}
+		if d.TargetRTP < 0 {
+			return fmt.Errorf("bucket target RTP must not be negative, got %v", d.TargetRTP)
+		}
+		if _, best := d.bestBucketTarget(); d.TargetRTP > best {
+			return fmt.Errorf("bucket target RTP %v is above the buckets' best RTP %v", d.TargetRTP, best)
+		}
+
+	default:
+		return fmt.Errorf("unknown crash distribution model %q", d.Model)
+	}
+
+	return nil
+}
+
+// Sample draws a round's target peak. A bust round rugs before its first tick.
+func (d CrashDistribution) Sample(rng RNG) (peak float64, bust bool) {
+	d = d.Resolved()
+
+	if d.Model == DistributionCurve {
+		peak = d.TargetRTP / (1 - rng.Float64())
+		if peak < StartingPrice {
+			return StartingPrice, true
+		}
+		return math.Min(peak, d.MaxPeak), false
+	}
+
+	// Only draw for the bust when there is an edge, so legacy rounds keep
+	// their original random stream
+	if d.TargetRTP > 0 && rng.Float64() < d.InstantBust() {
+		return StartingPrice, true
+	}
+
+	r := rng.Float64()
+	bucket := d.Buckets[len(d.Buckets)-1]
+	for _, b := range d.Buckets {
+		if r < b.Cumulative {
+			bucket = b
+			break
+		}
+	}
+	return bucket.Min + rng.Float64()*(bucket.Max-bucket.Min), false
+}
+
+// InstantBust returns the probability that a round rugs before its first tick
+func (d CrashDistribution) InstantBust() float64 {
+	d = d.Resolved()
+
+	if d.Model == DistributionCurve {
+		return 1 - d.TargetRTP
+	}
+	if d.TargetRTP <= 0 {
+		return 0
+	}
+	_, best := d.bestBucketTarget()
+	return 1 - d.TargetRTP/best
+}
+
+// Survival returns P(peak >= m), the chance an auto-cashout at m succeeds
+func (d CrashDistribution) Survival(m float64) float64 {
+	d = d.Resolved()
+
+	if m <= StartingPrice {
+		return 1
+	}
+	if d.Model == DistributionCurve {
+		if m > d.MaxPeak {
+			return 0
+		}
+		return d.TargetRTP / m
+	}
+	return (1 - d.InstantBust()) * d.bucketSurvival(m)
+}
+
+// RTP returns the expected return of a fixed auto-cashout at m
+func (d CrashDistribution) RTP(m float64) float64 {
+	return m * d.Survival(m)
+}
+
+// bucketSurvival returns P(peak >= m) of the raw buckets, without busts
+func (d CrashDistribution) bucketSurvival(m float64) float64 {
+	survival := 0.0
+	previous := 0.0
+	for _, b := range d.Buckets {
+		weight := b.Cumulative - previous
+		previous = b.Cumulative
+
+		switch {
+		case m <= b.Min:
+			survival += weight
+		case m < b.Max:
+			survival += weight * (b.Max - m) / (b.Max - b.Min)
+		}
+	}
+	return survival
+}
+
+// bestBucketTarget finds the target with the highest raw bucket RTP.
+// m * bucketSurvival(m) is a concave quadratic between bucket edges, so the
+// maximum is at an edge or at a vertex inside one of those segments.
+func (d CrashDistribution) bestBucketTarget() (target, rtp float64) {
+	edges := []float64{StartingPrice}
+	for _, b := range d.Buckets {
+		edges = append(edges, b.Min, b.Max)
+	}
+
+	consider := func(m float64) {
+		if value := m * d.bucketSurvival(m); value > rtp {
+			target, rtp = m, value
+		}
+	}
+
+	for _, lo := range edges {
+		consider(lo)
+
+		// Next edge above lo bounds the segment
+		hi := math.Inf(1)
+		for _, e := range edges {
+			if e > lo && e < hi {
+				hi = e
+			}
+		}
+		if math.IsInf(hi, 1) {
+			continue
+		}
+
+		// bucketSurvival is linear on the segment: intercept + slope*m
+		a0, a1 := d.bucketSurvival(lo), d.bucketSurvival(hi)
+		slope := (a1 - a0) / (hi - lo)
+		if slope < 0 {
+			intercept := a0 - slope*lo
+			if vertex := -intercept / (2 * slope); vertex > lo && vertex < hi {
+				consider(vertex)
+			}
+		}
+	}
+	return target, rtp
+}
@@ -0,0 +1,199 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+const rtpSamples = 200000
+
+// TestCrashDistributionRTP samples target peaks and checks that the empirical
+// return of fixed auto-cashout targets matches the configured RTP.
+func TestCrashDistributionRTP(t *testing.T) {
+	bucketsWithEdge := LegacyCrashDistribution
+	bucketsWithEdge.TargetRTP = 0.97
+	bestTarget, _ := bucketsWithEdge.bestBucketTarget()
+
+	cases := []struct {
+		name    string
+		dist    CrashDistribution
+		targets []float64 // Targets whose RTP must equal TargetRTP
+	}{
+		{"curve", CurveCrashDistribution, []float64{1.01, 1.5, 2, 5, 10, 50}},
+		{"curve 99%", CrashDistribution{Model: DistributionCurve, TargetRTP: 0.99, MaxPeak: 1000}, []float64{1.1, 2, 10, 100}},
+		{"buckets 97%", bucketsWithEdge, []float64{bestTarget}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.dist.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			peaks := make([]float64, rtpSamples)
+			busts := 0
+			for i := range peaks {
+				peak, bust := tc.dist.Sample(NewRNG(CurrentRNGVersion, fmt.Sprintf("rtp-%d", i)))
+				if bust {
+					busts++
+					peak = 0
+				}
+				peaks[i] = peak
+			}
+
+			bustRate := float64(busts) / rtpSamples
+			p := tc.dist.InstantBust()
+			if tolerance := 4 * math.Sqrt(p*(1-p)/rtpSamples); math.Abs(bustRate-p) > tolerance {
+				t.Errorf("instant bust rate %.5f, want %.5f ± %.5f", bustRate, p, tolerance)
+			}
+
+			for _, m := range tc.targets {
+				if got := tc.dist.RTP(m); math.Abs(got-tc.dist.TargetRTP) > 1e-9 {
+					t.Errorf("RTP(%v) = %.6f, configured %.6f", m, got, tc.dist.TargetRTP)
+				}
+
+				wins := 0
+				for _, peak := range peaks {
+					if peak >= m {
+						wins++
+					}
+				}
+				empirical := m * float64(wins) / rtpSamples
+
+				// 4 standard errors of m * Bernoulli(TargetRTP / m)
+				p := tc.dist.TargetRTP / m
+				tolerance := 4 * m * math.Sqrt(p*(1-p)/rtpSamples)
+				if math.Abs(empirical-tc.dist.TargetRTP) > tolerance {
+					t.Errorf("empirical RTP at %.2fx = %.4f, configured %.4f ± %.4f", m, empirical, tc.dist.TargetRTP, tolerance)
+				}
+			}
+		})
+	}
+}
+
+// TestSimulatorReachesTargetPeak checks that rounds peak exactly at their
+// drawn target, so auto-cashouts win iff the target reaches them, and that
+// busts rug before the first tick.
+func TestSimulatorReachesTargetPeak(t *testing.T) {
+	busts := 0
+	for i := 0; i < 300; i++ {
+		sim := NewCrashSimulator(CurrentRNGVersion, CurveCrashDistribution, fmt.Sprintf("seed-%d", i), "20260101-000000.000")
+		for {
+			if _, ok := sim.Next(); !ok {
+				break
+			}
+		}
+		result := sim.Result()
+
+		if sim.Bust() {
+			busts++
+			if !result.Rugged || result.TotalTicks != 0 || result.PeakMultiplier != StartingPrice {
+				t.Fatalf("seed-%d: bust round result %+v", i, result)
+			}
+			continue
+		}
+		if result.PeakMultiplier != sim.TargetPeak() {
+			t.Fatalf("seed-%d: peaked at %v, target %v", i, result.PeakMultiplier, sim.TargetPeak())
+		}
+	}
+	if busts == 0 {
+		t.Error("expected some instant busts")
+	}
+}
+
+func TestLegacyBucketRTP(t *testing.T) {
+	if p := LegacyCrashDistribution.InstantBust(); p != 0 {
+		t.Fatalf("legacy distribution busts %.4f of rounds, want 0", p)
+	}
+
+	// The analytic best target must beat every target on a fine grid
+	target, rtp := LegacyCrashDistribution.bestBucketTarget()
+	for m := 1.0; m <= 250; m += 0.001 {
+		if got := LegacyCrashDistribution.RTP(m); got > rtp+1e-9 {
+			t.Fatalf("RTP(%.3f) = %.6f beats the best target %.4fx at %.6f", m, got, target, rtp)
+		}
+	}
+	if got := (CrashDistribution{}).Survival(2); got != LegacyCrashDistribution.Survival(2) {
+		t.Fatalf("zero distribution survival %v, want the legacy %v", got, LegacyCrashDistribution.Survival(2))
+	}
+}
+
+func TestCrashDistributionValidate(t *testing.T) {
+	invalid := []CrashDistribution{
+		{Model: "exponential"},
+		{Model: DistributionCurve, TargetRTP: 0, MaxPeak: 100},
+		{Model: DistributionCurve, TargetRTP: 1.2, MaxPeak: 100},
+		{Model: DistributionCurve, TargetRTP: 0.97, MaxPeak: 1},
+		{Model: DistributionBuckets},
+		{Model: DistributionBuckets, Buckets: []PeakBucket{{Cumulative: 0.5, Min: 1, Max: 2}}},
+		{Model: DistributionBuckets, Buckets: []PeakBucket{{Cumulative: 1, Min: 0.5, Max: 2}}},
+		{Model: DistributionBuckets, TargetRTP: 2, Buckets: []PeakBucket{{Cumulative: 1, Min: 1, Max: 2}}},
+	}
+	for _, dist := range invalid {
+		if err := dist.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", dist)
+		}
+	}
+
+	for _, dist := range []CrashDistribution{{}, LegacyCrashDistribution, CurveCrashDistribution} {
+		if err := dist.Validate(); err != nil {
+			t.Errorf("%+v: %v", dist, err)
+		}
+	}
+}
+
+// TestLegacyBucketDistribution checks with a chi-square test that legacy
+// peaks fall into each bucket with its configured probability and are
+// uniform within it.
+func TestLegacyBucketDistribution(t *testing.T) {
+	const samples = 100000
+	const bins = 4          // Equal-width bins per bucket
+	const critical = 43.820 // Chi-square, 19 degrees of freedom, p = 0.001
+
+	buckets := LegacyCrashDistribution.Buckets
+	counts := make([]int, len(buckets)*bins)
+	for i := 0; i < samples; i++ {
+		peak, bust := LegacyCrashDistribution.Sample(NewRNG(CurrentRNGVersion, fmt.Sprintf("bucket-%d", i)))
+		if bust {
+			t.Fatal("legacy distribution busted")
+		}
+
+		cell := -1
+		for j, b := range buckets {
+			if peak >= b.Min && peak < b.Max {
+				cell = j*bins + int((peak-b.Min)/(b.Max-b.Min)*bins)
+				break
+			}
+		}
+		if cell < 0 {
+			t.Fatalf("peak %v outside every bucket", peak)
+		}
+		counts[cell]++
+	}
+
+	chi := 0.0
+	previous := 0.0
+	for j, b := range buckets {
+		expected := (b.Cumulative - previous) * samples / bins
+		previous = b.Cumulative
+		for k := 0; k < bins; k++ {
+			diff := float64(counts[j*bins+k]) - expected
+			chi += diff * diff / expected
+		}
+	}
+	if chi > critical {
+		t.Fatalf("chi-square %.2f exceeds %.3f, counts %v", chi, critical, counts)
+	}
+}
+
+func BenchmarkCrashDistributionSample(b *testing.B) {
+	for _, dist := range []CrashDistribution{CurveCrashDistribution, LegacyCrashDistribution} {
+		b.Run(dist.Model, func(b *testing.B) {
+			rng := NewHMACRNG("bench")
+			for i := 0; i < b.N; i++ {
+				dist.Sample(rng)
+			}
+		})
+	}
+}
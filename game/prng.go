@@ -1,13 +1,77 @@
 package game
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/binary"
 	"math/rand"
+	"strconv"
 )
 
-func NewSeededRNG(seed string) *rand.Rand {
+// RNG versions. The version is stored next to every game so rounds played
+// with an older generator stay verifiable.
+const (
+	RNGVersionLegacy  = 1 // math/rand seeded with the first 8 bytes of SHA-256(seed)
+	RNGVersionHMAC    = 2 // HMAC-SHA256 counter stream (see HMACRNG)
+	CurrentRNGVersion = RNGVersionHMAC
+)
+
+// RNG is the source of uniform random floats used by the games
+type RNG interface {
+	// Float64 returns a uniform float in [0, 1)
+	Float64() float64
+}
+
+// HMACRNG is a deterministic, language-neutral random stream.
+//
+// Block i (i = 0, 1, 2, ...) is HMAC-SHA256(key = seed, message = decimal ASCII of i).
+// Each 32-byte block is read as four 8-byte big-endian unsigned integers, in order.
+// Each integer x becomes the float (x >> 11) / 2^53, i.e. its top 53 bits
+// scaled into [0, 1).
+type HMACRNG struct {
+	key     []byte
+	counter uint64
+	block   []byte
+	pos     int
+}
+
+// NewHMACRNG creates an HMAC-SHA256 counter stream keyed by seed
+func NewHMACRNG(seed string) *HMACRNG {
+	return &HMACRNG{key: []byte(seed)}
+}
+
+// Float64 returns the next uniform float in [0, 1)
+func (r *HMACRNG) Float64() float64 {
+	if r.block == nil || r.pos >= len(r.block) {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(strconv.FormatUint(r.counter, 10)))
+		r.block = mac.Sum(nil)
+		r.counter++
+		r.pos = 0
+	}
+
+	x := binary.BigEndian.Uint64(r.block[r.pos : r.pos+8])
+	r.pos += 8
+	return float64(x>>11) / (1 << 53)
+}
+
+// NewLegacyRNG creates the math/rand generator used before RNGVersionHMAC
+func NewLegacyRNG(seed string) *rand.Rand {
 	hash := sha256.Sum256([]byte(seed))
 	seedInt := int64(binary.BigEndian.Uint64(hash[:8]))
 	return rand.New(rand.NewSource(seedInt))
 }
+
+// NewRNG creates the generator for the given version.
+// Unknown versions use the current generator.
+func NewRNG(version int, seed string) RNG {
+	if version == RNGVersionLegacy {
+		return NewLegacyRNG(seed)
+	}
+	return NewHMACRNG(seed)
+}
+
+// NewSeededRNG creates the current generator for seed
+func NewSeededRNG(seed string) RNG {
+	return NewRNG(CurrentRNGVersion, seed)
+}
@@ -0,0 +1,45 @@
+package game
+
+import "testing"
+
+// TestHMACRNGVectors pins the HMAC-SHA256 counter stream so third-party
+// verifiers can check their implementation against the same values.
+func TestHMACRNGVectors(t *testing.T) {
+	vectors := []struct {
+		seed string
+		want []float64
+	}{
+		{"test-seed", []float64{0.8505256853335839, 0.10539548490029527, 0.4684319178142772, 0.13564383548985803, 0.5187203776273626, 0.1409432831710754}},
+		{"", []float64{0.5995161989540974, 0.6203068370043401, 0.04725275925104366, 0.7977736918040736, 0.2573343078980269}},
+	}
+
+	for _, v := range vectors {
+		rng := NewHMACRNG(v.seed)
+		for i, want := range v.want {
+			if got := rng.Float64(); got != want {
+				t.Errorf("seed %q value %d: got %v, want %v", v.seed, i, got, want)
+			}
+		}
+	}
+}
+
+func TestNewRNGVersions(t *testing.T) {
+	legacy := NewRNG(RNGVersionLegacy, "seed")
+	if _, ok := legacy.(*HMACRNG); ok {
+		t.Error("legacy version returned the HMAC generator")
+	}
+	if want := NewLegacyRNG("seed").Float64(); legacy.Float64() != want {
+		t.Error("legacy version does not match NewLegacyRNG")
+	}
+
+	if _, ok := NewSeededRNG("seed").(*HMACRNG); !ok {
+		t.Error("NewSeededRNG does not use the HMAC generator")
+	}
+}
+
+func BenchmarkHMACRNG(b *testing.B) {
+	rng := NewHMACRNG("bench")
+	for i := 0; i < b.N; i++ {
+		rng.Float64()
+	}
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Compact binary tick series layout (big-endian):
+//
+//	magic      [4]byte "CTKS"
+//	version    uint8   TickFormatVersion
+//	rngVersion uint8
+//	flags      uint8   bit 0 = rugged
+//	reserved   uint8
+//	count      uint32  number of ticks
+//	peak       float64
+//	prices     count × float64, tick 0 first
+//
+// Prices are stored as full float64 so decoded values equal the replay exactly.
+const (
+	TickFormatVersion = 1
+	tickHeaderSize    = 4 + 4 + 4 + 8
+)
+
+var tickMagic = [4]byte{'C', 'T', 'K', 'S'}
+
+// TickSeries is every tick price of a replayed crash round
+type TickSeries struct {
+	RNGVersion int
+	Rugged     bool
+	Peak       float64
+	Prices     []float64 // Prices[i] is the price at tick i
+}
+
+// ReplayTickSeries replays a round and returns its full tick series
+func ReplayTickSeries(rngVersion int, dist CrashDistribution, serverSeed, gameID string) TickSeries {
+	prices, result := ReplayCrashGame(rngVersion, dist, serverSeed, gameID)
+	return TickSeries{
+		RNGVersion: result.RNGVersion,
+		Rugged:     result.Rugged,
+		Peak:       result.PeakMultiplier,
+		Prices:     prices,
+	}
+}
+
+// Ticks returns the series as indexed ticks
+func (s TickSeries) Ticks() []CrashTick {
+	ticks := make([]CrashTick, len(s.Prices))
+	for i, price := range s.Prices {
+		ticks[i] = CrashTick{Index: i, Price: price}
+	}
+	return ticks
+}
+
+// WriteCSV writes the series as "tick,price" rows. Prices use the shortest
+// representation that parses back to the same float64.
+func (s TickSeries) WriteCSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("tick,price\n")
+	for i, price := range s.Prices {
+		bw.WriteString(strconv.Itoa(i))
+		bw.WriteByte(',')
+		bw.WriteString(strconv.FormatFloat(price, 'g', -1, 64))
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// MarshalBinary encodes the series in the compact binary layout
+func (s TickSeries) MarshalBinary() ([]byte, error) {
+	if s.RNGVersion < 0 || s.RNGVersion > math.MaxUint8 {
+		return nil, fmt.Errorf("rng version %d does not fit the tick format", s.RNGVersion)
+	}
+
+	buf := make([]byte, tickHeaderSize+8*len(s.Prices))
+	copy(buf[0:4], tickMagic[:])
+	buf[4] = TickFormatVersion
+	buf[5] = uint8(s.RNGVersion)
+	if s.Rugged {
+		buf[6] = 1
+	}
+	binary.BigEndian.PutUint32(buf[8:12], uint32(len(s.Prices)))
+	binary.BigEndian.PutUint64(buf[12:20], math.Float64bits(s.Peak))
+
+	for i, price := range s.Prices {
+		off := tickHeaderSize + 8*i
+		binary.BigEndian.PutUint64(buf[off:off+8], math.Float64bits(price))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a series encoded by MarshalBinary
+func (s *TickSeries) UnmarshalBinary(data []byte) error {
+	if len(data) < tickHeaderSize {
+		return fmt.Errorf("tick data too short: %d bytes", len(data))
+	}
+	if [4]byte(data[0:4]) != tickMagic {
+		return fmt.Errorf("invalid tick data magic %q", data[0:4])
+	}
+	if data[4] != TickFormatVersion {
+		return fmt.Errorf("unsupported tick format version %d", data[4])
+	}
+
+	count := binary.BigEndian.Uint32(data[8:12])
+	if uint64(len(data)) != tickHeaderSize+8*uint64(count) {
+		return fmt.Errorf("tick data length %d does not match %d ticks", len(data), count)
+	}
+
+	s.RNGVersion = int(data[5])
+	s.Rugged = data[6]&1 == 1
+	s.Peak = math.Float64frombits(binary.BigEndian.Uint64(data[12:20]))
+	s.Prices = make([]float64, count)
+	for i := range s.Prices {
+		off := tickHeaderSize + 8*i
+		s.Prices[i] = math.Float64frombits(binary.BigEndian.Uint64(data[off : off+8]))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"testing"
+)
+
+func TestTickSeriesBinaryRoundTrip(t *testing.T) {
+	series := ReplayTickSeries(CurrentRNGVersion, DefaultCrashDistribution, "tick-seed", "20260101-000000.000")
+
+	data, err := series.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := tickHeaderSize + 8*len(series.Prices); len(data) != want {
+		t.Fatalf("encoded %d bytes, want %d", len(data), want)
+	}
+
+	var decoded TickSeries
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.RNGVersion != series.RNGVersion || decoded.Rugged != series.Rugged || decoded.Peak != series.Peak {
+		t.Fatalf("header mismatch: got %+v", decoded)
+	}
+	if len(decoded.Prices) != len(series.Prices) {
+		t.Fatalf("decoded %d prices, want %d", len(decoded.Prices), len(series.Prices))
+	}
+	for i := range series.Prices {
+		if decoded.Prices[i] != series.Prices[i] {
+			t.Fatalf("tick %d: decoded %v, want %v", i, decoded.Prices[i], series.Prices[i])
+		}
+	}
+
+	if err := decoded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestTickSeriesCSVIsExact(t *testing.T) {
+	series := ReplayTickSeries(CurrentRNGVersion, DefaultCrashDistribution, "tick-seed", "20260101-000000.000")
+
+	var buf bytes.Buffer
+	if err := series.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(series.Prices)+1 {
+		t.Fatalf("got %d rows, want %d", len(records), len(series.Prices)+1)
+	}
+
+	for i, record := range records[1:] {
+		price, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record[0] != strconv.Itoa(i) || price != series.Prices[i] {
+			t.Fatalf("row %d = %v, want tick %d price %v", i, record, i, series.Prices[i])
+		}
+	}
+}
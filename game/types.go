@@ -16,6 +16,7 @@ type GameResult struct {
 	FinalPrice     float64
 	Rugged         bool
 	TotalTicks     int
+	RNGVersion     int
 	ServerSeed     string
 	ServerSeedHash string
 	GameID         string
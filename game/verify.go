@@ -1,12 +1,14 @@
 package game
 
-// VerifyGamePeak calculates the predetermined peak multiplier for a game
-// without simulating the entire game. This is useful for verification purposes.
+import (
+	"fmt"
+	"math"
+)
+
+// VerifyGamePeak calculates the peak multiplier for a game by replaying it.
 // Given the same serverSeed and gameID, it will always return the same peak.
 func VerifyGamePeak(serverSeed, gameID string) float64 {
-	combined := serverSeed + "-" + gameID
-	rng := NewSeededRNG(combined)
-	return determineTargetPeak(rng)
+	return CalculateGame(serverSeed, gameID).PeakMultiplier
 }
 
 // VerifyGameResult runs a full game simulation and returns the complete result
@@ -14,3 +16,41 @@ func VerifyGamePeak(serverSeed, gameID string) float64 {
 func VerifyGameResult(serverSeed, gameID string) GameResult {
 	return CalculateGame(serverSeed, gameID)
 }
+
+// VerifyCrashHistory replays a round with the RNG version, distribution and
+// candle layout it was played with and checks it against a stored result.
+// Candles are compared on their OHLC values only, since start times are wall-clock.
+func VerifyCrashHistory(rngVersion int, dist CrashDistribution, layout CandleLayout, serverSeed, gameID string, peak float64, rugged bool, candles []CandleGroup) error {
+	prices, result := ReplayCrashGame(rngVersion, dist, serverSeed, gameID)
+
+	if !floatsMatch(result.PeakMultiplier, peak) {
+		return fmt.Errorf("peak mismatch: replayed %.6f, stored %.6f", result.PeakMultiplier, peak)
+	}
+	if result.Rugged != rugged {
+		return fmt.Errorf("rugged mismatch: replayed %v, stored %v", result.Rugged, rugged)
+	}
+
+	replayed := BuildCandles(layout, prices, result.Rugged)
+	if len(replayed) != len(candles) {
+		return fmt.Errorf("candle count mismatch: replayed %d, stored %d", len(replayed), len(candles))
+	}
+
+	for i := range replayed {
+		want, got := replayed[i], candles[i]
+		if got.Close == nil {
+			return fmt.Errorf("candle %d has no close value", i)
+		}
+		if !floatsMatch(want.Open, got.Open) || !floatsMatch(*want.Close, *got.Close) ||
+			!floatsMatch(want.Max, got.Max) || !floatsMatch(want.Min, got.Min) {
+			return fmt.Errorf("candle %d mismatch: replayed O=%.6f C=%.6f H=%.6f L=%.6f, stored O=%.6f C=%.6f H=%.6f L=%.6f",
+				i, want.Open, *want.Close, want.Max, want.Min, got.Open, *got.Close, got.Max, got.Min)
+		}
+	}
+
+	return nil
+}
+
+// floatsMatch compares two prices allowing for JSON round-trip error
+func floatsMatch(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
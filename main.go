@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -8,6 +9,8 @@ import (
 	"syscall"
 
 	"goLangServer/api"
+	"goLangServer/config"
+	"goLangServer/contract"
 	"goLangServer/db"
 	"goLangServer/ws"
 
@@ -22,18 +25,55 @@ func main() {
 		log.Println("✅ Loaded environment variables from .env")
 	}
 
+	// Load and validate the runtime configuration before anything uses it
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ws.Configure(cfg)
+	api.Configure(cfg)
+
 	// Initialize Redis
-	if err := db.InitRedis(); err != nil {
+	if err := db.InitRedis(cfg.Redis); err != nil {
 		log.Printf("⚠️  Warning: Failed to connect to Redis: %v", err)
 		log.Println("   Server will continue but API endpoints will not work")
 	}
 
+	// Share hub channels with the other instances and compete for the crash lease
+	ws.StartHubBus()
+
 	// Initialize PostgreSQL
-	if err := db.InitPostgres(); err != nil {
+	if err := db.InitPostgres(cfg.Postgres); err != nil {
 		log.Printf("⚠️  Warning: Failed to connect to PostgreSQL: %v", err)
 		log.Println("   Server will continue but verification endpoint will not work")
 	}
 
+	// Restart candleflip batches interrupted by the last shutdown
+	ws.ResumeCandleflipBatches()
+
+	// Load the pre-committed crash seed chain and publish its terminating hash
+	ws.InitSeedChain()
+
+	// Start the crash game once the seed chain is ready. The instance holding
+	// the crash lease settles interrupted rounds, then runs them.
+	ws.StartCrashGameLoop()
+
+	// Initialize the shared contract client
+	if err := contract.InitGameHouse(cfg.Chain); err != nil {
+		log.Printf("⚠️  Warning: Failed to initialize contract client: %v", err)
+		log.Println("   Server will continue but payouts and gasless relaying will not work")
+	}
+
+	// Start the payout queue worker
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	if db.PostgresPool == nil {
+		log.Println("⚠️  Warning: PostgreSQL unavailable, payouts will not be processed")
+	} else if contract.GameHouse != nil {
+		worker := contract.NewPayoutWorker(contract.GameHouse, cfg.Payouts)
+		worker.OnUpdate = ws.HandlePayoutUpdate
+		go worker.Run(workerCtx)
+	}
+
 	// Setup graceful shutdown
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
@@ -42,9 +82,13 @@ func main() {
 		<-shutdown
 		log.Println("\n🛑 Shutting down server...")
 
-		// Close database connections
+		// Stop submitting payouts
+		stopWorker()
+
+		// Close database and RPC connections
 		db.CloseRedis()
 		db.ClosePostgres()
+		contract.CloseGameHouse()
 
 		log.Println("✅ Cleanup complete")
 		os.Exit(0)
@@ -57,13 +101,42 @@ func main() {
 	// Verification and health endpoints
 	http.HandleFunc("/api/verify/", corsMiddleware(api.HandleVerifyGame))
 	http.HandleFunc("/api/health", corsMiddleware(api.HandleHealthCheck))
+	http.HandleFunc("/api/crash/chain", corsMiddleware(api.HandleGetSeedChain))
+
+	// CandleFlip batch endpoints
+	http.HandleFunc("/api/candleflip/batches", corsMiddleware(api.HandleGetAllBatches))
+	http.HandleFunc("/api/candleflip/batch/", corsMiddleware(api.HandleGetBatchByID))
+	http.HandleFunc("/api/candleflip/history", corsMiddleware(api.HandleGetBatchHistory))
+	http.HandleFunc("/api/candleflip/stats", corsMiddleware(api.HandleGetBatchStats))
+	http.HandleFunc("/api/verify/candleflip/", corsMiddleware(api.HandleVerifyBatch))
+	http.HandleFunc("/api/candle/preview-odds", corsMiddleware(ws.HandlePreviewOdds))
+
+	// Crash betting endpoints
+	http.HandleFunc("/api/crash/register", corsMiddleware(ws.HandleCrashRegister))
+	http.HandleFunc("/api/crash/cashout", corsMiddleware(ws.HandleCrashCashout))
+
+	// Gasless relayer endpoints
+	http.HandleFunc("/api/gasless/cashout", corsMiddleware(ws.HandleGaslessCashOut))
+	http.HandleFunc("/api/gasless/buyin", corsMiddleware(ws.HandleGaslessBuyIn))
+	http.HandleFunc("/api/gasless/nonce", corsMiddleware(ws.HandleGaslessNonce))
+
+	// Provably fair seed pair endpoints (crash rounds only mix in the client seed)
+	http.HandleFunc("/api/fair/seeds", corsMiddleware(api.HandleGetSeedPair))
+	http.HandleFunc("/api/fair/client-seed", corsMiddleware(api.HandleSetClientSeed))
+	http.HandleFunc("/api/fair/rotate", corsMiddleware(api.HandleRotateSeedPair))
+
+	// Admin endpoints (require ADMIN_API_KEY)
+	http.HandleFunc("/api/admin/payouts", corsMiddleware(api.HandleListPayouts))
+	http.HandleFunc("/api/admin/payouts/retry", corsMiddleware(api.HandleRetryPayouts))
+	http.HandleFunc("/api/admin/config", corsMiddleware(api.HandleGetConfig))
+	http.HandleFunc("/api/admin/connections", corsMiddleware(api.HandleListConnections))
 
 	// Legacy endpoints (with CORS)
 	http.HandleFunc("/api/bettor/add", corsMiddleware(ws.HandleAddBettor))
 	http.HandleFunc("/api/bettor/remove", corsMiddleware(ws.HandleRemoveBettor))
 	http.HandleFunc("/api/verify-game", corsMiddleware(ws.HandleVerifyGame))
 
-	addr := "0.0.0.0:8080"
+	addr := cfg.Server.Addr
 	log.Printf("🚀 Server starting on %s", addr)
 	log.Println("")
 	log.Println("📡 WebSocket Endpoints:")
@@ -72,19 +145,43 @@ func main() {
 	log.Println("   - Subscribe to 'chat' for server chat")
 	log.Println("   - Subscribe to 'rooms' for global rooms")
 	log.Println("   - Subscribe to 'candleflip:<roomId>' for specific room")
+	log.Println("   - Send 'place_bet' / 'cash_out' to bet on the crash round")
+	log.Println("   - Messages carry a per-channel 'seq'; send 'resync' for a snapshot after a gap")
+	log.Println("   - Subscribe to 'crash' with protocol 2 for a snapshot then price_tick deltas")
+	log.Println("   - Offer the 'msgpack' subprotocol for binary MessagePack frames instead of JSON")
+	log.Println("   - With Redis, channels, chat history and rooms are shared by every instance")
 	log.Println("")
 	log.Println("🎮 Crash Game API:")
 	log.Println("   POST /api/crash/register - Register a crash bet")
-	log.Println("   POST /api/crash/cashout - Cash out (gasless)")
+	log.Println("   POST /api/crash/cashout - Cash out at the current multiplier (signed)")
+	log.Println("   POST /api/gasless/cashout - Signed cashout, payout relayed by the server")
+	log.Println("   POST /api/gasless/buyin - Signed bet registration for a bet() deposit")
+	log.Println("   GET /api/gasless/nonce?address= - Relayer nonce and EIP-712 domain")
 	log.Println("")
 	log.Println("🎲 CandleFlip API:")
 	log.Println("   POST /api/candle/register - Register a candleflip game")
-	log.Println("   POST /api/candle/preview-odds - Preview odds")
+	log.Println("   POST /api/candle/preview-odds - Odds and max exposure for a batch (amountPerRoom, roomCount)")
+	log.Println("   GET /api/candleflip/batches - Active batches")
+	log.Println("   GET /api/candleflip/batch/:batchId - Batch details")
+	log.Println("   GET /api/candleflip/history?address= - Batch history (side, outcome, from, to, limit, offset)")
+	log.Println("   GET /api/candleflip/stats?address= - Win/loss and net profit totals")
 	log.Println("")
 	log.Println("🔍 Verification:")
 	log.Println("   GET /api/verify/:gameId - Verify crash game")
+	log.Println("   GET /api/verify/:gameId/ticks?format=json|csv|binary - Every tick price of a crash game")
+	log.Println("   GET /api/verify/candleflip/:batchId - Verify candleflip batch")
+	log.Println("   GET /api/crash/chain - Crash seed chain commitment")
+	log.Println("   GET /api/fair/seeds?address= - Active candleflip seed pair")
+	log.Println("   POST /api/fair/client-seed - Set client seed (signed)")
+	log.Println("   POST /api/fair/rotate - Reveal server seed and rotate (signed)")
 	log.Println("   GET /api/health - Health check")
 	log.Println("")
+	log.Println("🛠️  Admin API:")
+	log.Println("   GET /api/admin/payouts?status=failed - List payouts")
+	log.Println("   POST /api/admin/payouts/retry - Retry failed payouts")
+	log.Println("   GET /api/admin/config - Running configuration (secrets redacted)")
+	log.Println("   GET /api/admin/connections - Open WebSocket connections and their metrics")
+	log.Println("")
 
 	if err := http.ListenAndServe(addr, nil); err != nil {
 		log.Fatal("❌ Server error:", err)
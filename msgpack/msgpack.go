@@ -0,0 +1,401 @@
+// Package msgpack is a minimal MessagePack encoder and decoder for the binary
+// WebSocket encoding. Encoders append to a byte slice, so a message is built
+// in a single buffer. Message types with a fixed schema implement Marshaler
+// and write themselves field by field; anything else is encoded generically
+// in the same shape encoding/json gives it.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Marshaler is implemented by types that encode themselves
+type Marshaler interface {
+	AppendMsgpack(b []byte) []byte
+}
+
+// ErrShortBuffer is returned when a value is cut off
+var ErrShortBuffer = errors.New("msgpack: unexpected end of data")
+
+/* =========================
+   ENCODING
+========================= */
+
+// AppendNil appends nil
+func AppendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// AppendBool appends a boolean
+func AppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+// AppendInt appends a signed integer in its smallest form
+func AppendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return AppendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+// AppendUint appends an unsigned integer in its smallest form
+func AppendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+	}
+}
+
+// AppendFloat appends a float64. Prices are never narrowed to float32, so
+// clients decode exactly the value the JSON encoding would carry.
+func AppendFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// AppendString appends a UTF-8 string
+func AppendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// AppendBytes appends binary data
+func AppendBytes(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+// AppendArrayHeader appends the header of an array of n elements
+func AppendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+// AppendMapHeader appends the header of a map of n key/value pairs
+func AppendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// AppendFloats appends an array of float64
+func AppendFloats(b []byte, v []float64) []byte {
+	b = AppendArrayHeader(b, len(v))
+	for _, f := range v {
+		b = AppendFloat(b, f)
+	}
+	return b
+}
+
+// AppendValue appends any value. Marshalers, basic types, maps with string
+// keys and slices are encoded directly; other values go through
+// encoding/json first, so structs keep their JSON field names. Map keys are
+// sorted, so equal values encode to equal bytes.
+func AppendValue(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return AppendNil(b), nil
+	case Marshaler:
+		return v.AppendMsgpack(b), nil
+	case bool:
+		return AppendBool(b, v), nil
+	case int:
+		return AppendInt(b, int64(v)), nil
+	case int32:
+		return AppendInt(b, int64(v)), nil
+	case int64:
+		return AppendInt(b, v), nil
+	case uint:
+		return AppendUint(b, uint64(v)), nil
+	case uint32:
+		return AppendUint(b, uint64(v)), nil
+	case uint64:
+		return AppendUint(b, v), nil
+	case float32:
+		return AppendFloat(b, float64(v)), nil
+	case float64:
+		return AppendFloat(b, v), nil
+	case string:
+		return AppendString(b, v), nil
+	case []byte:
+		return AppendBytes(b, v), nil
+	case time.Time:
+		return AppendString(b, v.Format(time.RFC3339Nano)), nil
+	case *big.Int:
+		if v == nil {
+			return AppendNil(b), nil
+		}
+		return appendNumber(b, json.Number(v.String()))
+	case json.Number:
+		return appendNumber(b, v)
+	case []float64:
+		return AppendFloats(b, v), nil
+	case []string:
+		b = AppendArrayHeader(b, len(v))
+		for _, s := range v {
+			b = AppendString(b, s)
+		}
+		return b, nil
+	case []interface{}:
+		b = AppendArrayHeader(b, len(v))
+		for _, e := range v {
+			var err error
+			if b, err = AppendValue(b, e); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case []map[string]interface{}:
+		b = AppendArrayHeader(b, len(v))
+		for _, m := range v {
+			var err error
+			if b, err = AppendValue(b, m); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = AppendMapHeader(b, len(keys))
+		for _, k := range keys {
+			b = AppendString(b, k)
+			var err error
+			if b, err = AppendValue(b, v[k]); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	}
+
+	// Everything else takes the shape encoding/json gives it
+	data, err := json.Marshal(v)
+	if err != nil {
+		return b, fmt.Errorf("msgpack: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return b, fmt.Errorf("msgpack: %w", err)
+	}
+	return AppendValue(b, generic)
+}
+
+// appendNumber appends a JSON number as an integer when it is one that fits,
+// as a string when it is an integer too large for 64 bits (wei amounts), and
+// as a float otherwise
+func appendNumber(b []byte, n json.Number) ([]byte, error) {
+	s := string(n)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return AppendInt(b, i), nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return AppendUint(b, u), nil
+	}
+	if _, ok := new(big.Int).SetString(s, 10); ok {
+		return AppendString(b, s), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return b, fmt.Errorf("msgpack: invalid number %q", s)
+	}
+	return AppendFloat(b, f), nil
+}
+
+/* =========================
+   DECODING
+========================= */
+
+// Decode decodes the first value in b and returns it with the remaining
+// bytes. Maps decode to map[string]interface{}, arrays to []interface{},
+// signed integers to int64, unsigned integers to uint64, floats to float64,
+// strings to string and binary data to []byte.
+func Decode(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, b, ErrShortBuffer
+	}
+	c, b := b[0], b[1:]
+
+	switch {
+	case c <= 0x7f:
+		return uint64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xf0 == 0x80:
+		return decodeMap(b, int(c&0x0f))
+	case c&0xf0 == 0x90:
+		return decodeArray(b, int(c&0x0f))
+	case c&0xe0 == 0xa0:
+		return decodeString(b, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, b, err := readLength(b, c-0xc4)
+		if err != nil {
+			return nil, b, err
+		}
+		if len(b) < n {
+			return nil, b, ErrShortBuffer
+		}
+		return append([]byte(nil), b[:n]...), b[n:], nil
+	case 0xca:
+		v, b, err := readUint(b, 4)
+		return float64(math.Float32frombits(uint32(v))), b, err
+	case 0xcb:
+		v, b, err := readUint(b, 8)
+		return math.Float64frombits(v), b, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readUint(b, 1<<(c-0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, b, err := readUint(b, size)
+		shift := 64 - 8*size
+		return int64(v<<shift) >> shift, b, err
+	case 0xd9, 0xda, 0xdb:
+		n, b, err := readLength(b, c-0xd9)
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeString(b, n)
+	case 0xdc, 0xdd:
+		n, b, err := readLength(b, c-0xdc+1)
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeArray(b, n)
+	case 0xde, 0xdf:
+		n, b, err := readLength(b, c-0xde+1)
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeMap(b, n)
+	}
+	return nil, b, fmt.Errorf("msgpack: unsupported type byte 0x%02x", c)
+}
+
+// readLength reads a length of 1, 2 or 4 bytes for sizeClass 0, 1 or 2
+func readLength(b []byte, sizeClass byte) (int, []byte, error) {
+	v, b, err := readUint(b, 1<<sizeClass)
+	return int(v), b, err
+}
+
+func readUint(b []byte, size int) (uint64, []byte, error) {
+	if len(b) < size {
+		return 0, b, ErrShortBuffer
+	}
+	var v uint64
+	for _, c := range b[:size] {
+		v = v<<8 | uint64(c)
+	}
+	return v, b[size:], nil
+}
+
+func decodeString(b []byte, n int) (interface{}, []byte, error) {
+	if len(b) < n {
+		return nil, b, ErrShortBuffer
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func decodeArray(b []byte, n int) (interface{}, []byte, error) {
+	arr := make([]interface{}, 0, min(n, len(b)))
+	for i := 0; i < n; i++ {
+		v, rest, err := Decode(b)
+		if err != nil {
+			return nil, rest, err
+		}
+		arr, b = append(arr, v), rest
+	}
+	return arr, b, nil
+}
+
+func decodeMap(b []byte, n int) (interface{}, []byte, error) {
+	m := make(map[string]interface{}, min(n, len(b)))
+	for i := 0; i < n; i++ {
+		k, rest, err := Decode(b)
+		if err != nil {
+			return nil, rest, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, rest, fmt.Errorf("msgpack: map key %v is not a string", k)
+		}
+		v, rest, err := Decode(rest)
+		if err != nil {
+			return nil, rest, err
+		}
+		m[key], b = v, rest
+	}
+	return m, b, nil
+}
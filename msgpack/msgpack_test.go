@@ -0,0 +1,119 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEncodingVectors checks encodings against the MessagePack specification
+func TestEncodingVectors(t *testing.T) {
+	cases := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{"nil", AppendNil(nil), "c0"},
+		{"true", AppendBool(nil, true), "c3"},
+		{"fixint", AppendInt(nil, 5), "05"},
+		{"negative fixint", AppendInt(nil, -1), "ff"},
+		{"int8", AppendInt(nil, -100), "d09c"},
+		{"int16", AppendInt(nil, -1000), "d1fc18"},
+		{"uint8", AppendUint(nil, 200), "ccc8"},
+		{"uint16", AppendUint(nil, 1000), "cd03e8"},
+		{"uint32", AppendUint(nil, 1<<20), "ce00100000"},
+		{"uint64", AppendUint(nil, 1<<40), "cf0000010000000000"},
+		{"float64", AppendFloat(nil, 1.5), "cb3ff8000000000000"},
+		{"fixstr", AppendString(nil, "abc"), "a3616263"},
+		{"str8", AppendString(nil, strings.Repeat("a", 32))[:2], "d920"},
+		{"bin8", AppendBytes(nil, []byte{1, 2}), "c4020102"},
+		{"fixarray", AppendArrayHeader(nil, 3), "93"},
+		{"array16", AppendArrayHeader(nil, 16), "dc0010"},
+		{"fixmap", AppendMapHeader(nil, 2), "82"},
+		{"map16", AppendMapHeader(nil, 16), "de0010"},
+	}
+	for _, c := range cases {
+		if got := hex.EncodeToString(c.got); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := []interface{}{
+		nil, true, false,
+		uint64(0), uint64(127), uint64(128), uint64(math.MaxUint16 + 1), uint64(math.MaxUint64),
+		int64(-1), int64(-33), int64(math.MinInt16), int64(math.MinInt32), int64(math.MinInt64),
+		1.2345678901234567, math.Inf(-1),
+		"", "price_update", strings.Repeat("x", 300), strings.Repeat("y", 70000),
+		[]byte{0, 1, 2},
+		[]interface{}{uint64(1), "two", []interface{}{3.5}},
+		map[string]interface{}{"a": uint64(1), "b": map[string]interface{}{"c": nil}},
+	}
+	for _, v := range values {
+		b, err := AppendValue(nil, v)
+		if err != nil {
+			t.Fatalf("encode %v: %v", v, err)
+		}
+		got, rest, err := Decode(b)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("decode %v: %v (%d bytes left)", v, err, len(rest))
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("round trip: got %#v, want %#v", got, v)
+		}
+	}
+}
+
+// TestAppendValueFollowsJSON checks values without a direct encoding take
+// their JSON shape, and that wei amounts too large for 64 bits stay exact
+func TestAppendValueFollowsJSON(t *testing.T) {
+	type bet struct {
+		Player string   `json:"player"`
+		Amount *big.Int `json:"amount"`
+		Entry  float64  `json:"entry,omitempty"`
+		Ticks  int      `json:"ticks"`
+	}
+	wei, _ := new(big.Int).SetString("123456789012345678901234", 10)
+
+	b, err := AppendValue(nil, bet{Player: "0xabc", Amount: wei, Ticks: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"player": "0xabc",
+		"amount": "123456789012345678901234",
+		"ticks":  uint64(7),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestMapKeysAreSorted(t *testing.T) {
+	m := map[string]interface{}{"b": 1, "a": 2, "c": 3}
+	first, _ := AppendValue(nil, m)
+	for i := 0; i < 10; i++ {
+		again, _ := AppendValue(nil, m)
+		if !bytes.Equal(first, again) {
+			t.Fatal("map encoding is not deterministic")
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	b, _ := AppendValue(nil, map[string]interface{}{"price": 1.5, "tick": 3})
+	for i := 0; i < len(b); i++ {
+		if _, _, err := Decode(b[:i]); err == nil {
+			t.Errorf("decoding %d of %d bytes succeeded", i, len(b))
+		}
+	}
+}
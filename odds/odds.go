@@ -0,0 +1,140 @@
+// Package odds prices candleflip batches against the house bankroll. A
+// batch is offered the base odds while it risks little of what the house can
+// afford to lose, the odds shrink toward the minimum as it risks more, and a
+// batch whose potential payout the bankroll cannot cover is rejected.
+package odds
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"goLangServer/config"
+)
+
+var (
+	// ErrNoLiquidity is returned when the bankroll has nothing left to risk
+	ErrNoLiquidity = errors.New("house bankroll has no liquidity left")
+	// ErrMaxExposure is returned when a batch could pay out more than allowed
+	ErrMaxExposure = errors.New("batch exceeds the maximum exposure")
+)
+
+// Legacy is the fixed 2x payout of batches created before dynamic odds
+var Legacy = big.NewInt(2e18)
+
+// step is the odds granularity, 0.0001x
+var step = big.NewInt(1e14)
+
+// Bankroll is the house liquidity a quote is made against
+type Bankroll struct {
+	Balance      *big.Int // Contract balance
+	MinLiquidity *big.Int // Balance the contract keeps back, nil for none
+	MaxPayout    *big.Int // Largest payout per transaction, nil or zero for no limit
+	Exposure     *big.Int // Potential payouts of unsettled batches and queued payouts
+}
+
+// Available returns the balance left for new batches once reserves and
+// outstanding exposure are taken out
+func (b Bankroll) Available() *big.Int {
+	available := new(big.Int)
+	if b.Balance != nil {
+		available.Set(b.Balance)
+	}
+	if b.MinLiquidity != nil {
+		available.Sub(available, b.MinLiquidity)
+	}
+	if b.Exposure != nil {
+		available.Sub(available, b.Exposure)
+	}
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+	return available
+}
+
+// Quote is the odds offered for a batch
+type Quote struct {
+	Odds        *big.Int // Payout per won room as a multiplier with 18 decimals
+	Stake       *big.Int // Amount per room × rooms
+	Exposure    *big.Int // Payout if every room is won: stake × odds
+	MaxExposure *big.Int // Largest exposure a single batch may take on
+	Available   *big.Int // Bankroll left after reserves and outstanding exposure
+}
+
+// OddsFloat returns the odds as a plain multiplier
+func (q *Quote) OddsFloat() float64 {
+	return config.WeiToMultiplier(q.Odds)
+}
+
+// Engine quotes odds for candleflip batches
+type Engine struct {
+	base         *big.Int
+	min          *big.Int
+	reserveGames *big.Int
+}
+
+// New creates an engine scaling odds between cfg.BaseOdds and cfg.MinOdds.
+// A single batch may risk at most 1/cfg.ReserveGames of the available
+// bankroll, so the house survives that many worst-case batches in a row.
+func New(cfg config.CandleflipConfig) *Engine {
+	return &Engine{
+		base:         roundToStep(config.MultiplierToWei(cfg.BaseOdds)),
+		min:          roundToStep(config.MultiplierToWei(cfg.MinOdds)),
+		reserveGames: new(big.Int).SetUint64(cfg.ReserveGames),
+	}
+}
+
+// Quote prices a batch of rooms at amountPerRoom each. The odds fall linearly
+// from the base to the minimum as the batch's payout at base odds grows to the
+// maximum exposure. When the batch is rejected with ErrMaxExposure the quote
+// is still returned, so callers can report the limit.
+func (e *Engine) Quote(bank Bankroll, amountPerRoom *big.Int, rooms int) (*Quote, error) {
+	q := &Quote{
+		Stake:     new(big.Int).Mul(amountPerRoom, big.NewInt(int64(rooms))),
+		Available: bank.Available(),
+	}
+
+	q.MaxExposure = new(big.Int).Quo(q.Available, e.reserveGames)
+	if bank.MaxPayout != nil && bank.MaxPayout.Sign() > 0 && bank.MaxPayout.Cmp(q.MaxExposure) < 0 {
+		q.MaxExposure.Set(bank.MaxPayout)
+	}
+	if q.MaxExposure.Sign() == 0 {
+		return nil, ErrNoLiquidity
+	}
+
+	// reduction = (base - min) × payout at base odds / max exposure
+	spread := new(big.Int).Sub(e.base, e.min)
+	reduction := new(big.Int).Mul(q.Stake, e.base)
+	reduction.Quo(reduction, config.DecimalPrecision)
+	reduction.Mul(reduction, spread)
+	reduction.Quo(reduction, q.MaxExposure)
+	if reduction.Cmp(spread) > 0 {
+		reduction.Set(spread)
+	}
+
+	q.Odds = roundToStep(new(big.Int).Sub(e.base, reduction))
+	if q.Odds.Cmp(e.min) < 0 {
+		q.Odds.Set(e.min)
+	}
+
+	q.Exposure = Payout(amountPerRoom, rooms, q.Odds)
+	if q.Exposure.Cmp(q.MaxExposure) > 0 {
+		return q, fmt.Errorf("%w: batch pays up to %g MNT, limit is %g MNT",
+			ErrMaxExposure, config.WeiToMNT(q.Exposure), config.WeiToMNT(q.MaxExposure))
+	}
+	return q, nil
+}
+
+// Payout returns what wonRooms rooms at amountPerRoom pay at the given odds
+func Payout(amountPerRoom *big.Int, wonRooms int, odds *big.Int) *big.Int {
+	payout := new(big.Int).Mul(amountPerRoom, big.NewInt(int64(wonRooms)))
+	payout.Mul(payout, odds)
+	return payout.Quo(payout, config.DecimalPrecision)
+}
+
+// roundToStep rounds odds to the nearest step, absorbing float conversion error
+func roundToStep(odds *big.Int) *big.Int {
+	rounded := new(big.Int).Add(odds, new(big.Int).Rsh(step, 1))
+	rounded.Quo(rounded, step)
+	return rounded.Mul(rounded, step)
+}
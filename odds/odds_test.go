@@ -0,0 +1,87 @@
+package odds
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"goLangServer/config"
+)
+
+func mnt(amount float64) *big.Int {
+	return config.MNTToWei(amount)
+}
+
+func TestQuoteScalesOdds(t *testing.T) {
+	e := New(config.Default().Candleflip) // 2x down to 1.2x, 20 reserve games
+	bank := Bankroll{Balance: mnt(1000)}  // 50 MNT max exposure
+
+	cases := []struct {
+		amount float64
+		rooms  int
+		odds   string
+	}{
+		{0.001, 1, "2000000000000000000"}, // Negligible against the bankroll
+		{1, 5, "1840000000000000000"},     // Pays 10 MNT at base odds, a fifth of the limit
+		{5, 5, "1200000000000000000"},     // Pays 50 MNT at base odds, the whole limit
+	}
+	for _, c := range cases {
+		q, err := e.Quote(bank, mnt(c.amount), c.rooms)
+		if err != nil {
+			t.Fatalf("%g MNT × %d: %v", c.amount, c.rooms, err)
+		}
+		if q.Odds.String() != c.odds {
+			t.Errorf("%g MNT × %d: odds = %s, want %s", c.amount, c.rooms, q.Odds, c.odds)
+		}
+		if q.Exposure.Cmp(q.MaxExposure) > 0 {
+			t.Errorf("%g MNT × %d: exposure %s above limit %s", c.amount, c.rooms, q.Exposure, q.MaxExposure)
+		}
+	}
+}
+
+func TestQuoteRejectsMaxExposure(t *testing.T) {
+	e := New(config.Default().Candleflip)
+
+	// 9 × 5 MNT pays 54 MNT even at 1.2x, above the 50 MNT limit
+	q, err := e.Quote(Bankroll{Balance: mnt(1000)}, mnt(5), 9)
+	if !errors.Is(err, ErrMaxExposure) {
+		t.Fatalf("error = %v, want ErrMaxExposure", err)
+	}
+	if q == nil || q.MaxExposure.Cmp(mnt(50)) != 0 {
+		t.Errorf("rejected quote should report the limit, got %+v", q)
+	}
+
+	// Outstanding exposure and the contract reserve shrink the limit
+	bank := Bankroll{Balance: mnt(1000), MinLiquidity: mnt(200), Exposure: mnt(400)}
+	if q, err := e.Quote(bank, mnt(0.001), 1); err != nil || q.MaxExposure.Cmp(mnt(20)) != 0 {
+		t.Errorf("max exposure = %v (err %v), want 20 MNT", q, err)
+	}
+
+	// The contract's per-transaction cap applies when it is lower
+	bank = Bankroll{Balance: mnt(1000), MaxPayout: mnt(10)}
+	if _, err := e.Quote(bank, mnt(9), 1); !errors.Is(err, ErrMaxExposure) {
+		t.Errorf("payout above maxPayoutPerTx: error = %v", err)
+	}
+}
+
+func TestQuoteWithoutLiquidity(t *testing.T) {
+	e := New(config.Default().Candleflip)
+	for _, bank := range []Bankroll{
+		{},
+		{Balance: mnt(100), MinLiquidity: mnt(100)},
+		{Balance: mnt(100), Exposure: mnt(150)},
+	} {
+		if _, err := e.Quote(bank, mnt(1), 1); !errors.Is(err, ErrNoLiquidity) {
+			t.Errorf("bankroll %+v: error = %v, want ErrNoLiquidity", bank, err)
+		}
+	}
+}
+
+func TestPayout(t *testing.T) {
+	if got := Payout(mnt(1), 3, Legacy); got.Cmp(mnt(6)) != 0 {
+		t.Errorf("legacy payout = %s, want 6 MNT", got)
+	}
+	if got := Payout(big.NewInt(1e16), 2, big.NewInt(1.5e18)); got.String() != "30000000000000000" {
+		t.Errorf("payout at 1.5x = %s", got)
+	}
+}
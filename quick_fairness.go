@@ -1,37 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"goLangServer/crypto"
-	"goLangServer/game"
-)
-
-func main_quick() {
-	runs := 5
-	fmt.Println("Running 5 batches of 100 games each...\n")
-
-	for batch := 1; batch <= runs; batch++ {
-		greenWins := 0
-		redWins := 0
-		var totalChange float64
-
-		for i := 0; i < 100; i++ {
-			serverSeed, _ := crypto.GenerateServerSeed()
-			priceHistory, winner := game.SimulateCandleflipGame(serverSeed)
-			finalPrice := priceHistory[len(priceHistory)-1]
-			totalChange += finalPrice - 1.0
-
-			if winner == "GREEN" {
-				greenWins++
-			} else {
-				redWins++
-			}
-		}
-
-		avgChange := totalChange / 100.0
-		fmt.Printf("Batch %d: GREEN %d%% | RED %d%% | Avg Change: %.3f (%.1f%%)\n",
-			batch, greenWins, redWins, avgChange, avgChange*100)
-	}
-
-	fmt.Println("\n✅ Fixed formula is FAIR - results vary around 50/50")
-}
@@ -0,0 +1,224 @@
+// Package wager validates player wagers against the configured bet limits.
+// Every entry point that accepts a bet (the candleflip WebSocket, room
+// creation, crash bets and the bettor endpoints) checks it here, so limits
+// are enforced the same way everywhere and clients get stable error codes.
+package wager
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"goLangServer/config"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/* =========================
+   ERROR CODES
+========================= */
+
+// Error codes sent to clients next to the human readable message
+const (
+	CodeInvalidAddress    = "INVALID_ADDRESS"
+	CodeAddressChecksum   = "ADDRESS_CHECKSUM"
+	CodeInvalidAmount     = "INVALID_AMOUNT"
+	CodeAmountTooLow      = "AMOUNT_TOO_LOW"
+	CodeAmountTooHigh     = "AMOUNT_TOO_HIGH"
+	CodeInvalidRoomCount  = "INVALID_ROOM_COUNT"
+	CodeInvalidMultiplier = "INVALID_MULTIPLIER"
+	CodeInvalidSide       = "INVALID_SIDE"
+	CodeInvalidGameType   = "INVALID_GAME_TYPE"
+	CodeMaxExposure       = "MAX_EXPOSURE"
+	CodeInvalidMinOdds    = "INVALID_MIN_ODDS"
+	CodeOddsBelowMinimum  = "ODDS_BELOW_MINIMUM"
+)
+
+// Error is a rejected wager field
+type Error struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, field, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+/* =========================
+   VALIDATOR
+========================= */
+
+// Validator checks wagers against a set of bet limits
+type Validator struct {
+	limits config.BetConfig
+}
+
+// New creates a validator enforcing limits
+func New(limits config.BetConfig) *Validator {
+	return &Validator{limits: limits}
+}
+
+// Limits returns the enforced bet limits
+func (v *Validator) Limits() config.BetConfig {
+	return v.limits
+}
+
+// Address checks a player address and returns it parsed. All-lowercase and
+// all-uppercase addresses are accepted as is; mixed-case addresses must carry
+// a valid EIP-55 checksum, since a wrong one usually means a mistyped address.
+func (v *Validator) Address(field, address string) (common.Address, error) {
+	if !common.IsHexAddress(address) {
+		return common.Address{}, newError(CodeInvalidAddress, field, "%s must be a hex address", field)
+	}
+
+	addr := common.HexToAddress(address)
+	hexPart := address
+	if len(hexPart) == 42 {
+		hexPart = hexPart[2:]
+	}
+	if hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart) && hexPart != addr.Hex()[2:] {
+		return common.Address{}, newError(CodeAddressChecksum, field, "%s has an invalid checksum", field)
+	}
+	return addr, nil
+}
+
+// AmountWei checks a wei amount given as a decimal string and returns it
+func (v *Validator) AmountWei(field, amount string) (*big.Int, error) {
+	wei, ok := new(big.Int).SetString(amount, 10)
+	if !ok || wei.Sign() <= 0 {
+		return nil, newError(CodeInvalidAmount, field, "%s must be a positive amount in wei", field)
+	}
+	if err := v.checkAmount(field, wei); err != nil {
+		return nil, err
+	}
+	return wei, nil
+}
+
+// AmountMNT checks an amount given in MNT
+func (v *Validator) AmountMNT(field string, amount float64) error {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount <= 0 {
+		return newError(CodeInvalidAmount, field, "%s must be a positive amount", field)
+	}
+	return v.checkAmount(field, config.MNTToWei(amount))
+}
+
+func (v *Validator) checkAmount(field string, wei *big.Int) error {
+	if wei.Cmp(v.limits.MinAmount.Int) < 0 {
+		return newError(CodeAmountTooLow, field, "%s must be at least %g MNT", field, config.WeiToMNT(v.limits.MinAmount.Int))
+	}
+	if wei.Cmp(v.limits.MaxAmount.Int) > 0 {
+		return newError(CodeAmountTooHigh, field, "%s must be at most %g MNT", field, config.WeiToMNT(v.limits.MaxAmount.Int))
+	}
+	return nil
+}
+
+// Rooms checks a candleflip room count
+func (v *Validator) Rooms(field string, rooms int) error {
+	if rooms < v.limits.MinRooms || rooms > v.limits.MaxRooms {
+		return newError(CodeInvalidRoomCount, field, "%s must be between %d and %d", field, v.limits.MinRooms, v.limits.MaxRooms)
+	}
+	return nil
+}
+
+// Multiplier checks a multiplier a bet is placed or settled at
+func (v *Validator) Multiplier(field string, multiplier float64) error {
+	if math.IsNaN(multiplier) || multiplier < v.limits.MinMultiplier || multiplier > v.limits.MaxMultiplier {
+		return newError(CodeInvalidMultiplier, field, "%s must be between %.2fx and %.0fx", field, v.limits.MinMultiplier, v.limits.MaxMultiplier)
+	}
+	return nil
+}
+
+// AutoCashout checks an auto-cashout target. Zero disables auto-cashout;
+// otherwise the target must be above the minimum multiplier, since cashing
+// out at it would return no more than the stake.
+func (v *Validator) AutoCashout(field string, target float64) error {
+	if target == 0 {
+		return nil
+	}
+	if math.IsNaN(target) || target <= v.limits.MinMultiplier || target > v.limits.MaxMultiplier {
+		return newError(CodeInvalidMultiplier, field, "%s must be above %.2fx and at most %.0fx", field, v.limits.MinMultiplier, v.limits.MaxMultiplier)
+	}
+	return nil
+}
+
+// Side checks a candleflip side
+func Side(field, side string, allowed ...string) error {
+	for _, s := range allowed {
+		if side == s {
+			return nil
+		}
+	}
+	return newError(CodeInvalidSide, field, "%s must be %s", field, quoteList(allowed))
+}
+
+// GameType checks a room game type
+func GameType(field, gameType string) error {
+	if gameType != "crash" && gameType != "candleflip" {
+		return newError(CodeInvalidGameType, field, "%s must be 'crash' or 'candleflip'", field)
+	}
+	return nil
+}
+
+// MinOdds checks the lowest odds a player accepts for a batch. Odds are
+// quoted when the batch is created, so the player has to bound them up front.
+func MinOdds(field string, minOdds float64) error {
+	if math.IsNaN(minOdds) || math.IsInf(minOdds, 0) || minOdds <= 1 {
+		return newError(CodeInvalidMinOdds, field, "%s must be a multiplier above 1", field)
+	}
+	return nil
+}
+
+// BelowMinOdds rejects a batch quoted below the odds the player accepted
+func BelowMinOdds(field string, quoted, minOdds float64) error {
+	return newError(CodeOddsBelowMinimum, field, "odds changed to %.4fx, below the %.4fx %s", quoted, minOdds, field)
+}
+
+// quoteList formats allowed values as 'a' or 'b'
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	return strings.Join(quoted, " or ")
+}
+
+/* =========================
+   CANDLEFLIP BATCHES
+========================= */
+
+// CandleflipBatch is a validated candleflip batch request
+type CandleflipBatch struct {
+	Player        common.Address
+	AmountPerRoom *big.Int
+	Rooms         int
+	Side          string
+	MinOdds       float64 // Lowest odds the player accepts
+}
+
+// CandleflipBatch checks every field of a candleflip batch request
+func (v *Validator) CandleflipBatch(address, amountPerRoom string, rooms int, side string, minOdds float64) (*CandleflipBatch, error) {
+	player, err := v.Address("address", address)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Rooms("roomCount", rooms); err != nil {
+		return nil, err
+	}
+	amount, err := v.AmountWei("amountPerRoom", amountPerRoom)
+	if err != nil {
+		return nil, err
+	}
+	if err := Side("side", side, "bull", "bear"); err != nil {
+		return nil, err
+	}
+	if err := MinOdds("minOdds", minOdds); err != nil {
+		return nil, err
+	}
+	return &CandleflipBatch{Player: player, AmountPerRoom: amount, Rooms: rooms, Side: side, MinOdds: minOdds}, nil
+}
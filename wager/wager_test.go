@@ -0,0 +1,150 @@
+package wager
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"goLangServer/config"
+)
+
+// code returns the wager error code of err, or "" when err is nil
+func code(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var werr *Error
+	if !errors.As(err, &werr) {
+		t.Fatalf("error %v is not a *wager.Error", err)
+	}
+	return werr.Code
+}
+
+func TestAddress(t *testing.T) {
+	v := New(config.Default().Bets)
+
+	// Checksummed addresses from EIP-55
+	valid := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	cases := []struct {
+		address string
+		want    string
+	}{
+		{valid, ""},
+		{"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", ""},
+		{strings.ToLower(valid), ""},
+		{"0x" + strings.ToUpper(valid[2:]), ""},
+		{"0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", CodeAddressChecksum},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", CodeInvalidAddress},
+		{"not-an-address", CodeInvalidAddress},
+		{"", CodeInvalidAddress},
+	}
+	for _, c := range cases {
+		_, err := v.Address("address", c.address)
+		if got := code(t, err); got != c.want {
+			t.Errorf("Address(%q) code = %q, want %q", c.address, got, c.want)
+		}
+	}
+
+	if addr, _ := v.Address("address", strings.ToLower(valid)); addr.Hex() != valid {
+		t.Errorf("lowercase address parsed as %s, want %s", addr.Hex(), valid)
+	}
+}
+
+func TestAmounts(t *testing.T) {
+	v := New(config.Default().Bets) // 0.001 to 100 MNT
+
+	wei := []struct {
+		amount string
+		want   string
+	}{
+		{"1000000000000000", ""},
+		{"100000000000000000000", ""},
+		{"999999999999999", CodeAmountTooLow},
+		{"100000000000000000001", CodeAmountTooHigh},
+		{"0", CodeInvalidAmount},
+		{"-5", CodeInvalidAmount},
+		{"1.5", CodeInvalidAmount},
+		{"", CodeInvalidAmount},
+	}
+	for _, c := range wei {
+		_, err := v.AmountWei("amount", c.amount)
+		if got := code(t, err); got != c.want {
+			t.Errorf("AmountWei(%q) code = %q, want %q", c.amount, got, c.want)
+		}
+	}
+
+	mnt := []struct {
+		amount float64
+		want   string
+	}{
+		{0.001, ""},
+		{1, ""},
+		{100, ""},
+		{0.0009, CodeAmountTooLow},
+		{100.5, CodeAmountTooHigh},
+		{0, CodeInvalidAmount},
+		{math.NaN(), CodeInvalidAmount},
+		{math.Inf(1), CodeInvalidAmount},
+	}
+	for _, c := range mnt {
+		if got := code(t, v.AmountMNT("amount", c.amount)); got != c.want {
+			t.Errorf("AmountMNT(%v) code = %q, want %q", c.amount, got, c.want)
+		}
+	}
+}
+
+func TestRoomsAndMultipliers(t *testing.T) {
+	v := New(config.Default().Bets) // 1 to 10 rooms, 1x to 1000x
+
+	for rooms, want := range map[int]string{0: CodeInvalidRoomCount, 1: "", 10: "", 11: CodeInvalidRoomCount, 100: CodeInvalidRoomCount} {
+		if got := code(t, v.Rooms("rooms", rooms)); got != want {
+			t.Errorf("Rooms(%d) code = %q, want %q", rooms, got, want)
+		}
+	}
+
+	for m, want := range map[float64]string{1: "", 2.5: "", 1000: "", 0.5: CodeInvalidMultiplier, 1000.01: CodeInvalidMultiplier} {
+		if got := code(t, v.Multiplier("multiplier", m)); got != want {
+			t.Errorf("Multiplier(%v) code = %q, want %q", m, got, want)
+		}
+	}
+
+	for target, want := range map[float64]string{0: "", 1.01: "", 1000: "", 1: CodeInvalidMultiplier, 1001: CodeInvalidMultiplier} {
+		if got := code(t, v.AutoCashout("autoCashout", target)); got != want {
+			t.Errorf("AutoCashout(%v) code = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestCandleflipBatch(t *testing.T) {
+	v := New(config.Default().Bets)
+	player := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+
+	batch, err := v.CandleflipBatch(player, "10000000000000000", 3, "bear", 1.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.Player.Hex() != player || batch.AmountPerRoom.String() != "10000000000000000" || batch.Rooms != 3 || batch.Side != "bear" || batch.MinOdds != 1.9 {
+		t.Errorf("batch = %+v", batch)
+	}
+
+	_, err = v.CandleflipBatch(player, "10000000000000000", 50, "bull", 1.9)
+	var werr *Error
+	if !errors.As(err, &werr) || werr.Code != CodeInvalidRoomCount || werr.Field != "roomCount" {
+		t.Errorf("50 rooms: error = %v", err)
+	}
+
+	_, err = v.CandleflipBatch(player, "10000000000000000", 3, "sideways", 1.9)
+	if got := code(t, err); got != CodeInvalidSide {
+		t.Errorf("invalid side code = %q", got)
+	}
+
+	// Batches must bound the odds they are quoted at
+	for _, minOdds := range []float64{0, 1, math.NaN()} {
+		_, err = v.CandleflipBatch(player, "10000000000000000", 3, "bull", minOdds)
+		if got := code(t, err); got != CodeInvalidMinOdds {
+			t.Errorf("minOdds %v code = %q", minOdds, got)
+		}
+	}
+}
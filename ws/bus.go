@@ -0,0 +1,339 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/db"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Server instances share the hub channels through Redis pub/sub. Once the
+// bus is started the hub publishes every message instead of delivering it,
+// and delivers what it receives from Redis, its own messages included, so
+// every instance sends the same messages with the same sequence numbers,
+// which Redis assigns. Chat history and the global room list live in Redis
+// too. Without Redis the hub delivers locally, as a single instance.
+//
+// Only one instance runs crash rounds at a time: the one holding the crash
+// lease. The others relay its rounds to their subscribers and forward bets
+// and cashouts to it.
+
+var (
+	// hubBus is set once hub channels go through Redis
+	hubBus atomic.Bool
+
+	// hubIncoming carries messages received from Redis to the hub
+	hubIncoming = make(chan busMessage, 256)
+
+	// crashLeader is set while this instance holds the crash lease, which
+	// it last renewed at leaseRenewedAt (unix nanoseconds)
+	crashLeader    atomic.Bool
+	leaseRenewedAt atomic.Int64
+
+	// instanceID identifies this instance as a lease holder
+	instanceID = newInstanceID()
+)
+
+// busPayload is the form a hub message is published in
+type busPayload struct {
+	Message  map[string]interface{}         `json:"message"`
+	Variants map[int]map[string]interface{} `json:"variants,omitempty"`
+}
+
+// busMessage is a hub message received from Redis
+type busMessage struct {
+	channel  string
+	seq      uint64
+	message  map[string]interface{}
+	variants map[int]map[string]interface{}
+}
+
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// StartHubBus routes hub channels through Redis and starts competing for the
+// crash lease. It must be called after Redis is initialised and before the
+// crash loop starts; without Redis the hub stays local.
+func StartHubBus() {
+	if db.RedisClient == nil {
+		log.Println("📡 Hub channels are local to this instance (Redis unavailable)")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Wait for the subscription so no message published from now on is missed
+	pubsub := db.SubscribeHub(context.Background())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("⚠️ Failed to subscribe to hub channels, keeping them local: %v", err)
+		pubsub.Close()
+		return
+	}
+	hubBus.Store(true)
+	go runBusReceiver(pubsub.Channel())
+
+	renewCrashLease()
+	go holdCrashLease()
+	go runCrashCommands()
+
+	// Share this instance's view of the room list with late subscribers
+	BroadcastRoomUpdate()
+
+	log.Printf("📡 Hub channels shared through Redis (instance %s, crash leader: %v)", instanceID, crashLeader.Load())
+}
+
+// runBusReceiver hands messages received from Redis to the hub
+func runBusReceiver(messages <-chan *redis.Message) {
+	for msg := range messages {
+		envelope, err := db.ParseHubMessage(msg)
+		if err != nil {
+			log.Printf("⚠️ Dropping hub message: %v", err)
+			continue
+		}
+		message, variants, err := decodeBusPayload(envelope.Channel, envelope.Payload)
+		if err != nil {
+			log.Printf("⚠️ Dropping %s message %d: %v", envelope.Channel, envelope.Seq, err)
+			continue
+		}
+		hubIncoming <- busMessage{channel: envelope.Channel, seq: envelope.Seq, message: message, variants: variants}
+	}
+	log.Println("⚠️ Hub subscription closed")
+}
+
+// dispatchHubMessage publishes a message through Redis, or delivers it here
+// when the bus is not running. It runs on the hub goroutine.
+func dispatchHubMessage(channel string, message map[string]interface{}, variants map[int]map[string]interface{}) {
+	if !hubBus.Load() {
+		deliverHubMessage(channel, 0, message, variants)
+		return
+	}
+
+	payload, err := json.Marshal(busPayload{Message: message, Variants: variants})
+	if err != nil {
+		log.Printf("❌ Failed to marshal %s message: %v", channel, err)
+		return
+	}
+
+	historyLen := 0
+	if channel == "chat" {
+		historyLen = maxChatHistory
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := db.PublishHubMessage(ctx, channel, payload, historyLen); err != nil {
+		log.Printf("⚠️ %v, delivering to this instance only", err)
+		deliverHubMessage(channel, 0, message, variants)
+	}
+}
+
+// deliverHubMessage sends a message to this instance's subscribers of the
+// channel. A seq of 0 takes the channel's next local sequence number.
+func deliverHubMessage(channel string, seq uint64, message map[string]interface{}, variants map[int]map[string]interface{}) {
+	stamped := broadcastToSubscribers(channel, seq, message, variants)
+
+	switch channelKind(channel) {
+	case "crash":
+		// Instances that do not run crash rounds learn the current round here
+		if stamped["type"] == "game_start" {
+			if data, ok := stamped["data"].(map[string]interface{}); ok {
+				if gameID, ok := data["gameId"].(string); ok {
+					SetCurrentGameID(gameID)
+				}
+			}
+		}
+
+	case "chat":
+		if hubBus.Load() {
+			break // Kept in Redis by the publisher
+		}
+		chatHistoryMutex.Lock()
+		chatHistory = append(chatHistory, stamped)
+		if len(chatHistory) > maxChatHistory {
+			// Remove oldest message (FIFO)
+			chatHistory = chatHistory[1:]
+		}
+		chatHistoryMutex.Unlock()
+
+	case "candleflip":
+		select {
+		case candleflipOut <- stamped:
+		default:
+			log.Printf("⚠️ CandleFlip fan-out is behind, dropping %v on %s", stamped["type"], channel)
+		}
+	}
+}
+
+// sharedChatHistory returns the chat history kept in Redis as stamped messages
+func sharedChatHistory() []map[string]interface{} {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	envelopes, err := db.GetHubHistory(ctx, "chat")
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return nil
+	}
+
+	history := make([]map[string]interface{}, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		message, _, err := decodeBusPayload("chat", envelope.Payload)
+		if err != nil {
+			continue
+		}
+		message["channel"] = "chat"
+		message["seq"] = envelope.Seq
+		history = append(history, message)
+	}
+	return history
+}
+
+/* =========================
+   PAYLOAD DECODING
+========================= */
+
+// decodeBusPayload decodes a published message and its variants. Messages
+// with a schema get their typed data back, so the binary encoding is the
+// same on every instance; other fields decode as in a client message.
+func decodeBusPayload(channel string, data []byte) (map[string]interface{}, map[int]map[string]interface{}, error) {
+	var payload struct {
+		Message  json.RawMessage            `json:"message"`
+		Variants map[string]json.RawMessage `json:"variants"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, nil, err
+	}
+
+	message, err := decodeBusMessage(channel, payload.Message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var variants map[int]map[string]interface{}
+	for key, raw := range payload.Variants {
+		protocol, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid protocol version %q", key)
+		}
+		variant, err := decodeBusMessage(channel, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if variants == nil {
+			variants = make(map[int]map[string]interface{})
+		}
+		variants[protocol] = variant
+	}
+	return message, variants, nil
+}
+
+func decodeBusMessage(channel string, data json.RawMessage) (map[string]interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var msgType string
+	json.Unmarshal(fields["type"], &msgType)
+
+	message := make(map[string]interface{}, len(fields))
+	for key, raw := range fields {
+		var value interface{}
+		switch {
+		case channel == "crash" && key == "data" && msgType == "price_update":
+			value = &PriceUpdate{}
+		case channel == "crash" && key == "data" && msgType == "price_tick":
+			value = &PriceTick{}
+		case channel == "crash" && key == "data" && msgType == "game_end":
+			value = &GameEnd{}
+		case channel == "rooms" && key == "rooms" && msgType == "rooms_update":
+			value = &[]*RoomInfo{}
+		}
+
+		if value != nil {
+			if err := json.Unmarshal(raw, value); err != nil {
+				return nil, fmt.Errorf("invalid %s %s: %w", msgType, key, err)
+			}
+			if rooms, ok := value.(*[]*RoomInfo); ok {
+				value = *rooms
+			}
+			message[key] = value
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		message[key] = value
+	}
+	return message, nil
+}
+
+/* =========================
+   CRASH LEASE
+========================= */
+
+// isCrashLeader reports whether this instance runs crash rounds. Without
+// the bus it is the only instance, so it always does. A lease not renewed
+// within its TTL may have been taken by another instance, even if this one
+// has not heard of it yet.
+func isCrashLeader() bool {
+	if !hubBus.Load() {
+		return true
+	}
+	renewed := time.Unix(0, leaseRenewedAt.Load())
+	return crashLeader.Load() && time.Since(renewed) < config.CrashLeaseTTL
+}
+
+// holdCrashLease renews or competes for the crash lease until shutdown
+func holdCrashLease() {
+	ticker := time.NewTicker(config.CrashLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		renewCrashLease()
+	}
+}
+
+func renewCrashLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// The lease runs from before the request, in case it is slow
+	requested := time.Now()
+	held, err := db.AcquireLease(ctx, config.RedisCrashLeaderKey, instanceID, config.CrashLeaseTTL)
+	if err != nil {
+		// Keep the current role: isCrashLeader gives it up once the lease
+		// could have expired
+		log.Printf("⚠️ %v", err)
+		return
+	}
+	if held {
+		leaseRenewedAt.Store(requested.UnixNano())
+	}
+	if crashLeader.Swap(held) != held {
+		if held {
+			log.Printf("👑 Instance %s now runs crash rounds", instanceID)
+		} else {
+			log.Printf("👥 Instance %s lost the crash lease, relaying rounds", instanceID)
+		}
+	}
+}
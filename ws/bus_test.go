@@ -0,0 +1,46 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestBusPayloadRoundTrip checks that a message received through Redis
+// encodes exactly as the message that was published
+func TestBusPayloadRoundTrip(t *testing.T) {
+	update, tick := benchRound(120)
+	delete(update, "channel")
+	delete(update, "seq")
+	delete(tick, "channel")
+	delete(tick, "seq")
+
+	payload, err := json.Marshal(busPayload{Message: update, Variants: map[int]map[string]interface{}{protocolDelta: tick}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, variants, err := decodeBusPayload("crash", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name      string
+		published map[string]interface{}
+		received  map[string]interface{}
+	}{
+		{"price_update", update, message},
+		{"price_tick", tick, variants[protocolDelta]},
+	} {
+		for _, encoding := range []string{encodingJSON, encodingMsgpack} {
+			want, _ := encodeMessage(encoding, c.published)
+			got, err := encodeMessage(encoding, c.received)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s in %s differs after the round trip", c.name, encoding)
+			}
+		}
+	}
+}
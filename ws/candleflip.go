@@ -4,6 +4,7 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -12,9 +13,11 @@ import (
 	"time"
 
 	"goLangServer/config"
-	"goLangServer/contract"
 	"goLangServer/crypto"
+	"goLangServer/db"
 	"goLangServer/game"
+	"goLangServer/odds"
+	"goLangServer/wager"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/websocket"
@@ -22,13 +25,13 @@ import (
 
 // Room represents a single CandleFlip room in a batch
 type Room struct {
-	RoomNumber  int       `json:"roomNumber"`
-	Status      string    `json:"status"` // "waiting", "running", "completed"
-	FinalPrice  float64   `json:"finalPrice,omitempty"`
-	Winner      string    `json:"winner,omitempty"` // "bull" or "bear"
-	PlayerWon   bool      `json:"playerWon"`
-	StartTime   time.Time `json:"startTime,omitempty"`
-	EndTime     time.Time `json:"endTime,omitempty"`
+	RoomNumber int       `json:"roomNumber"`
+	Status     string    `json:"status"` // "waiting", "running", "completed"
+	FinalPrice float64   `json:"finalPrice,omitempty"`
+	Winner     string    `json:"winner,omitempty"` // "bull" or "bear"
+	PlayerWon  bool      `json:"playerWon"`
+	StartTime  time.Time `json:"startTime,omitempty"`
+	EndTime    time.Time `json:"endTime,omitempty"`
 }
 
 // CandleflipBatch represents a batch of rooms for a single player
@@ -41,7 +44,12 @@ type CandleflipBatch struct {
 	Rooms          []*Room
 	ServerSeed     string
 	ServerSeedHash string
-	Status         string // "waiting", "running", "completed", "paid"
+	ClientSeed     string // Empty for legacy batches seeded without a client seed
+	Nonce          uint64 // Nonce of room 1; room i uses Nonce+i-1
+	RNGVersion     int
+	PriceModel     int      // game.CandleflipPriceModel* the rooms are played with
+	Odds           *big.Int // Payout per won room as a multiplier with 18 decimals
+	Status         string   // "waiting", "running", "completed", "paying", "paid", "payout_failed"
 	WonRooms       int
 	PayoutAmount   *big.Int
 	PayoutTxHash   string
@@ -59,13 +67,19 @@ func (b *CandleflipBatch) RUnlock() {
 	b.mu.RUnlock()
 }
 
+// RoomSeed returns the seed for the room at index i (0-based)
+func (b *CandleflipBatch) RoomSeed(i int) string {
+	return crypto.CandleflipRoomSeed(b.ServerSeed, b.ClientSeed, b.Nonce, i)
+}
+
 // CreateBatchMessage - Client creates a new batch
 type CreateBatchMessage struct {
-	Type          string `json:"type"` // "create_batch"
-	Address       string `json:"address"`
-	RoomCount     int    `json:"roomCount"`
-	AmountPerRoom string `json:"amountPerRoom"` // wei
-	Side          string `json:"side"` // "bull" or "bear"
+	Type          string  `json:"type"` // "create_batch"
+	Address       string  `json:"address"`
+	RoomCount     int     `json:"roomCount"`
+	AmountPerRoom string  `json:"amountPerRoom"` // wei
+	Side          string  `json:"side"`          // "bull" or "bear"
+	MinOdds       float64 `json:"minOdds"`       // Lowest odds the player accepts, e.g. the previewed odds
 }
 
 var (
@@ -74,22 +88,39 @@ var (
 	candleflipBatchesMutex sync.RWMutex
 
 	// Connected clients
-	candleflipClients      = make(map[*websocket.Conn]bool)
+	candleflipClients      = make(map[*wsConn]bool)
 	candleflipClientsMutex sync.RWMutex
+
+	// Batch events for the hub, which publishes them on the batch's channel
+	candleflipBroadcast = make(chan map[string]interface{}, 100)
+
+	// Batch events delivered by the hub, for the /candleflip clients
+	candleflipOut = make(chan map[string]interface{}, 256)
 )
 
-// GetBatch retrieves a batch by ID (thread-safe)
+func init() {
+	go runCandleflipFanOut()
+	go holdBatchLeases()
+}
+
+// GetBatch retrieves a batch by ID (thread-safe), falling back to the
+// database for batches that are no longer in memory
 func GetBatch(batchID string) *CandleflipBatch {
 	candleflipBatchesMutex.RLock()
-	defer candleflipBatchesMutex.RUnlock()
-	return candleflipBatches[batchID]
+	batch := candleflipBatches[batchID]
+	candleflipBatchesMutex.RUnlock()
+
+	if batch != nil {
+		return batch
+	}
+	return loadBatch(batchID)
 }
 
 // GetAllBatches returns all active batches (for HTTP endpoint)
 func GetAllBatches() []*CandleflipBatch {
 	candleflipBatchesMutex.RLock()
 	defer candleflipBatchesMutex.RUnlock()
-	
+
 	batches := make([]*CandleflipBatch, 0, len(candleflipBatches))
 	for _, batch := range candleflipBatches {
 		batches = append(batches, batch)
@@ -97,15 +128,33 @@ func GetAllBatches() []*CandleflipBatch {
 	return batches
 }
 
-// Broadcast to all connected clients
+// Broadcast to all connected clients, on every instance, and to /ws clients
+// subscribed to the batch's channel
 func broadcastToAllCandleflipClients(message map[string]interface{}) {
-	candleflipClientsMutex.RLock()
-	defer candleflipClientsMutex.RUnlock()
+	candleflipBroadcast <- message
+}
+
+// candleflipChannel is the hub channel of a batch event: candleflip:<batchId>
+func candleflipChannel(message map[string]interface{}) string {
+	if data, ok := message["data"].(map[string]interface{}); ok {
+		if batchID, ok := data["batchId"].(string); ok && batchID != "" {
+			return "candleflip:" + batchID
+		}
+	}
+	return "candleflip"
+}
 
-	for conn := range candleflipClients {
-		if err := conn.WriteJSON(message); err != nil {
-			log.Printf("❌ Failed to broadcast to candleflip client: %v", err)
+// runCandleflipFanOut writes the batch events the hub delivers to the
+// /candleflip clients, so a slow client never holds up the hub
+func runCandleflipFanOut() {
+	for message := range candleflipOut {
+		candleflipClientsMutex.RLock()
+		for conn := range candleflipClients {
+			if err := conn.WriteJSON(message); err != nil {
+				log.Printf("❌ Failed to broadcast to candleflip client: %v", err)
+			}
 		}
+		candleflipClientsMutex.RUnlock()
 	}
 }
 
@@ -113,7 +162,7 @@ func broadcastToAllCandleflipClients(message map[string]interface{}) {
 func HandleCandleflipWS(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔥 CandleFlip WebSocket connection from: %s", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgradeConn(w, r, nil, "candleflip")
 	if err != nil {
 		log.Println("❌ WebSocket upgrade failed:", err)
 		return
@@ -150,13 +199,13 @@ func HandleCandleflipWS(w http.ResponseWriter, r *http.Request) {
 	candleflipClientsMutex.Lock()
 	delete(candleflipClients, conn)
 	candleflipClientsMutex.Unlock()
-	
+
 	conn.Close()
 	log.Printf("👋 CandleFlip client disconnected")
 }
 
 // Handle incoming messages
-func handleCandleflipMessage(conn *websocket.Conn, message []byte) {
+func handleCandleflipMessage(conn *wsConn, message []byte) {
 	var msg CreateBatchMessage
 	if err := json.Unmarshal(message, &msg); err != nil {
 		log.Printf("❌ Failed to parse candleflip message: %v", err)
@@ -172,54 +221,45 @@ func handleCandleflipMessage(conn *websocket.Conn, message []byte) {
 		return
 	}
 
-	// Validate inputs
-	if msg.Address == "" {
-		conn.WriteJSON(map[string]interface{}{
-			"type":  "error",
-			"error": "Address is required",
-		})
-		return
-	}
-
-	if msg.RoomCount < 1 || msg.RoomCount > 100 {
-		conn.WriteJSON(map[string]interface{}{
-			"type":  "error",
-			"error": "Room count must be between 1 and 100",
-		})
+	// Validate the wager against the configured limits
+	wagerReq, err := wagers.CandleflipBatch(msg.Address, msg.AmountPerRoom, msg.RoomCount, msg.Side, msg.MinOdds)
+	if err != nil {
+		conn.WriteJSON(errorReply("error", err))
 		return
 	}
+	playerAddr := wagerReq.Player
+	amountWei := wagerReq.AmountPerRoom
 
-	if msg.AmountPerRoom == "" {
+	// Read the bankroll before pricing the batch against it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	bank, err := candleflipBankroll(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("⚠️ Failed to read house bankroll: %v", err)
 		conn.WriteJSON(map[string]interface{}{
 			"type":  "error",
-			"error": "Amount per room is required",
+			"error": "House bankroll unavailable, try again later",
 		})
 		return
 	}
 
-	if msg.Side != "bull" && msg.Side != "bear" {
-		conn.WriteJSON(map[string]interface{}{
-			"type":  "error",
-			"error": "Side must be 'bull' or 'bear'",
-		})
-		return
-	}
+	// Create batch
+	batchID := fmt.Sprintf("batch-%s-%d", playerAddr.Hex()[:8], time.Now().UnixNano())
 
-	// Parse amount
-	playerAddr := common.HexToAddress(msg.Address)
-	amountWei, ok := new(big.Int).SetString(msg.AmountPerRoom, 10)
-	if !ok {
+	// Claim one nonce per room from the player's committed seed pair. A batch
+	// played with any other seed could not be verified against the pair.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	pair, nonce, err := db.ReserveNonces(ctx, playerAddr.Hex(), msg.RoomCount)
+	cancel()
+	if err != nil {
+		log.Printf("❌ Failed to reserve nonces for %s: %v", playerAddr.Hex(), err)
 		conn.WriteJSON(map[string]interface{}{
 			"type":  "error",
-			"error": "Invalid amount format",
+			"error": "Seed pair unavailable, try again later",
 		})
 		return
 	}
 
-	// Create batch
-	batchID := fmt.Sprintf("batch-%s-%d", playerAddr.Hex()[:8], time.Now().UnixNano())
-	serverSeed, seedHash := crypto.GenerateServerSeed()
-
 	batch := &CandleflipBatch{
 		BatchID:        batchID,
 		PlayerAddress:  playerAddr,
@@ -227,8 +267,12 @@ func handleCandleflipMessage(conn *websocket.Conn, message []byte) {
 		TotalRooms:     msg.RoomCount,
 		PlayerSide:     msg.Side,
 		Rooms:          make([]*Room, msg.RoomCount),
-		ServerSeed:     serverSeed,
-		ServerSeedHash: seedHash,
+		ServerSeed:     pair.ServerSeed,
+		ServerSeedHash: pair.ServerSeedHash,
+		ClientSeed:     pair.ClientSeed,
+		Nonce:          nonce,
+		RNGVersion:     game.CurrentRNGVersion,
+		PriceModel:     serverConfig.Candleflip.PriceModel,
 		Status:         "waiting",
 		CreatedAt:      time.Now(),
 	}
@@ -241,26 +285,54 @@ func handleCandleflipMessage(conn *websocket.Conn, message []byte) {
 		}
 	}
 
-	// Store batch
+	// Price the batch and store it in one step, so its exposure counts
+	// against the next batch. The player's minOdds is their consent to the
+	// quote, a batch priced below it is rejected.
+	exposureMutex.Lock()
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	quote, err := reserveBatch(ctx, bank, batchID, amountWei, msg.RoomCount, wagerReq.MinOdds)
+	cancel()
+	if err != nil {
+		exposureMutex.Unlock()
+		var werr *wager.Error
+		if !errors.As(err, &werr) {
+			log.Printf("⚠️ Failed to reserve exposure for batch %s: %v", batchID, err)
+			err = errors.New("House bankroll unavailable, try again later")
+		}
+		conn.WriteJSON(errorReply("error", err))
+		return
+	}
+	batch.Odds = quote.Odds
+
 	candleflipBatchesMutex.Lock()
 	if _, exists := candleflipBatches[batchID]; exists {
-			candleflipBatchesMutex.Unlock()
-			conn.WriteJSON(map[string]interface{}{
-					"type":  "error",
-					"error": "Batch ID collision, retry",
-			})
-			return
+		candleflipBatchesMutex.Unlock()
+		exposureMutex.Unlock()
+		releaseBatchExposure(batchID)
+		conn.WriteJSON(map[string]interface{}{
+			"type":  "error",
+			"error": "Batch ID collision, retry",
+		})
+		return
 	}
 	candleflipBatches[batchID] = batch
 	candleflipBatchesMutex.Unlock()
+	exposureMutex.Unlock()
 
-	log.Printf("🎮 CandleFlip batch created - Batch: %s, Player: %s, Rooms: %d, Amount: %s, Side: %s",
-		batchID, msg.Address, msg.RoomCount, msg.AmountPerRoom, msg.Side)
+	// Hold the batch so no instance starting up resumes it
+	if !claimBatch(batchID) {
+		log.Printf("⚠️ Failed to claim batch %s, another instance could resume it", batchID)
+	}
+	persistBatch(batch)
+
+	log.Printf("🎮 CandleFlip batch created - Batch: %s, Player: %s, Rooms: %d, Amount: %s, Side: %s, Odds: %.4fx",
+		batchID, msg.Address, msg.RoomCount, msg.AmountPerRoom, msg.Side, quote.OddsFloat())
 
 	// Send batch_created response to requester
 	conn.WriteJSON(map[string]interface{}{
 		"type":    "batch_created",
 		"batchId": batchID,
+		"odds":    quote.OddsFloat(),
 	})
 
 	// Broadcast batch start to all clients
@@ -273,7 +345,13 @@ func handleCandleflipMessage(conn *websocket.Conn, message []byte) {
 			"amountPerRoom":  msg.AmountPerRoom,
 			"playerSide":     msg.Side,
 			"aiSide":         getOppositeSide(msg.Side),
-			"serverSeedHash": seedHash,
+			"serverSeedHash": pair.ServerSeedHash,
+			"clientSeed":     pair.ClientSeed,
+			"nonce":          nonce,
+			"rngVersion":     batch.RNGVersion,
+			"priceModel":     batch.PriceModel,
+			"odds":           quote.OddsFloat(),
+			"oddsWei":        quote.Odds.String(),
 		},
 	})
 
@@ -287,12 +365,22 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 	batch.Status = "running"
 	batch.mu.Unlock()
 
+	persistBatch(batch)
+
 	wonRooms := 0
 
 	// Run each room
 	for i := 0; i < batch.TotalRooms; i++ {
 		room := batch.Rooms[i]
-		
+
+		// Rooms played before a restart keep their result
+		if room.Status == "completed" {
+			if room.PlayerWon {
+				wonRooms++
+			}
+			continue
+		}
+
 		room.Status = "running"
 		room.StartTime = time.Now()
 
@@ -306,19 +394,11 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 		})
 
 		// Generate price movement for this room
-		roomSeed := fmt.Sprintf("%s-room-%d", batch.ServerSeed, i)
-		rng := game.NewSeededRNG(roomSeed)
+		result := game.PlayCandleflipRoom(batch.RNGVersion, batch.PriceModel, batch.RoomSeed(i))
 
-		currentPrice := game.CandleflipStartingPrice
-		priceHistory := []float64{currentPrice}
-
-		// Simulate ticks
+		// Play back ticks
 		for tick := 0; tick < game.CandleflipTotalTicks; tick++ {
-			currentPrice = game.GenerateCandleflipPrice(rng, currentPrice)
-			if currentPrice < 0 {
-				currentPrice = 0
-			}
-			priceHistory = append(priceHistory, currentPrice)
+			currentPrice := result.Prices[tick+1]
 
 			// Broadcast price update
 			broadcastToAllCandleflipClients(map[string]interface{}{
@@ -332,17 +412,11 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 				},
 			})
 
-			time.Sleep(100 * time.Millisecond)
+			time.Sleep(serverConfig.Candleflip.TickInterval.D())
 		}
 
-		// Determine winner
-		finalPrice := currentPrice
-		var winner string
-		if finalPrice >= game.CandleflipStartingPrice {
-			winner = "bull"
-		} else {
-			winner = "bear"
-		}
+		finalPrice := result.FinalPrice
+		winner := result.Winner
 
 		playerWon := (winner == batch.PlayerSide)
 		if playerWon {
@@ -356,6 +430,8 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 		room.PlayerWon = playerWon
 		room.EndTime = time.Now()
 
+		persistBatch(batch)
+
 		// Broadcast room end
 		broadcastToAllCandleflipClients(map[string]interface{}{
 			"type": "room_end",
@@ -371,7 +447,7 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 		log.Printf("🎲 Room %d/%d - Final: %.3f, Winner: %s, Player Won: %v",
 			i+1, batch.TotalRooms, finalPrice, winner, playerWon)
 
-		time.Sleep(500 * time.Millisecond)
+		time.Sleep(serverConfig.Candleflip.RoomInterval.D())
 	}
 
 	// Update batch
@@ -381,24 +457,38 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 	batch.CompletedAt = time.Now()
 	batch.mu.Unlock()
 
-	// Broadcast batch end
+	// Broadcast batch end. Seed pairs stay secret until the player rotates them,
+	// only legacy per-batch seeds are revealed here.
+	endData := map[string]interface{}{
+		"batchId":    batch.BatchID,
+		"totalRooms": batch.TotalRooms,
+		"wonRooms":   wonRooms,
+	}
+	if batch.ClientSeed == "" {
+		endData["serverSeed"] = batch.ServerSeed
+	}
 	broadcastToAllCandleflipClients(map[string]interface{}{
 		"type": "batch_end",
-		"data": map[string]interface{}{
-			"batchId":    batch.BatchID,
-			"totalRooms": batch.TotalRooms,
-			"wonRooms":   wonRooms,
-			"serverSeed": batch.ServerSeed,
-		},
+		"data": endData,
 	})
 
 	log.Printf("🎯 CandleFlip batch complete - Player won %d/%d rooms", wonRooms, batch.TotalRooms)
 
-	// Attempt payout (non-blocking)
+	persistBatch(batch)
+
+	finishCandleflipBatch(batch)
+}
+
+// finishCandleflipBatch pays out a completed batch and removes it from memory
+func finishCandleflipBatch(batch *CandleflipBatch) {
+	// Attempt payout (non-blocking). A queued payout is counted by the payout
+	// queue from here on, so the batch's own exposure is released.
 	payoutCandleflipWinnings(batch)
+	persistBatch(batch)
+	releaseBatchExposure(batch.BatchID)
 
-	// Wait exactly 5 seconds after payout attempt finishes
-	time.Sleep(5 * time.Second)
+	// Keep the batch visible for a while after the payout attempt finishes
+	time.Sleep(serverConfig.Candleflip.CleanupDelay.D())
 
 	// Delete batch
 	candleflipBatchesMutex.Lock()
@@ -412,80 +502,72 @@ func runCandleflipBatch(batch *CandleflipBatch) {
 func payoutCandleflipWinnings(batch *CandleflipBatch) {
 	if batch.WonRooms == 0 {
 		log.Printf("❌ Player won 0 rooms, no payout for %s", batch.PlayerAddress.Hex())
-		
+
 		batch.mu.Lock()
 		batch.Status = "paid"
 		batch.PayoutAmount = big.NewInt(0)
 		batch.mu.Unlock()
-		
+
 		return
 	}
 
-	// Calculate payout: wonRooms * amountPerRoom * 2
-	wonRoomsBig := big.NewInt(int64(batch.WonRooms))
-	multiplier := big.NewInt(2)
-
-	payout := new(big.Int).Mul(batch.AmountPerRoom, wonRoomsBig)
-	payout.Mul(payout, multiplier)
+	// Calculate payout: wonRooms * amountPerRoom * odds the batch was accepted at
+	payout := odds.Payout(batch.AmountPerRoom, batch.WonRooms, batch.Odds)
 
 	batch.mu.Lock()
 	batch.PayoutAmount = payout
 	batch.mu.Unlock()
 
-	log.Printf("💰 Calculating payout: %d rooms × %s wei/room × 2 = %s wei",
-		batch.WonRooms, batch.AmountPerRoom.String(), payout.String())
+	log.Printf("💰 Calculating payout: %d rooms × %s wei/room × %.4f = %s wei",
+		batch.WonRooms, batch.AmountPerRoom.String(), config.WeiToMultiplier(batch.Odds), payout.String())
 
-	// Initialize contract
-	contractClient, err := contract.NewGameHouseContract()
-	if err != nil {
-		log.Printf("❌ Failed to initialize contract: %v", err)
-		
-		batch.mu.Lock()
-		batch.Status = "paid"
-		batch.PayoutError = err.Error()
-		batch.mu.Unlock()
-		
-		broadcastToAllCandleflipClients(map[string]interface{}{
-			"type": "payout_failed",
-			"data": map[string]interface{}{
-				"batchId": batch.BatchID,
-				"error":   err.Error(),
-			},
-		})
+	// Queue the payout; the payout worker submits it and tracks the receipt
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if db.PostgresPool == nil {
+		markCandleflipPayoutFailed(batch, "payout queue unavailable")
 		return
 	}
-	defer contractClient.Close()
-
-	// Call payPlayer
-	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
-	defer cancel()
 
-	err = contractClient.PayPlayer(ctx, batch.PlayerAddress, payout)
+	queued, err := db.EnqueuePayout(ctx, PayoutKindCandleflip, batch.BatchID, batch.PlayerAddress.Hex(), payout.String())
 	if err != nil {
-		log.Printf("No balance in contract to pay players")
-		
-		batch.mu.Lock()
-		batch.Status = "paid"
-		batch.PayoutError = err.Error()
-		batch.mu.Unlock()
-		
-		broadcastToAllCandleflipClients(map[string]interface{}{
-			"type": "payout_failed",
-			"data": map[string]interface{}{
-				"batchId": batch.BatchID,
-				"error":   "No balance in contract to pay players",
-			},
-		})
+		log.Printf("❌ Failed to queue payout for batch %s: %v", batch.BatchID, err)
+		markCandleflipPayoutFailed(batch, err.Error())
 		return
 	}
 
-	// Success
 	batch.mu.Lock()
-	batch.Status = "paid"
+	batch.Status = "paying"
 	batch.mu.Unlock()
 
+	broadcastToAllCandleflipClients(map[string]interface{}{
+		"type": "payout_queued",
+		"data": map[string]interface{}{
+			"batchId":  batch.BatchID,
+			"payoutId": queued.ID,
+			"amount":   payout.String(),
+		},
+	})
+
 	payoutMNT := config.WeiToMNT(payout)
-	log.Printf("✅ Paid %s: %.4f MNT", batch.PlayerAddress.Hex(), payoutMNT)
+	log.Printf("✅ Queued payout #%d for %s: %.4f MNT", queued.ID, batch.PlayerAddress.Hex(), payoutMNT)
+}
+
+// markCandleflipPayoutFailed records a payout that could not be made
+func markCandleflipPayoutFailed(batch *CandleflipBatch, reason string) {
+	batch.mu.Lock()
+	batch.Status = "payout_failed"
+	batch.PayoutError = reason
+	batch.mu.Unlock()
+
+	broadcastToAllCandleflipClients(map[string]interface{}{
+		"type": "payout_failed",
+		"data": map[string]interface{}{
+			"batchId": batch.BatchID,
+			"error":   reason,
+		},
+	})
 }
 
 // Helper functions
@@ -494,4 +576,4 @@ func getOppositeSide(side string) string {
 		return "bear"
 	}
 	return "bull"
-}
\ No newline at end of file
+}
@@ -0,0 +1,257 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/contract"
+	"goLangServer/db"
+	"goLangServer/odds"
+	"goLangServer/wager"
+)
+
+// houseLiquidityTTL is how long a read of the contract bankroll is reused
+const houseLiquidityTTL = 10 * time.Second
+
+var (
+	// exposureMutex serialises quoting and registering batches on this
+	// instance, so two batches are never both accepted against the same
+	// liquidity. Redis keeps other instances in step.
+	exposureMutex sync.Mutex
+
+	liquidityMutex   sync.Mutex
+	cachedLiquidity  *contract.HouseLiquidity
+	liquidityFetched time.Time
+)
+
+/* =========================
+   BANKROLL
+========================= */
+
+// houseLiquidity returns the contract bankroll, read at most once per
+// houseLiquidityTTL
+func houseLiquidity(ctx context.Context) (*contract.HouseLiquidity, error) {
+	liquidityMutex.Lock()
+	defer liquidityMutex.Unlock()
+
+	if cachedLiquidity != nil && time.Since(liquidityFetched) < houseLiquidityTTL {
+		return cachedLiquidity, nil
+	}
+	if contract.GameHouse == nil {
+		return nil, fmt.Errorf("house contract unavailable")
+	}
+
+	liquidity, err := contract.GameHouse.HouseLiquidity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cachedLiquidity, liquidityFetched = liquidity, time.Now()
+	return liquidity, nil
+}
+
+// candleflipBankroll returns the house bankroll with queued, unconfirmed
+// payouts as its exposure. Unsettled batches are added by quoteBatch.
+func candleflipBankroll(ctx context.Context) (odds.Bankroll, error) {
+	liquidity, err := houseLiquidity(ctx)
+	if err != nil {
+		return odds.Bankroll{}, err
+	}
+
+	queued := big.NewInt(0)
+	if db.PostgresPool != nil {
+		if queued, err = db.GetOutstandingPayoutTotal(ctx); err != nil {
+			return odds.Bankroll{}, err
+		}
+	}
+
+	return odds.Bankroll{
+		Balance:      liquidity.Balance,
+		MinLiquidity: liquidity.MinLiquidity,
+		MaxPayout:    liquidity.MaxPayoutPerTx,
+		Exposure:     queued,
+	}, nil
+}
+
+// invalidateHouseLiquidity drops the cached bankroll, so a confirmed payout
+// is not counted both in the stale balance and as settled
+func invalidateHouseLiquidity() {
+	liquidityMutex.Lock()
+	cachedLiquidity = nil
+	liquidityMutex.Unlock()
+}
+
+// unsettledBatchExposure returns what unsettled batches could still pay out.
+// With Redis it is shared by every instance, otherwise the batches in memory
+// are all there are. Once a payout is queued it is covered by the payout
+// queue total instead.
+func unsettledBatchExposure(ctx context.Context) (*big.Int, error) {
+	if db.RedisClient != nil {
+		return db.GetCandleflipExposure(ctx)
+	}
+	return localBatchExposure(), nil
+}
+
+// localBatchExposure sums what batches in memory could still pay out
+func localBatchExposure() *big.Int {
+	total := big.NewInt(0)
+
+	candleflipBatchesMutex.RLock()
+	defer candleflipBatchesMutex.RUnlock()
+
+	for _, batch := range candleflipBatches {
+		batch.mu.RLock()
+		switch batch.Status {
+		case "waiting", "running", "completed":
+			if batch.PayoutAmount != nil {
+				total.Add(total, batch.PayoutAmount)
+			} else {
+				total.Add(total, odds.Payout(batch.AmountPerRoom, batch.TotalRooms, batch.Odds))
+			}
+		}
+		batch.mu.RUnlock()
+	}
+	return total
+}
+
+// quoteBatch prices a batch against the bankroll and the outstanding
+// exposure of unsettled batches. A batch the bankroll cannot cover is
+// rejected with wager.CodeMaxExposure.
+func quoteBatch(bank odds.Bankroll, outstanding, amountPerRoom *big.Int, rooms int) (*odds.Quote, error) {
+	bank.Exposure = new(big.Int).Add(bank.Exposure, outstanding)
+
+	quote, err := oddsEngine.Quote(bank, amountPerRoom, rooms)
+	if errors.Is(err, odds.ErrMaxExposure) || errors.Is(err, odds.ErrNoLiquidity) {
+		return quote, &wager.Error{Code: wager.CodeMaxExposure, Field: "amountPerRoom", Message: err.Error()}
+	}
+	return quote, err
+}
+
+// reserveBatch prices a batch and records its exposure in one step, so the
+// next batch on any instance is priced against it. A batch quoted below
+// minOdds is rejected with wager.CodeOddsBelowMinimum. Without Redis the
+// caller must hold exposureMutex until the batch is registered in memory.
+func reserveBatch(ctx context.Context, bank odds.Bankroll, batchID string, amountPerRoom *big.Int, rooms int, minOdds float64) (*odds.Quote, error) {
+	var quote *odds.Quote
+	reserve := func(outstanding *big.Int) (*big.Int, error) {
+		q, err := quoteBatch(bank, outstanding, amountPerRoom, rooms)
+		if err != nil {
+			return nil, err
+		}
+		if q.OddsFloat() < minOdds {
+			return nil, wager.BelowMinOdds("minOdds", q.OddsFloat(), minOdds)
+		}
+		quote = q
+		return q.Exposure, nil
+	}
+
+	if db.RedisClient == nil {
+		if _, err := reserve(localBatchExposure()); err != nil {
+			return nil, err
+		}
+		return quote, nil
+	}
+	if err := db.ReserveCandleflipExposure(ctx, batchID, reserve); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// releaseBatchExposure drops a settled batch from the shared exposure
+func releaseBatchExposure(batchID string) {
+	if db.RedisClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := db.ReleaseCandleflipExposure(ctx, batchID); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+/* =========================
+   HTTP HANDLERS
+========================= */
+
+// PreviewOddsRequest is the batch to price
+type PreviewOddsRequest struct {
+	AmountPerRoom string `json:"amountPerRoom"` // wei
+	RoomCount     int    `json:"roomCount"`
+}
+
+// HandlePreviewOdds quotes the odds a batch would be offered right now
+// POST /api/candle/preview-odds
+func HandlePreviewOdds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PreviewOddsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := wagers.Rooms("roomCount", req.RoomCount); err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+	amount, err := wagers.AmountWei("amountPerRoom", req.AmountPerRoom)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	bank, err := candleflipBankroll(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to read house bankroll: %v", err)
+		sendJSONError(w, "House bankroll unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	outstanding, err := unsettledBatchExposure(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to read candleflip exposure: %v", err)
+		sendJSONError(w, "House bankroll unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	quote, err := quoteBatch(bank, outstanding, amount, req.RoomCount)
+
+	var werr *wager.Error
+	if err != nil && !errors.As(err, &werr) {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":       true,
+		"accepted":      err == nil,
+		"amountPerRoom": amount.String(),
+		"roomCount":     req.RoomCount,
+	}
+	if werr != nil {
+		response["code"] = werr.Code
+		response["error"] = werr.Message
+	}
+	if quote != nil {
+		response["odds"] = quote.OddsFloat()
+		response["oddsWei"] = quote.Odds.String()
+		response["exposure"] = quote.Exposure.String()
+		response["maxExposure"] = quote.MaxExposure.String()
+		response["maxExposureMNT"] = config.WeiToMNT(quote.MaxExposure)
+	}
+	sendJSONResponse(w, response)
+}
@@ -0,0 +1,288 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/db"
+	"goLangServer/odds"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Finished reports whether all rooms of the batch have been played.
+// The caller must hold the batch lock.
+func (b *CandleflipBatch) Finished() bool {
+	switch b.Status {
+	case "completed", "paying", "paid", "payout_failed":
+		return true
+	}
+	return false
+}
+
+// persistBatch writes the batch and its rooms to PostgreSQL
+func persistBatch(batch *CandleflipBatch) {
+	if db.PostgresPool == nil {
+		return
+	}
+
+	batch.mu.RLock()
+	record := batchToRecord(batch)
+	batch.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.SaveCandleflipBatch(ctx, record); err != nil {
+		log.Printf("⚠️ Failed to persist batch %s: %v", batch.BatchID, err)
+	}
+}
+
+// loadBatch reads a batch that is no longer in memory from PostgreSQL
+func loadBatch(batchID string) *CandleflipBatch {
+	if db.PostgresPool == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	record, err := db.GetCandleflipBatch(ctx, batchID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load batch %s: %v", batchID, err)
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+	return batchFromRecord(record)
+}
+
+// GetBatchHistory returns a page of a player's finished batches from the
+// database and the total number of batches matching the filter
+func GetBatchHistory(ctx context.Context, filter db.CandleflipHistoryFilter) ([]*CandleflipBatch, int, error) {
+	if db.PostgresPool == nil {
+		return nil, 0, fmt.Errorf("database unavailable")
+	}
+
+	records, total, err := db.GetCandleflipHistory(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	batches := make([]*CandleflipBatch, len(records))
+	for i, record := range records {
+		batches[i] = batchFromRecord(record)
+	}
+	return batches, total, nil
+}
+
+// ResumeCandleflipBatches restarts batches that were interrupted by a
+// restart. Rooms are reproducible from their seeds, so unfinished batches
+// continue from their first unplayed room; finished batches whose payout was
+// never queued are paid out. Batches another instance holds the lease on are
+// left to it.
+func ResumeCandleflipBatches() {
+	if db.PostgresPool == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	records, err := db.GetCandleflipBatchesByStatus(ctx, []string{"waiting", "running", "completed"})
+	if err != nil {
+		log.Printf("⚠️ Failed to load unfinished candleflip batches: %v", err)
+		return
+	}
+
+	resumed := 0
+	for _, record := range records {
+		// Another instance may be running the batch
+		if !claimBatch(record.BatchID) {
+			log.Printf("⏭️ Batch %s is owned by another instance, not resuming it", record.BatchID)
+			continue
+		}
+		batch := batchFromRecord(record)
+		resumed++
+
+		candleflipBatchesMutex.Lock()
+		candleflipBatches[batch.BatchID] = batch
+		candleflipBatchesMutex.Unlock()
+
+		if batch.Status == "completed" {
+			log.Printf("♻️ Resuming payout of batch %s", batch.BatchID)
+			go finishCandleflipBatch(batch)
+		} else {
+			log.Printf("♻️ Resuming batch %s", batch.BatchID)
+			go runCandleflipBatch(batch)
+		}
+	}
+
+	if resumed > 0 {
+		log.Printf("♻️ Resumed %d candleflip batches", resumed)
+	}
+}
+
+// claimBatch takes or renews this instance's lease on a batch, and reports
+// whether it holds it. Without Redis there is only one instance, which owns
+// every batch.
+func claimBatch(batchID string) bool {
+	if db.RedisClient == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	held, err := db.AcquireLease(ctx, fmt.Sprintf(config.RedisCandleflipOwnerKey, batchID), instanceID, config.CandleflipLeaseTTL)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return false
+	}
+	return held
+}
+
+// holdBatchLeases renews the lease on every batch this instance is still
+// playing or paying out, so no other instance resumes it
+func holdBatchLeases() {
+	ticker := time.NewTicker(config.CandleflipLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		candleflipBatchesMutex.RLock()
+		var owned []*CandleflipBatch
+		for _, batch := range candleflipBatches {
+			owned = append(owned, batch)
+		}
+		candleflipBatchesMutex.RUnlock()
+
+		for _, batch := range owned {
+			batch.mu.RLock()
+			status := batch.Status
+			batch.mu.RUnlock()
+			if status == "paid" || status == "payout_failed" {
+				continue
+			}
+			if !claimBatch(batch.BatchID) {
+				log.Printf("⚠️ Lost the lease on batch %s, another instance may resume it", batch.BatchID)
+			}
+		}
+	}
+}
+
+// batchToRecord converts a batch to its database record.
+// The caller must hold the batch lock.
+func batchToRecord(batch *CandleflipBatch) *db.CandleflipBatchRecord {
+	record := &db.CandleflipBatchRecord{
+		BatchID:        batch.BatchID,
+		PlayerAddress:  batch.PlayerAddress.Hex(),
+		AmountPerRoom:  batch.AmountPerRoom.String(),
+		TotalRooms:     batch.TotalRooms,
+		PlayerSide:     batch.PlayerSide,
+		ServerSeed:     batch.ServerSeed,
+		ServerSeedHash: batch.ServerSeedHash,
+		ClientSeed:     batch.ClientSeed,
+		Nonce:          batch.Nonce,
+		RNGVersion:     batch.RNGVersion,
+		PriceModel:     batch.PriceModel,
+		Odds:           batch.Odds.String(),
+		Status:         batch.Status,
+		WonRooms:       batch.WonRooms,
+		PayoutTxHash:   batch.PayoutTxHash,
+		PayoutError:    batch.PayoutError,
+		CreatedAt:      batch.CreatedAt,
+		CompletedAt:    optionalTime(batch.CompletedAt),
+		Rooms:          make([]*db.CandleflipRoomRecord, len(batch.Rooms)),
+	}
+	if batch.PayoutAmount != nil {
+		record.PayoutAmount = batch.PayoutAmount.String()
+	}
+
+	for i, room := range batch.Rooms {
+		record.Rooms[i] = &db.CandleflipRoomRecord{
+			RoomNumber: room.RoomNumber,
+			Status:     room.Status,
+			FinalPrice: room.FinalPrice,
+			Winner:     room.Winner,
+			PlayerWon:  room.PlayerWon,
+			StartTime:  optionalTime(room.StartTime),
+			EndTime:    optionalTime(room.EndTime),
+		}
+	}
+	return record
+}
+
+// batchFromRecord rebuilds a batch from its database record
+func batchFromRecord(record *db.CandleflipBatchRecord) *CandleflipBatch {
+	amount, ok := new(big.Int).SetString(record.AmountPerRoom, 10)
+	if !ok {
+		amount = big.NewInt(0)
+	}
+
+	batch := &CandleflipBatch{
+		BatchID:        record.BatchID,
+		PlayerAddress:  common.HexToAddress(record.PlayerAddress),
+		AmountPerRoom:  amount,
+		TotalRooms:     record.TotalRooms,
+		PlayerSide:     record.PlayerSide,
+		Rooms:          make([]*Room, record.TotalRooms),
+		ServerSeed:     record.ServerSeed,
+		ServerSeedHash: record.ServerSeedHash,
+		ClientSeed:     record.ClientSeed,
+		Nonce:          record.Nonce,
+		RNGVersion:     record.RNGVersion,
+		PriceModel:     record.PriceModel,
+		Status:         record.Status,
+		WonRooms:       record.WonRooms,
+		PayoutTxHash:   record.PayoutTxHash,
+		PayoutError:    record.PayoutError,
+		CreatedAt:      record.CreatedAt,
+	}
+	if record.PayoutAmount != "" {
+		batch.PayoutAmount, _ = new(big.Int).SetString(record.PayoutAmount, 10)
+	}
+	batch.Odds, ok = new(big.Int).SetString(record.Odds, 10)
+	if !ok {
+		batch.Odds = odds.Legacy
+	}
+	if record.CompletedAt != nil {
+		batch.CompletedAt = *record.CompletedAt
+	}
+
+	for i := range batch.Rooms {
+		batch.Rooms[i] = &Room{RoomNumber: i + 1, Status: "waiting"}
+	}
+	for _, r := range record.Rooms {
+		if r.RoomNumber < 1 || r.RoomNumber > len(batch.Rooms) {
+			continue
+		}
+		room := &Room{
+			RoomNumber: r.RoomNumber,
+			Status:     r.Status,
+			FinalPrice: r.FinalPrice,
+			Winner:     r.Winner,
+			PlayerWon:  r.PlayerWon,
+		}
+		if r.StartTime != nil {
+			room.StartTime = *r.StartTime
+		}
+		if r.EndTime != nil {
+			room.EndTime = *r.EndTime
+		}
+		batch.Rooms[r.RoomNumber-1] = room
+	}
+	return batch
+}
+
+// optionalTime maps the zero time to NULL
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
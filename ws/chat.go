@@ -11,7 +11,7 @@ import (
 
 type ChatClient struct {
 	ID       string
-	Conn     *websocket.Conn
+	Conn     *wsConn
 	Username string
 	Send     chan []byte
 }
@@ -54,7 +54,7 @@ func handleChatMessages() {
 func HandleChatWS(w http.ResponseWriter, r *http.Request) {
 	log.Println("💬 Chat WebSocket connection attempt from:", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgradeConn(w, r, nil, "chat")
 	if err != nil {
 		log.Println("❌ Chat WebSocket upgrade failed:", err)
 		return
@@ -0,0 +1,28 @@
+package ws
+
+import (
+	"goLangServer/config"
+	"goLangServer/odds"
+	"goLangServer/wager"
+)
+
+var (
+	// serverConfig is the runtime configuration, replaced by Configure on startup
+	serverConfig = config.Default()
+
+	// wagers validates every bet against the configured limits
+	wagers = wager.New(serverConfig.Bets)
+
+	// oddsEngine prices candleflip batches against the house bankroll
+	oddsEngine = odds.New(serverConfig.Candleflip)
+)
+
+// Configure applies the runtime configuration. It must be called before
+// StartCrashGameLoop and before the server accepts connections.
+func Configure(cfg *config.Config) {
+	serverConfig = cfg
+	wagers = wager.New(cfg.Bets)
+	oddsEngine = odds.New(cfg.Candleflip)
+	upgrader.ReadBufferSize = cfg.WebSocket.ReadBufferSize
+	upgrader.WriteBufferSize = cfg.WebSocket.WriteBufferSize
+}
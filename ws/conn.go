@@ -0,0 +1,219 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsConn wraps every WebSocket connection the server accepts. It applies the
+// configured read limit and deadlines, pings the client so a half-open
+// connection times out instead of lingering, serialises writes and keeps
+// the connection's metrics.
+type wsConn struct {
+	*websocket.Conn
+	metrics *connMetrics
+
+	writeMu      sync.Mutex // Handlers and broadcasts may write to the same connection
+	readDeadline time.Duration
+	writeTimeout time.Duration
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// connMetrics is the running record of one connection
+type connMetrics struct {
+	id          uint64
+	endpoint    string
+	remoteAddr  string
+	connectedAt time.Time
+
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+	messagesIn  atomic.Int64
+	messagesOut atomic.Int64
+	dropped     atomic.Int64 // Messages not delivered: send buffer full or write failed
+}
+
+// ConnStats is a snapshot of a connection's metrics
+type ConnStats struct {
+	ID               uint64    `json:"id"`
+	Endpoint         string    `json:"endpoint"`
+	RemoteAddr       string    `json:"remoteAddr"`
+	ConnectedAt      time.Time `json:"connectedAt"`
+	ConnectedSeconds float64   `json:"connectedSeconds"`
+	BytesIn          int64     `json:"bytesIn"`
+	BytesOut         int64     `json:"bytesOut"`
+	MessagesIn       int64     `json:"messagesIn"`
+	MessagesOut      int64     `json:"messagesOut"`
+	Dropped          int64     `json:"dropped"`
+}
+
+var (
+	// Every open connection, on all endpoints
+	openConns      = make(map[*wsConn]bool)
+	openConnsMutex sync.Mutex
+	connIDCounter  atomic.Uint64
+)
+
+// upgradeConn upgrades an HTTP request on the given endpoint and starts
+// keeping the connection alive
+func upgradeConn(w http.ResponseWriter, r *http.Request, responseHeader http.Header, endpoint string) (*wsConn, error) {
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := serverConfig.WebSocket
+	c := &wsConn{
+		Conn: conn,
+		metrics: &connMetrics{
+			id:          connIDCounter.Add(1),
+			endpoint:    endpoint,
+			remoteAddr:  r.RemoteAddr,
+			connectedAt: time.Now(),
+		},
+		readDeadline: cfg.ReadDeadline.D(),
+		writeTimeout: cfg.WriteDeadline.D(),
+		done:         make(chan struct{}),
+	}
+
+	// A client must send something, if only a pong, within every read
+	// deadline. Reads fail once it is passed, which ends the read loop.
+	conn.SetReadLimit(cfg.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(c.readDeadline))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.readDeadline))
+	})
+
+	openConnsMutex.Lock()
+	openConns[c] = true
+	openConnsMutex.Unlock()
+
+	go c.keepAlive(cfg.PingInterval.D())
+	return c, nil
+}
+
+// keepAlive pings the client every interval until the connection is closed
+func (c *wsConn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+				// Unblocks the read loop, which cleans up
+				c.Conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// ReadMessage reads the next message and extends the read deadline
+func (c *wsConn) ReadMessage() (int, []byte, error) {
+	messageType, data, err := c.Conn.ReadMessage()
+	if err != nil {
+		return messageType, data, err
+	}
+	c.metrics.bytesIn.Add(int64(len(data)))
+	c.metrics.messagesIn.Add(1)
+	c.Conn.SetReadDeadline(time.Now().Add(c.readDeadline))
+	return messageType, data, nil
+}
+
+// ReadJSON reads the next message as JSON into v
+func (c *wsConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage writes a message within the write deadline. A failed write
+// counts the message as dropped.
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	if err := c.Conn.WriteMessage(messageType, data); err != nil {
+		c.metrics.dropped.Add(1)
+		return err
+	}
+	c.metrics.bytesOut.Add(int64(len(data)))
+	c.metrics.messagesOut.Add(1)
+	return nil
+}
+
+// WriteJSON writes v as a JSON text message
+func (c *wsConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// dropped counts a message that was never written
+func (c *wsConn) dropped() {
+	c.metrics.dropped.Add(1)
+}
+
+// Close closes the connection and logs its metrics. It is safe to call more
+// than once.
+func (c *wsConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		openConnsMutex.Lock()
+		delete(openConns, c)
+		openConnsMutex.Unlock()
+
+		s := c.stats()
+		log.Printf("📊 %s connection %d from %s closed after %.0fs: in %d msgs/%d B, out %d msgs/%d B, %d dropped",
+			s.Endpoint, s.ID, s.RemoteAddr, s.ConnectedSeconds, s.MessagesIn, s.BytesIn, s.MessagesOut, s.BytesOut, s.Dropped)
+	})
+	return err
+}
+
+func (c *wsConn) stats() ConnStats {
+	m := c.metrics
+	return ConnStats{
+		ID:               m.id,
+		Endpoint:         m.endpoint,
+		RemoteAddr:       m.remoteAddr,
+		ConnectedAt:      m.connectedAt,
+		ConnectedSeconds: time.Since(m.connectedAt).Seconds(),
+		BytesIn:          m.bytesIn.Load(),
+		BytesOut:         m.bytesOut.Load(),
+		MessagesIn:       m.messagesIn.Load(),
+		MessagesOut:      m.messagesOut.Load(),
+		Dropped:          m.dropped.Load(),
+	}
+}
+
+// ConnectionStats returns the metrics of every open connection, oldest first
+func ConnectionStats() []ConnStats {
+	openConnsMutex.Lock()
+	stats := make([]ConnStats, 0, len(openConns))
+	for c := range openConns {
+		stats = append(stats, c.stats())
+	}
+	openConnsMutex.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
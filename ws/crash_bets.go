@@ -0,0 +1,496 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/contract"
+	"goLangServer/db"
+	"goLangServer/wager"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PlaceCrashBetRequest registers a crash bet for the current round
+type PlaceCrashBetRequest struct {
+	Address     string  `json:"address"`
+	BetAmount   string  `json:"betAmount"`             // Wei as string
+	TxHash      string  `json:"txHash"`                // The player's bet() deposit, usable for one bet
+	AutoCashout float64 `json:"autoCashout,omitempty"` // Optional target multiplier
+	GameID      string  `json:"gameId,omitempty"`      // Optional, rejects bets for any other round
+}
+
+// CrashCashOutRequest cashes out the player's bet in the current round. It
+// must be signed by the player as an EIP-712 CashOut over gameId and
+// minMultiplier with their next relayer nonce, like a gasless cashout.
+type CrashCashOutRequest struct {
+	Address       string  `json:"address"`
+	GameID        string  `json:"gameId"`        // Rejects cashouts for any other round
+	MinMultiplier float64 `json:"minMultiplier"` // Rejects cashouts below this multiplier
+	Nonce         uint64  `json:"nonce"`
+	Signature     string  `json:"signature"`
+
+	relayed bool // Payout is sent by the gasless relayer instead of the payout queue
+}
+
+var (
+	// crashBetsMutex serialises bet placement and settlement so a player cannot
+	// place or cash out the same bet twice concurrently
+	crashBetsMutex sync.Mutex
+
+	// Auto-cashout targets of open bets in the current round, checked every tick.
+	// Guarded by crashBetsMutex.
+	autoCashoutGameID  string
+	autoCashoutTargets = make(map[string]float64)
+
+	// errDepositClaimed rejects a bet whose deposit already backs another bet
+	errDepositClaimed = errors.New("deposit already used for a bet")
+)
+
+// placeDepositedCrashBet places a bet the player paid for with their own
+// bet() transaction, which is verified on-chain here and claimed by
+// placeCrashBet so each deposit backs a single bet
+func placeDepositedCrashBet(ctx context.Context, req PlaceCrashBetRequest) (*db.CrashBetData, error) {
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := wagers.AmountWei("betAmount", req.BetAmount)
+	if err != nil {
+		return nil, err
+	}
+	if err := wagers.AutoCashout("autoCashout", req.AutoCashout); err != nil {
+		return nil, err
+	}
+	if len(common.FromHex(req.TxHash)) != common.HashLength {
+		return nil, fmt.Errorf("invalid bet transaction hash")
+	}
+	depositHash := common.HexToHash(req.TxHash)
+
+	rel, err := getRelayer()
+	if err != nil {
+		return nil, err
+	}
+	if err := rel.VerifyDeposit(ctx, player, amount, depositHash); err != nil {
+		log.Printf("❌ Crash bet rejected for %s: %v", player.Hex(), err)
+		return nil, err
+	}
+
+	return placeCrashBet(ctx, req)
+}
+
+// placeCrashBet validates the round status and stores the bet at the current
+// multiplier, claiming its deposit (txHash) only once every check has passed.
+// Instances that do not run crash rounds forward it to the leader.
+func placeCrashBet(ctx context.Context, req PlaceCrashBetRequest) (*db.CrashBetData, error) {
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := wagers.AmountWei("betAmount", req.BetAmount)
+	if err != nil {
+		return nil, err
+	}
+	if err := wagers.AutoCashout("autoCashout", req.AutoCashout); err != nil {
+		return nil, err
+	}
+	if len(common.FromHex(req.TxHash)) != common.HashLength {
+		return nil, fmt.Errorf("invalid bet transaction hash")
+	}
+	playerAddr := player.Hex()
+	depositHash := common.HexToHash(req.TxHash).Hex()
+
+	if !isCrashLeader() {
+		reply, err := forwardCrashCommand(ctx, &crashCommand{PlaceBet: &req})
+		if err != nil {
+			return nil, err
+		}
+		return reply.Bet, nil
+	}
+
+	crashBetsMutex.Lock()
+	defer crashBetsMutex.Unlock()
+
+	// Hold the game lock so the round cannot end while the bet is stored
+	currentCrashGameMutex.RLock()
+	defer currentCrashGameMutex.RUnlock()
+
+	state := currentCrashGame
+	if state == nil || (state.Status != "countdown" && state.Status != "running") {
+		return nil, fmt.Errorf("no round is accepting bets")
+	}
+	gameID := state.ContractGameID.String()
+	if req.GameID != "" && req.GameID != gameID {
+		return nil, fmt.Errorf("game %s is not the current round", req.GameID)
+	}
+	if req.AutoCashout != 0 && req.AutoCashout <= state.CurrentMultiplier {
+		return nil, fmt.Errorf("auto-cashout %.2fx is not above the current multiplier %.2fx", req.AutoCashout, state.CurrentMultiplier)
+	}
+
+	existing, err := db.GetCrashBet(ctx, gameID, playerAddr)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("player already has a bet in this round")
+	}
+
+	// Every check has passed, so a claimed deposit always ends up backing
+	// this bet. It is released again if the bet cannot be stored.
+	claimed, err := db.ClaimRelayerDeposit(ctx, depositHash)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errDepositClaimed
+	}
+
+	bet := &db.CrashBetData{
+		PlayerAddress:   playerAddr,
+		GameID:          gameID,
+		BetAmount:       amount.String(),
+		EntryMultiplier: state.CurrentMultiplier,
+		EntryTick:       state.CurrentTick,
+		Timestamp:       time.Now(),
+		TxHash:          depositHash,
+		AutoCashout:     req.AutoCashout,
+	}
+	if state.Status == "countdown" {
+		bet.EntryTick = -1
+	}
+	if err := db.StoreCrashBet(ctx, gameID, playerAddr, bet); err != nil {
+		if releaseErr := db.ReleaseRelayerDeposit(ctx, depositHash); releaseErr != nil {
+			log.Printf("❌ Deposit %s of %s stays claimed without a bet: %v", depositHash, playerAddr, releaseErr)
+		}
+		return nil, err
+	}
+
+	if bet.AutoCashout > 0 {
+		if autoCashoutGameID != gameID {
+			autoCashoutGameID = gameID
+			autoCashoutTargets = make(map[string]float64)
+		}
+		autoCashoutTargets[playerAddr] = bet.AutoCashout
+	}
+
+	AddActiveBettor(playerAddr, config.WeiToMNT(amount), bet.EntryMultiplier)
+
+	publishCrash(map[string]interface{}{
+		"type": "bet_placed",
+		"data": bet,
+	})
+
+	return bet, nil
+}
+
+// authorizeCrashCashOut checks the player signed the cashout and consumes
+// the signature's nonce, so nobody can cash out another player's bet
+func authorizeCrashCashOut(ctx context.Context, req CrashCashOutRequest) error {
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		return err
+	}
+	gameID, ok := new(big.Int).SetString(req.GameID, 10)
+	if !ok {
+		return fmt.Errorf("invalid game ID %q", req.GameID)
+	}
+	if err := wagers.Multiplier("minMultiplier", req.MinMultiplier); err != nil {
+		return err
+	}
+	signature, err := decodeSignature(req.Signature)
+	if err != nil {
+		return err
+	}
+
+	rel, err := getRelayer()
+	if err != nil {
+		return err
+	}
+	return rel.AuthorizeCashOut(ctx, &contract.CashOutRequest{
+		PlayerAddress: player,
+		GameID:        gameID,
+		Multiplier:    signedMultiplier(req.MinMultiplier),
+		Nonce:         req.Nonce,
+		Signature:     signature,
+	})
+}
+
+// signedMultiplier converts a multiplier to the 18-decimal value the player
+// signed, from its shortest decimal form so 1.1 is 1100000000000000000
+func signedMultiplier(multiplier float64) *big.Int {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(multiplier, 'f', -1, 64))
+	r.Mul(r, new(big.Rat).SetInt(big.NewInt(1e18)))
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// cashOutCrashBet settles the player's bet at the server-authoritative current
+// multiplier. Instances that do not run crash rounds forward it to the leader.
+// Callers must have authorized the request.
+func cashOutCrashBet(ctx context.Context, req CrashCashOutRequest) (*db.CrashCashedOutData, error) {
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		return nil, err
+	}
+	playerAddr := player.Hex()
+
+	if !isCrashLeader() {
+		reply, err := forwardCrashCommand(ctx, &crashCommand{CashOut: &req, Relayed: req.relayed})
+		if err != nil {
+			return nil, err
+		}
+		return reply.CashedOut, nil
+	}
+
+	crashBetsMutex.Lock()
+	defer crashBetsMutex.Unlock()
+
+	// Hold the game lock so the price cannot move or rug during settlement
+	currentCrashGameMutex.RLock()
+	defer currentCrashGameMutex.RUnlock()
+
+	state := currentCrashGame
+	if state == nil || state.Status != "running" {
+		return nil, fmt.Errorf("round is not running")
+	}
+	if req.GameID != "" && req.GameID != state.ContractGameID.String() {
+		return nil, fmt.Errorf("game %s is not the current round", req.GameID)
+	}
+	if state.CurrentMultiplier < req.MinMultiplier {
+		return nil, fmt.Errorf("current multiplier %.2fx is below requested %.2fx", state.CurrentMultiplier, req.MinMultiplier)
+	}
+
+	return settleCrashBet(ctx, state, playerAddr, state.CurrentMultiplier, !req.relayed)
+}
+
+// settleCrashBet records a cashout at the given multiplier and broadcasts it,
+// queueing the payout if queuePayout is set.
+// Callers must hold crashBetsMutex and currentCrashGameMutex.
+func settleCrashBet(ctx context.Context, state *CrashGameState, playerAddr string, multiplier float64, queuePayout bool) (*db.CrashCashedOutData, error) {
+	gameID := state.ContractGameID.String()
+
+	bet, err := db.GetCrashBet(ctx, gameID, playerAddr)
+	if err != nil {
+		return nil, err
+	}
+	if bet == nil {
+		return nil, fmt.Errorf("no active bet in this round")
+	}
+
+	cashedOut, err := newCashedOut(bet, multiplier)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.StoreCashedOut(ctx, gameID, playerAddr, cashedOut); err != nil {
+		return nil, err
+	}
+	if err := db.DeleteCrashBet(ctx, gameID, playerAddr); err != nil {
+		log.Printf("⚠️ Failed to delete settled bet for %s: %v", playerAddr, err)
+	}
+
+	delete(autoCashoutTargets, playerAddr)
+	RemoveActiveBettor(playerAddr)
+
+	if queuePayout {
+		go queueCrashPayout(cashedOut)
+	}
+
+	publishCrash(map[string]interface{}{
+		"type": "cashed_out",
+		"data": cashedOut,
+	})
+
+	log.Printf("💰 %s cashed out game %s at %.2fx (entry %.2fx) - Payout: %s wei",
+		playerAddr, gameID, multiplier, bet.EntryMultiplier, cashedOut.Payout)
+	return cashedOut, nil
+}
+
+// newCashedOut computes the settlement of a bet cashed out at multiplier:
+// payout = betAmount × cashoutMultiplier / entryMultiplier
+func newCashedOut(bet *db.CrashBetData, multiplier float64) (*db.CrashCashedOutData, error) {
+	amount, ok := new(big.Int).SetString(bet.BetAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("stored bet has invalid amount %q", bet.BetAmount)
+	}
+
+	payout := new(big.Int).Mul(amount, config.MultiplierToWei(multiplier))
+	payout.Div(payout, config.MultiplierToWei(bet.EntryMultiplier))
+
+	return &db.CrashCashedOutData{
+		PlayerAddress:     bet.PlayerAddress,
+		GameID:            bet.GameID,
+		BetAmount:         bet.BetAmount,
+		EntryMultiplier:   bet.EntryMultiplier,
+		CashoutMultiplier: multiplier,
+		Payout:            payout.String(),
+		CashoutTimestamp:  time.Now(),
+	}, nil
+}
+
+// processAutoCashouts settles every bet whose auto-cashout target the price
+// has reached. Called by the game loop after each tick, before the next one,
+// so players are paid at exactly their target.
+func processAutoCashouts(ctx context.Context) {
+	crashBetsMutex.Lock()
+	defer crashBetsMutex.Unlock()
+
+	currentCrashGameMutex.RLock()
+	defer currentCrashGameMutex.RUnlock()
+
+	state := currentCrashGame
+	if state == nil || state.Status != "running" || autoCashoutGameID != state.ContractGameID.String() {
+		return
+	}
+
+	for playerAddr, target := range autoCashoutTargets {
+		if state.CurrentMultiplier < target {
+			continue
+		}
+		if _, err := settleCrashBet(ctx, state, playerAddr, target, true); err != nil {
+			log.Printf("⚠️ Auto-cashout failed for %s at %.2fx: %v", playerAddr, target, err)
+			delete(autoCashoutTargets, playerAddr)
+		}
+	}
+}
+
+/* =========================
+   WEBSOCKET HANDLERS
+========================= */
+
+// handlePlaceBetMessage handles a "place_bet" message on /ws, paid by the
+// player's bet() deposit in txHash
+func handlePlaceBetMessage(c *ClientConnection, msgData map[string]interface{}) {
+	req := PlaceCrashBetRequest{}
+	req.Address, _ = msgData["address"].(string)
+	req.BetAmount, _ = msgData["betAmount"].(string)
+	req.TxHash, _ = msgData["txHash"].(string)
+	req.AutoCashout, _ = msgData["autoCashout"].(float64)
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.Relayer.TxTimeout.D())
+	defer cancel()
+
+	bet, err := placeDepositedCrashBet(ctx, req)
+	if err != nil {
+		c.sendMessage(errorReply("bet_error", err))
+		return
+	}
+
+	c.sendMessage(map[string]interface{}{
+		"type": "bet_accepted",
+		"data": bet,
+	})
+}
+
+// handleCashOutMessage handles a signed "cash_out" message on /ws
+func handleCashOutMessage(c *ClientConnection, msgData map[string]interface{}) {
+	req := CrashCashOutRequest{}
+	req.Address, _ = msgData["address"].(string)
+	req.GameID, _ = msgData["gameId"].(string)
+	req.MinMultiplier, _ = msgData["minMultiplier"].(float64)
+	req.Signature, _ = msgData["signature"].(string)
+	if nonce, ok := msgData["nonce"].(float64); ok && nonce >= 0 {
+		req.Nonce = uint64(nonce)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := authorizeCrashCashOut(ctx, req); err != nil {
+		c.sendMessage(errorReply("cashout_error", err))
+		return
+	}
+
+	cashedOut, err := cashOutCrashBet(ctx, req)
+	if err != nil {
+		c.sendMessage(errorReply("cashout_error", err))
+		return
+	}
+
+	c.sendMessage(map[string]interface{}{
+		"type": "cashout_accepted",
+		"data": cashedOut,
+	})
+}
+
+/* =========================
+   HTTP HANDLERS
+========================= */
+
+// HandleCrashRegister registers a crash bet for the current round, paid by
+// the player's bet() deposit in txHash
+// POST /api/crash/register
+func HandleCrashRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PlaceCrashBetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), serverConfig.Relayer.TxTimeout.D())
+	defer cancel()
+
+	bet, err := placeDepositedCrashBet(ctx, req)
+	if errors.Is(err, errDepositClaimed) {
+		sendWagerError(w, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"bet":     bet,
+	})
+}
+
+// HandleCrashCashout cashes out the player's bet at the current multiplier,
+// authorized by the player's signature
+// POST /api/crash/cashout
+func HandleCrashCashout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CrashCashOutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := authorizeCrashCashOut(r.Context(), req); err != nil {
+		status := http.StatusUnauthorized
+		var werr *wager.Error
+		if errors.As(err, &werr) {
+			status = http.StatusBadRequest
+		}
+		sendWagerError(w, err, status)
+		return
+	}
+
+	cashedOut, err := cashOutCrashBet(r.Context(), req)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success":   true,
+		"cashedOut": cashedOut,
+	})
+}
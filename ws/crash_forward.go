@@ -0,0 +1,142 @@
+package ws
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/db"
+)
+
+// Bets and cashouts settle against the live round, which only the crash
+// leader runs. Other instances forward them to the leader through a Redis
+// queue and wait for its reply.
+
+// crashCommandTimeout bounds how long a forwarded command waits for the
+// leader, when the caller's context allows longer
+const crashCommandTimeout = 5 * time.Second
+
+// crashCommand is a bet or cashout forwarded to the crash leader
+type crashCommand struct {
+	ReplyKey string                `json:"replyKey"`
+	Deadline time.Time             `json:"deadline"` // The sender has given up after this
+	PlaceBet *PlaceCrashBetRequest `json:"placeBet,omitempty"`
+	CashOut  *CrashCashOutRequest  `json:"cashOut,omitempty"`
+	Relayed  bool                  `json:"relayed,omitempty"` // CashOut.relayed
+}
+
+// crashCommandReply is the leader's answer to a crashCommand
+type crashCommandReply struct {
+	Bet       *db.CrashBetData       `json:"bet,omitempty"`
+	CashedOut *db.CrashCashedOutData `json:"cashedOut,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// forwardCrashCommand sends a command to the crash leader and waits for its reply
+func forwardCrashCommand(ctx context.Context, cmd *crashCommand) (*crashCommandReply, error) {
+	id := make([]byte, 8)
+	rand.Read(id)
+	cmd.ReplyKey = fmt.Sprintf(config.RedisCrashReplyKey, hex.EncodeToString(id))
+
+	cmd.Deadline = time.Now().Add(crashCommandTimeout)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(cmd.Deadline) {
+		cmd.Deadline = deadline
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal crash command: %w", err)
+	}
+	if err := db.PushQueue(ctx, config.RedisCrashCommandsKey, data, config.CrashCommandTTL); err != nil {
+		return nil, err
+	}
+
+	data, err = db.PopQueue(ctx, cmd.ReplyKey, time.Until(cmd.Deadline))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("crash leader did not answer")
+	}
+
+	var reply crashCommandReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal crash command reply: %w", err)
+	}
+	if reply.Error == errDepositClaimed.Error() {
+		return nil, errDepositClaimed
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return &reply, nil
+}
+
+// runCrashCommands takes forwarded commands off the queue while this
+// instance is the crash leader
+func runCrashCommands() {
+	for {
+		if !isCrashLeader() {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		data, err := db.PopQueue(context.Background(), config.RedisCrashCommandsKey, time.Second)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if data != nil {
+			go handleCrashCommand(data)
+		}
+	}
+}
+
+// handleCrashCommand runs a forwarded command and replies to its sender
+func handleCrashCommand(data []byte) {
+	var cmd crashCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		log.Printf("⚠️ Dropping crash command: %v", err)
+		return
+	}
+	if time.Now().After(cmd.Deadline) {
+		// The sender has already reported a failure
+		log.Printf("⚠️ Dropping expired crash command %s", cmd.ReplyKey)
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), cmd.Deadline)
+	defer cancel()
+
+	var reply crashCommandReply
+	var err error
+	switch {
+	case cmd.PlaceBet != nil:
+		reply.Bet, err = placeCrashBet(ctx, *cmd.PlaceBet)
+	case cmd.CashOut != nil:
+		req := *cmd.CashOut
+		req.relayed = cmd.Relayed
+		reply.CashedOut, err = cashOutCrashBet(ctx, req)
+	default:
+		err = fmt.Errorf("unknown crash command")
+	}
+	if err != nil {
+		reply.Error = err.Error()
+	}
+
+	out, err := json.Marshal(reply)
+	if err != nil {
+		log.Printf("❌ Failed to marshal crash command reply: %v", err)
+		return
+	}
+	if err := db.PushQueue(ctx, cmd.ReplyKey, out, config.CrashCommandTTL); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/crypto"
+	"goLangServer/db"
+	"goLangServer/game"
+)
+
+// trackCrashRound records the live round in Redis until it is settled
+func trackCrashRound(round *db.CrashRoundData) {
+	round.UpdatedAt = time.Now()
+	if db.RedisClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := db.StoreCrashRound(ctx, round); err != nil {
+		log.Printf("⚠️ Failed to track crash round %s: %v", round.GameID, err)
+	}
+}
+
+// RecoverCrashRounds settles crash rounds left unfinished by a restart or by
+// a leader that lost the crash lease. Rounds whose tracking record was
+// updated within the lease TTL are skipped, since their instance may still
+// be running them.
+// Each round's outcome is replayed from its committed seed:
+//   - rounds that crashed only need their cashouts queued and history stored
+//   - bets in rounds that never left the countdown are refunded
+//   - bets in running rounds are settled at their auto-cashout target if the
+//     replay reached it after the bet was placed, and refunded otherwise,
+//     since their players lost the chance to cash out
+//
+// Recovered rounds are written to crash_history with recovered set.
+func RecoverCrashRounds() {
+	if db.RedisClient == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rounds, err := db.GetOpenCrashRounds(ctx)
+	if err != nil {
+		log.Printf("⚠️ Failed to load unfinished crash rounds: %v", err)
+		return
+	}
+
+	for _, round := range rounds {
+		if time.Since(round.UpdatedAt) < config.CrashLeaseTTL {
+			continue // May still be running, here or on the previous leader
+		}
+
+		if err := recoverCrashRound(ctx, round); err != nil {
+			log.Printf("⚠️ Failed to recover crash round %s, will retry before a later round: %v", round.GameID, err)
+			continue
+		}
+		if err := db.CloseCrashRound(ctx, round.GameID); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+}
+
+// recoverCrashRound settles one unfinished round
+func recoverCrashRound(ctx context.Context, round *db.CrashRoundData) error {
+	if db.PostgresPool == nil {
+		return fmt.Errorf("payout queue unavailable")
+	}
+
+	roundSeed := crypto.CrashRoundSeed(round.ServerSeed, round.ClientSeed, round.ChainRound)
+	prices, result := game.ReplayCrashGame(round.RNGVersion, round.Distribution, roundSeed, round.RoundID)
+
+	// Cashouts made before the restart may not have reached the payout queue
+	cashedOutPlayers, err := db.GetCashedOutPlayers(ctx, round.GameID)
+	if err != nil {
+		return err
+	}
+	for _, player := range cashedOutPlayers {
+		cashedOut, err := db.GetCashedOut(ctx, round.GameID, player)
+		if err != nil {
+			return err
+		}
+		if cashedOut == nil {
+			continue
+		}
+		if _, err := db.EnqueuePayout(ctx, PayoutKindCrash, round.GameID, player, cashedOut.Payout); err != nil {
+			return err
+		}
+	}
+
+	players, err := db.GetActivePlayers(ctx, round.GameID)
+	if err != nil {
+		return err
+	}
+
+	settled, refunded := 0, 0
+	for _, player := range players {
+		bet, err := db.GetCrashBet(ctx, round.GameID, player)
+		if err != nil {
+			return err
+		}
+		if bet == nil {
+			continue
+		}
+
+		switch round.Status {
+		case "crashed":
+			// Bets still open when the round crashed were lost
+
+		case "running":
+			if target, ok := replayAutoCashout(bet, prices); ok {
+				cashedOut, err := newCashedOut(bet, target)
+				if err != nil {
+					return err
+				}
+				if err := db.StoreCashedOut(ctx, round.GameID, player, cashedOut); err != nil {
+					return err
+				}
+				if _, err := db.EnqueuePayout(ctx, PayoutKindCrash, round.GameID, player, cashedOut.Payout); err != nil {
+					return err
+				}
+				settled++
+				break
+			}
+			fallthrough
+
+		default:
+			if _, err := db.EnqueuePayout(ctx, PayoutKindCrashRefund, round.GameID, player, bet.BetAmount); err != nil {
+				return err
+			}
+			refunded++
+		}
+
+		if err := db.DeleteCrashBet(ctx, round.GameID, player); err != nil {
+			return err
+		}
+	}
+
+	historyRecord := &db.CrashHistoryRecord{
+		GameID:             round.RoundID,
+		ServerSeed:         round.ServerSeed,
+		ServerSeedHash:     round.ServerSeedHash,
+		ClientSeed:         round.ClientSeed,
+		Peak:               result.PeakMultiplier,
+		CandlestickHistory: game.BuildCandles(round.CandleLayout, prices, result.Rugged),
+		Rugged:             result.Rugged,
+		RNGVersion:         result.RNGVersion,
+		Distribution:       round.Distribution,
+		CandleLayout:       round.CandleLayout,
+		ChainRound:         round.ChainRound,
+		TerminatingHash:    round.TerminatingHash,
+		Recovered:          true,
+		CreatedAt:          time.Now(),
+	}
+	if err := db.StoreCrashHistory(ctx, historyRecord); err != nil {
+		return err
+	}
+
+	log.Printf("♻️ Recovered crash round %s (%s) - %d settled, %d refunded, %d cashouts requeued",
+		round.GameID, round.Status, settled, refunded, len(cashedOutPlayers))
+	return nil
+}
+
+// replayAutoCashout returns the bet's auto-cashout target if the replayed
+// price reached it on a tick after the bet was placed
+func replayAutoCashout(bet *db.CrashBetData, prices []float64) (float64, bool) {
+	if bet.AutoCashout <= 0 {
+		return 0, false
+	}
+	for i := bet.EntryTick + 1; i < len(prices); i++ {
+		if i >= 0 && prices[i] >= bet.AutoCashout {
+			return bet.AutoCashout, true
+		}
+	}
+	return 0, false
+}
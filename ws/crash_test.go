@@ -0,0 +1,312 @@
+package ws
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"goLangServer/contract"
+	"goLangServer/db"
+	"goLangServer/game"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// startTestRound makes a running round of the given seed the live round, as
+// runCrashGameLoop does, and returns its simulator
+func startTestRound(t *testing.T, seed string) (*game.CrashSimulator, string) {
+	t.Helper()
+	contractGameID := big.NewInt(1700000000)
+	currentCrashGameMutex.Lock()
+	currentCrashGame = &CrashGameState{
+		GameID:            "20260101-000000.000",
+		ServerSeed:        seed,
+		Status:            "running",
+		ContractGameID:    contractGameID,
+		CurrentMultiplier: game.StartingPrice,
+	}
+	currentCrashGameMutex.Unlock()
+	t.Cleanup(func() {
+		currentCrashGameMutex.Lock()
+		currentCrashGame = nil
+		currentCrashGameMutex.Unlock()
+	})
+	return game.NewCrashSimulator(game.CurrentRNGVersion, serverConfig.Crash.Distribution, seed, "20260101-000000.000"), contractGameID.String()
+}
+
+// TestAutoCashoutSettlesInLiveLoop drives the live round past a bet's
+// auto-cashout target and checks the bet is settled at the target
+func TestAutoCashoutSettlesInLiveLoop(t *testing.T) {
+	useFakeRedis(t)
+
+	// Find a round whose price goes well above the starting price
+	var seed string
+	var peak float64
+	for i := 0; peak < 1.5; i++ {
+		seed = fmt.Sprintf("auto-cashout-%d", i)
+		_, result := game.ReplayCrashGame(game.CurrentRNGVersion, serverConfig.Crash.Distribution, seed, "20260101-000000.000")
+		peak = result.PeakMultiplier
+	}
+	target := 1.25
+
+	sim, gameID := startTestRound(t, seed)
+	player := common.HexToAddress("0xbeef").Hex()
+
+	ctx := context.Background()
+	if _, err := placeCrashBet(ctx, PlaceCrashBetRequest{
+		Address:     player,
+		BetAmount:   "1000000000000000000",
+		TxHash:      common.HexToHash("0x01").Hex(),
+		AutoCashout: target,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		tick, ok := advanceCrashRound(sim)
+		if !ok {
+			t.Fatal("round crashed before the bet was settled")
+		}
+		if tick.Price < target {
+			continue
+		}
+
+		// Settled on the tick that reached the target, before the next
+		cashedOut, err := db.GetCashedOut(ctx, gameID, player)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cashedOut == nil {
+			t.Fatalf("bet not settled at tick %d (price %.4fx, target %.2fx)", tick.Index, tick.Price, target)
+		}
+		if cashedOut.CashoutMultiplier != target || cashedOut.Payout != "1250000000000000000" {
+			t.Errorf("cashed out at %.4fx for %s wei, want %.2fx for 1.25 MNT", cashedOut.CashoutMultiplier, cashedOut.Payout, target)
+		}
+		if bet, _ := db.GetCrashBet(ctx, gameID, player); bet != nil {
+			t.Error("settled bet is still open")
+		}
+		return
+	}
+}
+
+// TestForwardedBetReachesLeader forwards a bet through the command queue and
+// checks the leader places it and replies to the sender
+func TestForwardedBetReachesLeader(t *testing.T) {
+	fake := useFakeRedis(t)
+	_, gameID := startTestRound(t, "forwarded-bet")
+	player := common.HexToAddress("0xf00d").Hex()
+
+	type result struct {
+		reply *crashCommandReply
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := forwardCrashCommand(context.Background(), &crashCommand{PlaceBet: &PlaceCrashBetRequest{
+			Address:   player,
+			BetAmount: "500000000000000000",
+			TxHash:    common.HexToHash("0x02").Hex(),
+			GameID:    gameID,
+		}})
+		done <- result{reply, err}
+	}()
+
+	// Act as the leader: take the command off the queue and run it
+	var command []byte
+	for command == nil {
+		time.Sleep(5 * time.Millisecond)
+		fake.mu.Lock()
+		if queue := fake.lists["crash:commands"]; len(queue) > 0 {
+			command = []byte(queue[0])
+			fake.lists["crash:commands"] = queue[1:]
+		}
+		fake.mu.Unlock()
+	}
+	handleCrashCommand(command)
+
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.reply.Bet == nil || r.reply.Bet.PlayerAddress != player || r.reply.Bet.GameID != gameID {
+		t.Fatalf("reply bet = %+v", r.reply.Bet)
+	}
+	if bet, _ := db.GetCrashBet(context.Background(), gameID, player); bet == nil {
+		t.Error("forwarded bet was not stored")
+	}
+}
+
+// TestDirectBetRequiresDeposit checks a bet without a deposit transaction is
+// rejected before it reaches the round
+func TestDirectBetRequiresDeposit(t *testing.T) {
+	useFakeRedis(t)
+	_, gameID := startTestRound(t, "direct-bet")
+	player := common.HexToAddress("0xcafe").Hex()
+
+	ctx := context.Background()
+	for _, txHash := range []string{"", "0x01"} {
+		if _, err := placeDepositedCrashBet(ctx, PlaceCrashBetRequest{
+			Address:   player,
+			BetAmount: "1000000000000000000",
+			TxHash:    txHash,
+		}); err == nil {
+			t.Errorf("bet with deposit %q accepted", txHash)
+		}
+	}
+	if bet, _ := db.GetCrashBet(ctx, gameID, player); bet != nil {
+		t.Error("unverified bet was stored")
+	}
+}
+
+// TestRejectedBetLeavesDepositUnclaimed checks the deposit is only claimed by
+// a bet the round accepts, and only once
+func TestRejectedBetLeavesDepositUnclaimed(t *testing.T) {
+	useFakeRedis(t)
+	_, gameID := startTestRound(t, "deposit-claim")
+	player := common.HexToAddress("0xd00d").Hex()
+	deposit := common.HexToHash("0x03").Hex()
+
+	ctx := context.Background()
+	req := PlaceCrashBetRequest{
+		Address:   player,
+		BetAmount: "1000000000000000000",
+		TxHash:    deposit,
+		GameID:    "1",
+	}
+	if _, err := placeCrashBet(ctx, req); err == nil {
+		t.Fatal("bet for another round accepted")
+	}
+
+	req.GameID = gameID
+	if _, err := placeCrashBet(ctx, req); err != nil {
+		t.Fatalf("deposit of a rejected bet was claimed: %v", err)
+	}
+
+	other := req
+	other.Address = common.HexToAddress("0xd00e").Hex()
+	if _, err := placeCrashBet(ctx, other); !errors.Is(err, errDepositClaimed) {
+		t.Errorf("second bet on the same deposit: error = %v", err)
+	}
+}
+
+// useTestRelayer makes a relayer with an in-memory nonce store the gasless
+// relayer for the test
+func useTestRelayer(t *testing.T) *contract.Relayer {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	rel := contract.NewRelayer(nil, common.HexToAddress("0x43a01A18a2C947179595A7b17bDCc3d88ecF04F5"), abi.ABI{}, key, big.NewInt(5000), contract.NewMemoryNonceStore())
+
+	relayerMutex.Lock()
+	previous := relayer
+	relayer = rel
+	relayerMutex.Unlock()
+	t.Cleanup(func() {
+		relayerMutex.Lock()
+		relayer = previous
+		relayerMutex.Unlock()
+	})
+	return rel
+}
+
+// signCashOut signs req as a wallet's eth_signTypedData_v4 would
+func signCashOut(t *testing.T, rel *contract.Relayer, key *ecdsa.PrivateKey, req CrashCashOutRequest) CrashCashOutRequest {
+	t.Helper()
+	gameID, _ := new(big.Int).SetString(req.GameID, 10)
+	hash, _, err := apitypes.TypedDataAndHash(rel.CashOutTypedData(&contract.CashOutRequest{
+		PlayerAddress: common.HexToAddress(req.Address),
+		GameID:        gameID,
+		Multiplier:    signedMultiplier(req.MinMultiplier),
+		Nonce:         req.Nonce,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	req.Signature = hexutil.Encode(sig)
+	return req
+}
+
+// TestCashOutRequiresPlayerSignature checks only the player's signature over
+// the round and minimum multiplier cashes out their bet
+func TestCashOutRequiresPlayerSignature(t *testing.T) {
+	useFakeRedis(t)
+	rel := useTestRelayer(t)
+	_, gameID := startTestRound(t, "signed-cashout")
+
+	playerKey, _ := crypto.GenerateKey()
+	attackerKey, _ := crypto.GenerateKey()
+	player := crypto.PubkeyToAddress(playerKey.PublicKey).Hex()
+
+	ctx := context.Background()
+	if _, err := placeCrashBet(ctx, PlaceCrashBetRequest{
+		Address:   player,
+		BetAmount: "1000000000000000000",
+		TxHash:    common.HexToHash("0x04").Hex(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := CrashCashOutRequest{Address: player, GameID: gameID, MinMultiplier: 1.1}
+	if err := authorizeCrashCashOut(ctx, req); err == nil {
+		t.Error("unsigned cashout authorized")
+	}
+	if err := authorizeCrashCashOut(ctx, signCashOut(t, rel, attackerKey, req)); err == nil {
+		t.Error("cashout signed by another key authorized")
+	}
+
+	// A signature over one multiplier does not authorize another
+	tampered := signCashOut(t, rel, playerKey, req)
+	tampered.MinMultiplier = 1.0
+	if err := authorizeCrashCashOut(ctx, tampered); err == nil {
+		t.Error("tampered cashout authorized")
+	}
+
+	if err := authorizeCrashCashOut(ctx, signCashOut(t, rel, playerKey, req)); err != nil {
+		t.Fatalf("signed cashout rejected: %v", err)
+	}
+	if bet, _ := db.GetCrashBet(ctx, gameID, player); bet == nil {
+		t.Error("authorization alone settled the bet")
+	}
+}
+
+func TestSignedMultiplier(t *testing.T) {
+	for multiplier, want := range map[float64]string{
+		1.1:  "1100000000000000000",
+		2.5:  "2500000000000000000",
+		1.23: "1230000000000000000",
+	} {
+		if got := signedMultiplier(multiplier).String(); got != want {
+			t.Errorf("signedMultiplier(%v) = %s, want %s", multiplier, got, want)
+		}
+	}
+}
+
+// TestCrashRoundsNeedCommittedSeedChain checks no round seed is handed out
+// from a chain that was not stored, so it cannot change on a restart
+func TestCrashRoundsNeedCommittedSeedChain(t *testing.T) {
+	t.Cleanup(func() {
+		seedChainMutex.Lock()
+		seedChain, seedChainRecord = nil, nil
+		seedChainMutex.Unlock()
+	})
+
+	InitSeedChain()
+	if _, err := nextCrashSeed(); err == nil {
+		t.Fatal("expected a seed chain without PostgreSQL to be refused")
+	}
+	if _, ok := GetSeedChainInfo(); ok {
+		t.Error("uncommitted seed chain was published")
+	}
+}
@@ -3,9 +3,8 @@ package ws
 import (
 	"context"
 	"log"
-	"math"
-	"net/http"
 	"math/big"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,12 +33,6 @@ type ActiveBettor struct {
 	BetTime         time.Time `json:"betTime"`
 }
 
-const (
-	MaxGameHistory           = 10
-	InitialGroupDurationMs = 1000 // 1 second candles
-	MergeThreshold         = 25   // Merge when we have 25+ groups
-)
-
 var clientCount int64
 
 var upgrader = websocket.Upgrader{
@@ -61,23 +54,39 @@ type CrashGameState struct {
 	ServerSeedHash string
 	Status         string // "countdown", "running", "crashed"
 	ContractGameID *big.Int
+	ChainRound     uint64 // Position of the seed in the hash chain (0 if uncommitted)
+
+	// Server-authoritative price, updated every tick
+	CurrentTick       int
+	CurrentMultiplier float64
 }
 
-func init() {
-	// Start the crash game loop
+// StartCrashGameLoop starts the live crash game with the configured timing
+func StartCrashGameLoop() {
 	go runCrashGameLoop()
 }
 
 func HandleWS(w http.ResponseWriter, r *http.Request) {
 	log.Println("📥 WebSocket connection attempt from:", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgradeConn(w, r, nil, "crash-legacy")
 	if err != nil {
 		log.Println("❌ WebSocket upgrade failed:", err)
 		return
 	}
 	defer conn.Close()
 
+	// This endpoint only writes, but pongs and close frames are handled by
+	// reads: drain the connection so a silent client times out
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
 	// Increment client count
 	atomic.AddInt64(&clientCount, 1)
 	count := atomic.LoadInt64(&clientCount)
@@ -87,7 +96,7 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 		log.Printf("👋 Client disconnected. Total clients: %d\n", atomic.LoadInt64(&clientCount))
 	}()
 
-	// Game loop - restart games with 15 second delay
+	// Game loop - restart games after the configured end wait
 	for {
 		serverSeed, seedHash := crypto.GenerateServerSeed()
 		gameID := time.Now().Format("20060102-150405.000")
@@ -107,157 +116,37 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Simulate game tick-by-tick
-		combined := serverSeed + "-" + gameID
-		rng := game.NewSeededRNG(combined)
-
-		price := 1.0
-		peak := 1.0
-		tick := 0
-		rugged := false
-
-		// Candle grouping state
-		var groups []game.CandleGroup
-		var currentGroup *game.CandleGroup
-		groupDuration := int64(InitialGroupDurationMs)
-		groupStartTime := time.Now().UnixMilli()
-
-		for tick < 5000 {
-			if rng.Float64() < game.RugProb {
-				rugged = true
-				break
-			}
+		sim := game.NewCrashSimulator(game.CurrentRNGVersion, serverConfig.Crash.Distribution, serverSeed, gameID)
+		candles := game.NewCandleBuilder(serverConfig.Crash.CandleLayout())
 
-			// God candle (v3)
-			if rng.Float64() < game.GodCandleChance && price <= 100 {
-				price *= game.GodCandleMult
-			} else {
-				var change float64
-
-				// Big move
-				if rng.Float64() < game.BigMoveChance {
-					move := game.BigMoveMin + rng.Float64()*(game.BigMoveMax-game.BigMoveMin)
-					if rng.Float64() > 0.5 {
-						change = move
-					} else {
-						change = -move
-					}
-				} else {
-					// Normal drift
-					drift := game.DriftMin + rng.Float64()*(game.DriftMax-game.DriftMin)
-					volatility := 0.005 * math.Min(10, math.Sqrt(price))
-					noise := volatility * (2*rng.Float64() - 1)
-					change = drift + noise
-				}
-
-				price = price * (1 + change)
-				if price < 0 {
-					price = 0
-				}
-			}
-
-			if price > peak {
-				peak = price
-			}
-
-			// Candle grouping logic
-			now := time.Now().UnixMilli()
-
-			// Initialize first group if needed
-			if currentGroup == nil {
-				currentGroup = &game.CandleGroup{
-					Open:       price,
-					Close:      &price,
-					Max:        price,
-					Min:        price,
-					ValueList:  []float64{price},
-					StartTime:  now,
-					DurationMs: groupDuration,
-					IsComplete: false,
-				}
-				groupStartTime = now
-			} else {
-				// Check if we need to complete current group and start a new one
-				elapsed := now - groupStartTime
-
-				if elapsed >= groupDuration {
-					// Complete current group - create a deep copy with FINAL CLOSE VALUE
-					// CRITICAL: Must copy the close VALUE, not the pointer reference
-					finalCloseValue := *currentGroup.Close // Dereference the pointer to get the actual value
-					completedGroup := game.CandleGroup{
-						Open:       currentGroup.Open,
-						Close:      &finalCloseValue, // New pointer to the final value
-						Max:        currentGroup.Max,
-						Min:        currentGroup.Min,
-						ValueList:  []float64{}, // Empty valueList for completed candles (save bandwidth)
-						StartTime:  currentGroup.StartTime,
-						DurationMs: currentGroup.DurationMs,
-						IsComplete: true,
-					}
-					// Don't copy valueList - completed candles don't need it
-					groups = append(groups, completedGroup)
-					log.Printf("📊 Completed candle #%d: Open=%.2f, Close=%.2f (IMMUTABLE at %p), Max=%.2f, Min=%.2f",
-						len(groups), completedGroup.Open, *completedGroup.Close, completedGroup.Close, completedGroup.Max, completedGroup.Min)
-
-					// Check if we need to merge
-					if len(groups) >= MergeThreshold {
-						log.Printf("🔄 Merging %d groups (threshold reached)", len(groups))
-						groups, groupDuration = mergeGroups(groups, groupDuration)
-						log.Printf("✅ After merge: %d groups, new duration: %dms", len(groups), groupDuration)
-					}
-
-					// Start new group
-					currentGroup = &game.CandleGroup{
-						Open:       price,
-						Close:      &price,
-						Max:        price,
-						Min:        price,
-						ValueList:  []float64{price},
-						StartTime:  now,
-						DurationMs: groupDuration,
-						IsComplete: false,
-					}
-					groupStartTime = now
-					log.Printf("🆕 Started new candle group with price %.2f, duration %dms", price, groupDuration)
-				} else {
-					// Update current group
-					currentGroup.ValueList = append(currentGroup.ValueList, price)
-					currentGroup.Close = &price
-					currentGroup.Max = math.Max(currentGroup.Max, price)
-					currentGroup.Min = math.Min(currentGroup.Min, price)
-				}
+		for {
+			t, ok := sim.Next()
+			if !ok {
+				break
 			}
+			candles.Add(t.Price, time.Now().UnixMilli())
 
 			// Send completed groups separately from current group
-			// Always ensure previousCandles is an array (not nil) for JSON serialization
-			var previousCandles []game.CandleGroup
-			if len(groups) > 0 {
-				previousCandles = make([]game.CandleGroup, len(groups))
-				copy(previousCandles, groups)
-			} else {
-				previousCandles = []game.CandleGroup{} // Empty array instead of nil
-			}
+			previousCandles := candles.Completed()
+			currentGroup := candles.Current()
 
 			response := map[string]interface{}{
 				"type": "price_update",
 				"data": map[string]interface{}{
-					"tick":            tick,
-					"price":           price,
-					"multiplier":      price,
+					"tick":            t.Index,
+					"price":           t.Price,
+					"multiplier":      t.Price,
 					"gameEnded":       false,
 					"connectedUsers":  atomic.LoadInt64(&clientCount),
 					"previousCandles": previousCandles,
+					"currentCandle":   *currentGroup,
 				},
 			}
 
-			// Add current candle if it exists
-			if currentGroup != nil {
-				response["data"].(map[string]interface{})["currentCandle"] = *currentGroup
-			}
-
 			// Debug log first few ticks to verify data structure
-			if tick < 5 {
-				log.Printf("📤 Tick %d - Previous: %d candles, Current: %v, CurrentGroup details: %+v",
-					tick, len(previousCandles), currentGroup != nil, currentGroup)
+			if t.Index < 5 {
+				log.Printf("📤 Tick %d - Previous: %d candles, CurrentGroup details: %+v",
+					t.Index, len(previousCandles), currentGroup)
 			}
 
 			if err := conn.WriteJSON(response); err != nil {
@@ -265,35 +154,12 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			time.Sleep(500 * time.Millisecond)
-			tick++
+			time.Sleep(serverConfig.Crash.TickInterval.D())
 		}
 
 		// Complete the final group if game ended
-		if currentGroup != nil && !currentGroup.IsComplete {
-			// Get the final close value BEFORE creating the copy
-			var finalCloseValue float64
-			if rugged {
-				finalCloseValue = 0.0
-				currentGroup.Min = 0.0
-			} else {
-				finalCloseValue = *currentGroup.Close
-			}
-
-			// Create deep copy with FINAL VALUE (not pointer reference)
-			finalGroup := game.CandleGroup{
-				Open:       currentGroup.Open,
-				Close:      &finalCloseValue, // New pointer to final value
-				Max:        currentGroup.Max,
-				Min:        currentGroup.Min,
-				ValueList:  []float64{}, // Empty for completed candles
-				StartTime:  currentGroup.StartTime,
-				DurationMs: currentGroup.DurationMs,
-				IsComplete: true,
-			}
-			// Don't copy valueList - completed candles don't need it
-			groups = append(groups, finalGroup)
-		}
+		result := sim.Result()
+		groups := candles.Finish(result.Rugged)
 
 		// End game - send all completed candles (no current candle since game ended)
 		if err := conn.WriteJSON(map[string]interface{}{
@@ -302,9 +168,9 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 				"gameId":          gameID,
 				"serverSeed":      serverSeed,
 				"serverSeedHash":  seedHash,
-				"peakMultiplier":  peak,
-				"rugged":          rugged,
-				"totalTicks":      tick,
+				"peakMultiplier":  result.PeakMultiplier,
+				"rugged":          result.Rugged,
+				"totalTicks":      result.TotalTicks,
 				"connectedUsers":  atomic.LoadInt64(&clientCount),
 				"previousCandles": groups,
 			},
@@ -312,18 +178,40 @@ func HandleWS(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Wait 15 seconds before starting next game
-		time.Sleep(15 * time.Second)
+		// Wait before starting next game
+		time.Sleep(serverConfig.Crash.EndWait.D())
 	}
 }
 
-
 func runCrashGameLoop() {
 	log.Println("🎰 Crash game loop started")
 
+rounds:
 	for {
-		serverSeed, seedHash := crypto.GenerateServerSeed()
+		// Only the instance holding the crash lease runs rounds
+		for !isCrashLeader() {
+			time.Sleep(time.Second)
+		}
+
+		// Settle the rounds left unfinished by the last shutdown or by a
+		// previous leader. Rounds another instance may still be running are
+		// left for a later round.
+		RecoverCrashRounds()
+
+		// Claim the next pre-committed seed from the hash chain. Every round
+		// must be verifiable against the published chain, so no round starts
+		// without one.
+		seed, err := nextCrashSeed()
+		if err != nil {
+			log.Printf("❌ Failed to claim chain seed, skipping round: %v", err)
+			time.Sleep(serverConfig.Crash.EndWait.D())
+			continue
+		}
+		serverSeed, seedHash := seed.Seed, seed.SeedHash
+		chainRound, terminatingHash := seed.ChainRound, seed.TerminatingHash
 		gameID := time.Now().Format("20060102-150405.000")
+		dist := serverConfig.Crash.Distribution
+		layout := serverConfig.Crash.CandleLayout()
 
 		// Convert gameID to big.Int for contract (use Unix timestamp)
 		timestamp := time.Now().Unix()
@@ -336,222 +224,151 @@ func runCrashGameLoop() {
 			ServerSeedHash: seedHash,
 			Status:         "countdown",
 			ContractGameID: contractGameID,
+			ChainRound:     chainRound,
+
+			CurrentMultiplier: game.StartingPrice,
 		}
 		currentCrashGameMutex.Unlock()
 
 		// Set current game ID for API handlers to access
 		SetCurrentGameID(contractGameID.String())
 
+		// Track the round until it is settled so a restart can recover it
+		round := &db.CrashRoundData{
+			GameID:          contractGameID.String(),
+			RoundID:         gameID,
+			ServerSeed:      serverSeed,
+			ServerSeedHash:  seedHash,
+			ChainRound:      chainRound,
+			TerminatingHash: terminatingHash,
+			RNGVersion:      game.CurrentRNGVersion,
+			Distribution:    dist,
+			CandleLayout:    layout,
+			Status:          "countdown",
+			StartedAt:       time.Now(),
+		}
+		trackCrashRound(round)
+
 		// Broadcast game start (send contractGameID as string for client)
-		crashBroadcast <- map[string]interface{}{
+		publishCrash(map[string]interface{}{
 			"type": "game_start",
 			"data": map[string]interface{}{
-				"gameId":         contractGameID.String(), // Send contract game ID to client
-				"serverSeedHash": seedHash,
-				"startingPrice":  1.0,
+				"gameId":          contractGameID.String(), // Send contract game ID to client
+				"serverSeedHash":  seedHash,
+				"chainRound":      chainRound,
+				"terminatingHash": terminatingHash,
+				"distribution":    dist,
+				"startingPrice":   1.0,
 			},
-		}
+		})
 
 		// Countdown: 3, 2, 1
-		for i := 3; i > 0; i-- {
-			crashBroadcast <- map[string]interface{}{
+		for i := serverConfig.Crash.CountdownSeconds; i > 0; i-- {
+			if !isCrashLeader() {
+				abortCrashRound(round)
+				continue rounds
+			}
+			trackCrashRound(round)
+
+			publishCrash(map[string]interface{}{
 				"type": "countdown",
 				"data": map[string]interface{}{
 					"countdown": i,
 				},
-			}
+			})
 			time.Sleep(1 * time.Second)
 		}
 
+		// Bets are in: mix the players' client seeds into the committed
+		// chain seed, so neither side alone picks the round's result
+		clientSeed, clientSeeds := crashRoundClientSeed(contractGameID.String())
+		roundSeed := crypto.CrashRoundSeed(serverSeed, clientSeed, chainRound)
+
 		// Update status to running
 		currentCrashGameMutex.Lock()
 		currentCrashGame.Status = "running"
 		currentCrashGameMutex.Unlock()
 
-		// Run game simulation
-		combined := serverSeed + "-" + gameID
-		rng := game.NewSeededRNG(combined)
-
-		price := 1.0
-		peak := 1.0
-		tick := 0
-		rugged := false
-
-		// Candle grouping state
-		var groups []game.CandleGroup
-		var currentGroup *game.CandleGroup
-		groupDuration := int64(InitialGroupDurationMs)
-		groupStartTime := time.Now().UnixMilli()
-
-		for tick < 5000 {
-			if rng.Float64() < game.RugProb {
-				rugged = true
-				break
-			}
-
-			// God candle
-			if rng.Float64() < game.GodCandleChance && price <= 100 {
-				price *= game.GodCandleMult
-			} else {
-				var change float64
-
-				// Big move
-				if rng.Float64() < game.BigMoveChance {
-					move := game.BigMoveMin + rng.Float64()*(game.BigMoveMax-game.BigMoveMin)
-					if rng.Float64() > 0.5 {
-						change = move
-					} else {
-						change = -move
-					}
-				} else {
-					// Normal drift
-					drift := game.DriftMin + rng.Float64()*(game.DriftMax-game.DriftMin)
-					volatility := 0.005 * math.Min(10, math.Sqrt(price))
-					noise := volatility * (2*rng.Float64() - 1)
-					change = drift + noise
-				}
-
-				price = price * (1 + change)
-				if price < 0 {
-					price = 0
-				}
-			}
+		round.ClientSeed = clientSeed
+		round.Status = "running"
+		trackCrashRound(round)
 
-			if price > peak {
-				peak = price
+		// Run game simulation
+		sim := game.NewCrashSimulator(game.CurrentRNGVersion, dist, roundSeed, gameID)
+		candles := game.NewCandleBuilder(layout)
+
+		for {
+			// A leader that lost the lease stops at once, so only the new
+			// leader settles the round
+			if !isCrashLeader() {
+				abortCrashRound(round)
+				continue rounds
 			}
 
-			// Candle grouping logic
-			now := time.Now().UnixMilli()
-
-			if currentGroup == nil {
-				currentGroup = &game.CandleGroup{
-					Open:       price,
-					Close:      &price,
-					Max:        price,
-					Min:        price,
-					ValueList:  []float64{price},
-					StartTime:  now,
-					DurationMs: groupDuration,
-					IsComplete: false,
-				}
-				groupStartTime = now
-			} else {
-				elapsed := now - groupStartTime
-
-				if elapsed >= groupDuration {
-					// Complete current group
-					finalCloseValue := *currentGroup.Close
-					completedGroup := game.CandleGroup{
-						Open:       currentGroup.Open,
-						Close:      &finalCloseValue,
-						Max:        currentGroup.Max,
-						Min:        currentGroup.Min,
-						ValueList:  []float64{},
-						StartTime:  currentGroup.StartTime,
-						DurationMs: currentGroup.DurationMs,
-						IsComplete: true,
-					}
-					groups = append(groups, completedGroup)
-
-					// Check if we need to merge
-					if len(groups) >= MergeThreshold {
-						groups, groupDuration = mergeGroups(groups, groupDuration)
-					}
-
-					// Start new group
-					currentGroup = &game.CandleGroup{
-						Open:       price,
-						Close:      &price,
-						Max:        price,
-						Min:        price,
-						ValueList:  []float64{price},
-						StartTime:  now,
-						DurationMs: groupDuration,
-						IsComplete: false,
-					}
-					groupStartTime = now
-				} else {
-					// Update current group
-					currentGroup.ValueList = append(currentGroup.ValueList, price)
-					currentGroup.Close = &price
-					currentGroup.Max = math.Max(currentGroup.Max, price)
-					currentGroup.Min = math.Min(currentGroup.Min, price)
-				}
+			t, ok := advanceCrashRound(sim)
+			if !ok {
+				break
 			}
 
-			// Broadcast price update
-			var previousCandles []game.CandleGroup
-			if len(groups) > 0 {
-				previousCandles = make([]game.CandleGroup, len(groups))
-				copy(previousCandles, groups)
-			} else {
-				previousCandles = []game.CandleGroup{}
+			// Keep the tracking record fresh, so recovery leaves the round
+			// alone while it runs here
+			if time.Since(round.UpdatedAt) >= time.Second {
+				trackCrashRound(round)
 			}
-
-			message := map[string]interface{}{
-				"type": "price_update",
-				"data": map[string]interface{}{
-					"gameId":          contractGameID.String(), // Include gameId in every update
-					"tick":            tick,
-					"price":           price,
-					"multiplier":      price,
-					"gameEnded":       false,
-					"previousCandles": previousCandles,
+			delta := candles.AddDelta(t.Price, time.Now().UnixMilli())
+
+			// Broadcast price update, with all candles for full protocol
+			// clients and only what this tick changed for delta clients
+			crashBroadcast <- hubMessage{
+				message: map[string]interface{}{
+					"type": "price_update",
+					"data": &PriceUpdate{
+						GameID:          contractGameID.String(), // Include gameId in every update
+						Tick:            t.Index,
+						Price:           t.Price,
+						Multiplier:      t.Price,
+						GameEnded:       false,
+						PreviousCandles: candles.Completed(),
+						CurrentCandle:   *candles.Current(),
+					},
+				},
+				variants: map[int]map[string]interface{}{
+					protocolDelta: priceTickMessage(contractGameID.String(), t.Index, delta),
 				},
 			}
 
-			if currentGroup != nil {
-				message["data"].(map[string]interface{})["currentCandle"] = *currentGroup
-			}
-
-			crashBroadcast <- message
-
-			time.Sleep(500 * time.Millisecond)
-			tick++
+			time.Sleep(serverConfig.Crash.TickInterval.D())
 		}
 
-		// Complete final group
-		if currentGroup != nil && !currentGroup.IsComplete {
-			var finalCloseValue float64
-			if rugged {
-				finalCloseValue = 0.0
-				currentGroup.Min = 0.0
-			} else {
-				finalCloseValue = *currentGroup.Close
-			}
+		round.Status = "crashed"
+		trackCrashRound(round)
 
-			finalGroup := game.CandleGroup{
-				Open:       currentGroup.Open,
-				Close:      &finalCloseValue,
-				Max:        currentGroup.Max,
-				Min:        currentGroup.Min,
-				ValueList:  []float64{},
-				StartTime:  currentGroup.StartTime,
-				DurationMs: currentGroup.DurationMs,
-				IsComplete: true,
-			}
-			groups = append(groups, finalGroup)
-		}
-
-		// Update status to crashed
-		currentCrashGameMutex.Lock()
-		currentCrashGame.Status = "crashed"
-		currentCrashGameMutex.Unlock()
+		// Complete final group
+		result := sim.Result()
+		peak := result.PeakMultiplier
+		rugged := result.Rugged
+		groups := candles.Finish(rugged)
 
 		// Broadcast game end FIRST
-		crashBroadcast <- map[string]interface{}{
+		publishCrash(map[string]interface{}{
 			"type": "game_end",
-			"data": map[string]interface{}{
-				"gameId":          contractGameID.String(),
-				"serverSeed":      serverSeed,
-				"serverSeedHash":  seedHash,
-				"peakMultiplier":  peak,
-				"rugged":          rugged,
-				"totalTicks":      tick,
-				"previousCandles": groups,
+			"data": &GameEnd{
+				GameID:          contractGameID.String(),
+				RoundID:         gameID,
+				RNGVersion:      result.RNGVersion,
+				Distribution:    dist,
+				ServerSeed:      serverSeed,
+				ServerSeedHash:  seedHash,
+				ClientSeed:      clientSeed,
+				ClientSeeds:     clientSeeds,
+				ChainRound:      chainRound,
+				TerminatingHash: terminatingHash,
+				PeakMultiplier:  peak,
+				Rugged:          rugged,
+				TotalTicks:      result.TotalTicks,
+				PreviousCandles: groups,
 			},
-		}
+		})
 
 		// Add to history
 		gameHistoryMutex.Lock()
@@ -562,9 +379,9 @@ func runCrashGameLoop() {
 			Candles:        groups,
 			Timestamp:      time.Now(),
 		})
-		// Keep only last 10 games
-		if len(crashGameHistory) > MaxGameHistory {
-			crashGameHistory = crashGameHistory[len(crashGameHistory)-MaxGameHistory:]
+		// Keep only the configured number of games
+		if size := serverConfig.Crash.HistorySize; len(crashGameHistory) > size {
+			crashGameHistory = crashGameHistory[len(crashGameHistory)-size:]
 		}
 		gameHistoryMutex.Unlock()
 
@@ -577,24 +394,33 @@ func runCrashGameLoop() {
 				GameID:             gameID,
 				ServerSeed:         serverSeed,
 				ServerSeedHash:     seedHash,
+				ClientSeed:         clientSeed,
 				Peak:               peak,
 				CandlestickHistory: groups,
 				Rugged:             rugged,
+				RNGVersion:         result.RNGVersion,
+				Distribution:       dist,
+				CandleLayout:       layout,
+				ChainRound:         chainRound,
+				TerminatingHash:    terminatingHash,
 				CreatedAt:          time.Now(),
 			}
 
 			if err := db.StoreCrashHistory(storeCtx, historyRecord); err != nil {
 				log.Printf("⚠️ Failed to store crash history in PostgreSQL: %v", err)
+				return
 			}
-		}()
 
-		// Clean up Redis for this game
-		gameIDStr := contractGameID.String()
-		go func() {
+			// Clean up Redis for this game once its history is safe; until
+			// then startup recovery can still finish the round
 			cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
-			if err := db.CleanupCrashGame(cleanupCtx, gameIDStr); err != nil {
+			if err := db.CleanupCrashGame(cleanupCtx, round.GameID); err != nil {
 				log.Printf("⚠️ Failed to cleanup Redis: %v", err)
+				return
+			}
+			if err := db.CloseCrashRound(cleanupCtx, round.GameID); err != nil {
+				log.Printf("⚠️ %v", err)
 			}
 		}()
 
@@ -602,49 +428,83 @@ func runCrashGameLoop() {
 
 		// Broadcast updated history
 		updatedHistory := getCrashGameHistory()
-		crashBroadcast <- map[string]interface{}{
+		publishCrash(map[string]interface{}{
 			"type":    "crash_history",
 			"history": updatedHistory,
-		}
+		})
 		log.Printf("📜 Broadcasted updated crash history (%d games)", len(updatedHistory))
 
 		// Clear all active bettors for next game
 		ClearActiveBettors()
 
 		// Wait before next game
-		time.Sleep(15 * time.Second)
+		time.Sleep(serverConfig.Crash.EndWait.D())
 	}
 }
 
-// mergeGroups merges candlestick groups when threshold is reached
-func mergeGroups(groups []game.CandleGroup, currentDuration int64) ([]game.CandleGroup, int64) {
-	// Simple merge: combine pairs
-	merged := make([]game.CandleGroup, 0, len(groups)/2+1)
-	newDuration := currentDuration * 2
-
-	for i := 0; i < len(groups); i += 2 {
-		if i+1 < len(groups) {
-			// Merge two groups
-			g1, g2 := groups[i], groups[i+1]
-			closeVal := *g2.Close
-			merged = append(merged, game.CandleGroup{
-				Open:       g1.Open,
-				Close:      &closeVal,
-				Max:        math.Max(g1.Max, g2.Max),
-				Min:        math.Min(g1.Min, g2.Min),
-				ValueList:  []float64{},
-				StartTime:  g1.StartTime,
-				DurationMs: newDuration,
-				IsComplete: true,
-			})
-		} else {
-			// Odd one out
-			merged = append(merged, groups[i])
+// abortCrashRound stops the live round without settling it, after this
+// instance lost the crash lease. Its tracking record stops being refreshed,
+// so the new leader's recovery settles it.
+func abortCrashRound(round *db.CrashRoundData) {
+	currentCrashGameMutex.Lock()
+	currentCrashGame.Status = "aborted"
+	currentCrashGameMutex.Unlock()
+
+	ClearActiveBettors()
+	log.Printf("👥 Lost the crash lease during round %s, leaving it to the new leader", round.GameID)
+}
+
+// advanceCrashRound moves the live round to the simulator's next tick and
+// settles the auto-cashouts the price reached, before the tick is broadcast.
+// It reports false once the round has crashed.
+func advanceCrashRound(sim *game.CrashSimulator) (game.CrashTick, bool) {
+	// Advance under the game lock so cashouts always settle at the
+	// latest tick and none slip in after the round has rugged
+	currentCrashGameMutex.Lock()
+	t, ok := sim.Next()
+	if ok {
+		currentCrashGame.CurrentTick = t.Index
+		currentCrashGame.CurrentMultiplier = t.Price
+	} else {
+		currentCrashGame.Status = "crashed"
+	}
+	currentCrashGameMutex.Unlock()
+
+	if !ok {
+		return t, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.Crash.TickInterval.D())
+	processAutoCashouts(ctx)
+	cancel()
+	return t, true
+}
+
+// crashRoundClientSeed combines the client seeds of the players who bet on
+// the round (see crypto.CrashClientSeed) and returns them with the result
+func crashRoundClientSeed(gameID string) (string, map[string]string) {
+	clientSeeds := make(map[string]string)
+	if db.RedisClient == nil {
+		return crypto.CrashClientSeed(clientSeeds), clientSeeds
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	players, err := db.GetActivePlayers(ctx, gameID)
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	for _, player := range players {
+		pair, err := db.GetSeedPair(ctx, player)
+		if err != nil {
+			log.Printf("⚠️  Leaving %s out of the round client seed: %v", player, err)
+			continue
 		}
+		clientSeeds[player] = pair.ClientSeed
 	}
 
-	log.Printf("🔄 Merged %d groups into %d (new duration: %dms)", len(groups), len(merged), newDuration)
-	return merged, newDuration
+	return crypto.CrashClientSeed(clientSeeds), clientSeeds
 }
 
 // AddActiveBettor adds a new bettor to the active list
@@ -708,9 +568,9 @@ func broadcastActiveBettors() {
 		list = append(list, bettor)
 	}
 
-	crashBroadcast <- map[string]interface{}{
+	publishCrash(map[string]interface{}{
 		"type":    "active_bettors",
 		"bettors": list,
 		"count":   len(list),
-	}
-}
\ No newline at end of file
+	})
+}
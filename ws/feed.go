@@ -0,0 +1,308 @@
+package ws
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"goLangServer/game"
+)
+
+// Every message broadcast on a hub channel carries the channel name and a
+// sequence number that grows by one per message on that channel. A client
+// that sees a gap sends "resync" and gets a snapshot of the channel state
+// with the sequence number it is current to.
+//
+// Clients choose a protocol version per channel in "subscribe". Version 1
+// clients are replayed the channel state as individual messages and get every
+// message in full. On channels that speak version 2 (crash), version 2 clients
+// get the state as a single snapshot and then deltas: price_tick carries only
+// the new tick and any candle completion or merge, where price_update carries
+// every candle of the round.
+
+// Protocol versions a client can subscribe with
+const (
+	protocolFull  = 1
+	protocolDelta = 2
+)
+
+// channelProtocols is the newest protocol version of each channel kind.
+// Channels not listed only speak protocolFull.
+var channelProtocols = map[string]int{
+	"crash": protocolDelta,
+}
+
+// hubChannel is the sequence and replay state of one hub channel.
+// It is owned by the event hub goroutine.
+type hubChannel struct {
+	seq    uint64
+	latest map[string]map[string]interface{} // Last message of each replayed type
+}
+
+// replayTypes lists per channel the message types whose latest copy makes up
+// the channel state, and the types each of them supersedes
+var replayTypes = map[string]map[string][]string{
+	"crash": {
+		"game_start":     {"countdown", "price_update", "game_end"},
+		"countdown":      nil,
+		"price_update":   {"countdown"},
+		"game_end":       {"price_update"},
+		"crash_history":  nil,
+		"active_bettors": nil,
+	},
+	"rooms": {
+		"rooms_update": nil,
+	},
+}
+
+// hubChannels is only accessed by the event hub goroutine
+var hubChannels = make(map[string]*hubChannel)
+
+// hubMessage is a message for a hub channel. Variants replace the message
+// for clients subscribed with the given protocol versions.
+type hubMessage struct {
+	message  map[string]interface{}
+	variants map[int]map[string]interface{}
+}
+
+// hubRequest asks the hub to subscribe a client to a channel with the
+// requested protocol version, or with resync set, to send it a snapshot of
+// the channel
+type hubRequest struct {
+	client   *ClientConnection
+	channel  string
+	protocol int
+	resync   bool
+}
+
+var hubRequests = make(chan hubRequest)
+
+// stampMessage assigns a sequence number of a channel to a message and
+// records it for replay: seq, as assigned through Redis, or with a seq of 0
+// the channel's next. The original message is not modified.
+func stampMessage(channel string, seq uint64, message map[string]interface{}) map[string]interface{} {
+	ch := hubChannelFor(channel)
+	if seq == 0 {
+		seq = ch.seq + 1
+	}
+	ch.seq = seq
+
+	stamped := make(map[string]interface{}, len(message)+2)
+	for k, v := range message {
+		stamped[k] = v
+	}
+	stamped["channel"] = channel
+	stamped["seq"] = seq
+
+	msgType, _ := message["type"].(string)
+	if superseded, ok := replayTypes[channelKind(channel)][msgType]; ok {
+		for _, t := range superseded {
+			delete(ch.latest, t)
+		}
+		ch.latest[msgType] = stamped
+	}
+	return stamped
+}
+
+// seedChannel records a message for replay without taking a sequence
+// number, so the seed state does not run ahead of the sequence numbers
+// assigned through Redis
+func seedChannel(channel string, message map[string]interface{}) {
+	ch := hubChannelFor(channel)
+	seeded := make(map[string]interface{}, len(message)+2)
+	for k, v := range message {
+		seeded[k] = v
+	}
+	seeded["channel"] = channel
+	seeded["seq"] = uint64(0)
+
+	msgType, _ := message["type"].(string)
+	ch.latest[msgType] = seeded
+}
+
+func hubChannelFor(channel string) *hubChannel {
+	ch, ok := hubChannels[channel]
+	if !ok {
+		ch = &hubChannel{latest: make(map[string]map[string]interface{})}
+		hubChannels[channel] = ch
+	}
+	return ch
+}
+
+// stampVariant copies the channel and sequence number of a stamped message
+// onto one of its variants
+func stampVariant(stamped, variant map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(variant)+2)
+	for k, v := range variant {
+		out[k] = v
+	}
+	out["channel"] = stamped["channel"]
+	out["seq"] = stamped["seq"]
+	return out
+}
+
+// negotiateProtocol returns the version a client asking for requested gets
+// on a channel: the requested version if the channel speaks it, else the
+// channel's newest. Clients that ask for none get protocolFull.
+func negotiateProtocol(channel string, requested int) int {
+	newest, ok := channelProtocols[channelKind(channel)]
+	if !ok {
+		newest = protocolFull
+	}
+	if requested < protocolFull {
+		return protocolFull
+	}
+	if requested > newest {
+		return newest
+	}
+	return requested
+}
+
+// channelSeq returns the sequence number of the last message on a channel
+func channelSeq(channel string) uint64 {
+	if ch, ok := hubChannels[channel]; ok {
+		return ch.seq
+	}
+	return 0
+}
+
+// channelReplay returns the messages that rebuild the channel state, in
+// sequence order
+func channelReplay(channel string) []map[string]interface{} {
+	if channel == "chat" && hubBus.Load() {
+		return chatReplay()
+	}
+	if channel == "chat" {
+		chatHistoryMutex.RLock()
+		defer chatHistoryMutex.RUnlock()
+
+		history := make([]map[string]interface{}, len(chatHistory))
+		copy(history, chatHistory)
+		return history
+	}
+
+	ch, ok := hubChannels[channel]
+	if !ok {
+		return nil
+	}
+	messages := make([]map[string]interface{}, 0, len(ch.latest))
+	for _, m := range ch.latest {
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i]["seq"].(uint64) < messages[j]["seq"].(uint64)
+	})
+	return messages
+}
+
+// chatReplay returns the chat history kept in Redis up to the last message
+// this instance delivered. An instance that has delivered none yet catches
+// up to the history, and skips the messages in it when they arrive.
+func chatReplay() []map[string]interface{} {
+	ch := hubChannelFor("chat")
+	history := sharedChatHistory()
+
+	if ch.seq == 0 && len(history) > 0 {
+		ch.seq = history[len(history)-1]["seq"].(uint64)
+	}
+	for i, m := range history {
+		if m["seq"].(uint64) > ch.seq {
+			return history[:i]
+		}
+	}
+	return history
+}
+
+// channelKind strips the room ID from per-room channels
+func channelKind(channel string) string {
+	if i := strings.IndexByte(channel, ':'); i >= 0 {
+		return channel[:i]
+	}
+	return channel
+}
+
+// handleHubRequest subscribes a client or answers a resync. It runs on the
+// hub goroutine, so no broadcast can slip between the state a client is sent
+// and the sequence number it is told it is current to.
+func handleHubRequest(req hubRequest) {
+	clientsMutex.RLock()
+	_, registered := clients[req.client]
+	clientsMutex.RUnlock()
+	if !registered {
+		return
+	}
+
+	c := req.client
+	seq := channelSeq(req.channel)
+	replay := channelReplay(req.channel)
+
+	snapshot := map[string]interface{}{
+		"type":     "snapshot",
+		"channel":  req.channel,
+		"seq":      seq,
+		"messages": replay,
+	}
+
+	if req.resync {
+		c.sendMessage(snapshot)
+		log.Printf("🔄 Client %s resynced %s at seq %d", c.ID, req.channel, seq)
+		return
+	}
+
+	protocol := negotiateProtocol(req.channel, req.protocol)
+	c.mu.Lock()
+	c.Subscriptions[req.channel] = protocol
+	c.mu.Unlock()
+
+	if protocol >= protocolDelta {
+		snapshot["protocol"] = protocol
+		c.sendMessage(snapshot)
+		log.Printf("📡 Client %s subscribed to: %s (protocol %d, snapshot of %d messages, seq %d)", c.ID, req.channel, protocol, len(replay), seq)
+		return
+	}
+
+	// Replay the channel state as individual messages, then confirm the
+	// sequence number the client is current to
+	for _, m := range replay {
+		c.sendMessage(m)
+	}
+	c.sendMessage(map[string]interface{}{
+		"type":     "subscribed",
+		"channel":  req.channel,
+		"seq":      seq,
+		"protocol": protocol,
+	})
+	log.Printf("📡 Client %s subscribed to: %s (replayed %d messages, seq %d)", c.ID, req.channel, len(replay), seq)
+}
+
+// sendMessage encodes a message in the client's wire encoding and queues it
+func (c *ClientConnection) sendMessage(message interface{}) bool {
+	data, err := encodeMessage(c.Encoding, message)
+	if err != nil {
+		log.Printf("❌ Failed to encode message for client %s: %v", c.ID, err)
+		return false
+	}
+	return c.send(data)
+}
+
+// publishCrash broadcasts a message sent alike to every crash subscriber
+func publishCrash(message map[string]interface{}) {
+	crashBroadcast <- hubMessage{message: message}
+}
+
+// priceTickMessage is the delta protocol form of a price_update: the tick,
+// and the candle completed, merge and group started by it, if any
+func priceTickMessage(gameID string, tick int, delta game.CandleDelta) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "price_tick",
+		"data": &PriceTick{
+			GameID:              gameID,
+			Tick:                tick,
+			Price:               delta.Price,
+			Time:                delta.Time,
+			CompletedCandle:     delta.Completed,
+			Merged:              delta.Merged,
+			NewCandleDurationMs: delta.DurationMs,
+		},
+	}
+}
@@ -3,11 +3,17 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"net/http"
-	"strings"
+	"sync"
+	"time"
+
+	"goLangServer/config"
+	"goLangServer/contract"
+	"goLangServer/db"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -16,16 +22,71 @@ import (
 type GaslessCashOutRequest struct {
 	PlayerAddress     string `json:"playerAddress"`
 	GameID            string `json:"gameId"`
-	CurrentMultiplier string `json:"currentMultiplier"`
-	Signature         string `json:"signature"` // Player's signature authorizing the cashout
+	CurrentMultiplier string `json:"currentMultiplier"` // Minimum accepted multiplier, e.g. "2.5"
+	Nonce             uint64 `json:"nonce"`             // Player's next relayer nonce
+	Signature         string `json:"signature"`         // EIP-712 CashOut signature
 }
 
 // GaslessCashOutResponse represents the response
 type GaslessCashOutResponse struct {
-	Success         bool   `json:"success"`
-	TransactionHash string `json:"transactionHash,omitempty"`
-	Error           string `json:"error,omitempty"`
-	Payout          string `json:"payout,omitempty"`
+	Success           bool    `json:"success"`
+	TransactionHash   string  `json:"transactionHash,omitempty"`
+	Error             string  `json:"error,omitempty"`
+	Payout            string  `json:"payout,omitempty"`
+	CashoutMultiplier float64 `json:"cashoutMultiplier,omitempty"`
+}
+
+// GaslessBuyInRequest registers a crash bet from a signed request and the
+// player's on-chain bet() deposit. The GameHouse contract has no meta-transaction
+// entry point for bets, so the stake itself always comes from the player.
+type GaslessBuyInRequest struct {
+	PlayerAddress string  `json:"playerAddress"`
+	GameID        string  `json:"gameId"`
+	BetAmount     string  `json:"betAmount"`     // Wei as string
+	DepositTxHash string  `json:"depositTxHash"` // Player's bet() transaction
+	AutoCashout   float64 `json:"autoCashout,omitempty"`
+	Nonce         uint64  `json:"nonce"`
+	Signature     string  `json:"signature"` // EIP-712 BuyIn signature
+}
+
+var (
+	relayer      *contract.Relayer
+	relayerMutex sync.Mutex
+)
+
+// redisNonceStore keeps relayer nonces in Redis
+type redisNonceStore struct{}
+
+func (redisNonceStore) NextNonce(ctx context.Context, player common.Address) (uint64, error) {
+	return db.GetRelayerNonce(ctx, player.Hex())
+}
+
+func (redisNonceStore) UseNonce(ctx context.Context, player common.Address, nonce uint64) error {
+	return db.UseRelayerNonce(ctx, player.Hex(), nonce)
+}
+
+// getRelayer returns the gasless relayer, created on first use from the shared contract client
+func getRelayer() (*contract.Relayer, error) {
+	relayerMutex.Lock()
+	defer relayerMutex.Unlock()
+
+	if relayer != nil {
+		return relayer, nil
+	}
+
+	if contract.GameHouse == nil {
+		return nil, fmt.Errorf("relayer unavailable: contract client not initialized")
+	}
+
+	relayer = contract.NewRelayerFromContract(contract.GameHouse, serverConfig.Relayer, redisNonceStore{})
+	log.Printf("✅ Gasless relayer initialized - Relayer: %s", relayer.Address().Hex())
+	return relayer, nil
+}
+
+// Relayer returns the gasless relayer, which also checks the players' signed
+// requests made outside the crash game
+func Relayer() (*contract.Relayer, error) {
+	return getRelayer()
 }
 
 // HandleGaslessCashOut handles gasless cashout requests
@@ -42,8 +103,9 @@ func HandleGaslessCashOut(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate inputs
-	if !common.IsHexAddress(req.PlayerAddress) {
-		sendJSONError(w, "Invalid player address", http.StatusBadRequest)
+	playerAddr, err := wagers.Address("playerAddress", req.PlayerAddress)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -60,18 +122,35 @@ func HandleGaslessCashOut(w http.ResponseWriter, r *http.Request) {
 		sendJSONError(w, "Invalid multiplier", http.StatusBadRequest)
 		return
 	}
+	minMultiplier, _ := multiplierFloat.Float64()
+	if err := wagers.Multiplier("currentMultiplier", minMultiplier); err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
 
 	// Convert to wei (18 decimals)
-	multiplierWei, _ := multiplierFloat.Mul(multiplierFloat, big.NewFloat(1e18)).Int(nil)
+	multiplierWei, _ := new(big.Float).Mul(multiplierFloat, big.NewFloat(1e18)).Int(nil)
 
-	playerAddr := common.HexToAddress(req.PlayerAddress)
+	signature, err := decodeSignature(req.Signature)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	log.Printf("🎮 Gasless cashout request from %s for game %s at %sx",
 		playerAddr.Hex(), gameID.String(), req.CurrentMultiplier)
 
 	// Execute gasless cashout via relayer
-	ctx := context.Background()
-	txHash, payout, err := executeGaslessCashOut(ctx, playerAddr, gameID, multiplierWei, req.Signature)
+	ctx, cancel := context.WithTimeout(r.Context(), serverConfig.Relayer.TxTimeout.D())
+	defer cancel()
+
+	resp, err := executeGaslessCashOut(ctx, &contract.CashOutRequest{
+		PlayerAddress: playerAddr,
+		GameID:        gameID,
+		Multiplier:    multiplierWei,
+		Nonce:         req.Nonce,
+		Signature:     signature,
+	}, minMultiplier)
 
 	if err != nil {
 		log.Printf("❌ Gasless cashout failed: %v", err)
@@ -82,36 +161,65 @@ func HandleGaslessCashOut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("✅ Gasless cashout successful! TX: %s, Payout: %s MNT", txHash, payout)
+	log.Printf("✅ Gasless cashout successful! TX: %s, Payout: %s MNT", resp.TransactionHash, resp.Payout)
 
-	sendJSONResponse(w, GaslessCashOutResponse{
-		Success:         true,
-		TransactionHash: txHash,
-		Payout:          payout,
-	})
+	sendJSONResponse(w, resp)
 }
 
-// executeGaslessCashOut executes the cashout via the relayer
-func executeGaslessCashOut(ctx context.Context, player common.Address, gameID *big.Int, multiplier *big.Int, signature string) (string, string, error) {
-	// TODO: Initialize relayer if not already done
-	// This would typically be done in main.go and passed here
+// executeGaslessCashOut verifies the signed request, settles the bet at the
+// current multiplier and relays the payout
+func executeGaslessCashOut(ctx context.Context, req *contract.CashOutRequest, minMultiplier float64) (GaslessCashOutResponse, error) {
+	rel, err := getRelayer()
+	if err != nil {
+		return GaslessCashOutResponse{}, err
+	}
+
+	if err := rel.AuthorizeCashOut(ctx, req); err != nil {
+		return GaslessCashOutResponse{}, err
+	}
 
-	// For now, return a mock response
-	// In production, this would call the actual relayer:
-	/*
-		tx, err := relayer.RelayCashOut(ctx, gameHouseContract, contract.CashOutRequest{
-			PlayerAddress:    player,
-			GameID:           gameID,
-			CurrentMultiplier: multiplier,
-			Signature:        common.FromHex(signature),
-		})
-		if err != nil {
-			return "", "", err
-		}
-		return tx.Hash().Hex(), "1.234", nil
-	*/
+	cashedOut, err := cashOutCrashBet(ctx, CrashCashOutRequest{
+		Address:       req.PlayerAddress.Hex(),
+		GameID:        req.GameID.String(),
+		MinMultiplier: minMultiplier,
+		relayed:       true,
+	})
+	if err != nil {
+		return GaslessCashOutResponse{}, err
+	}
 
-	return "0x" + strings.Repeat("0", 64), "0.000", fmt.Errorf("relayer not implemented yet - deploy contract first")
+	payout, ok := new(big.Int).SetString(cashedOut.Payout, 10)
+	if !ok {
+		return GaslessCashOutResponse{}, fmt.Errorf("invalid payout %q", cashedOut.Payout)
+	}
+
+	tx, err := rel.RelayPayout(ctx, req.PlayerAddress, payout)
+	if err != nil {
+		// The bet is already settled, fall back to the payout queue
+		log.Printf("⚠️ Relayed payout failed, queueing instead: %v", err)
+		queueCrashPayout(cashedOut)
+		return GaslessCashOutResponse{
+			Success:           true,
+			Payout:            fmt.Sprintf("%.6f", config.WeiToMNT(payout)),
+			CashoutMultiplier: cashedOut.CashoutMultiplier,
+		}, nil
+	}
+
+	return GaslessCashOutResponse{
+		Success:           true,
+		TransactionHash:   tx.Hash().Hex(),
+		Payout:            fmt.Sprintf("%.6f", config.WeiToMNT(payout)),
+		CashoutMultiplier: cashedOut.CashoutMultiplier,
+	}, nil
+}
+
+// decodeSignature parses a 0x-prefixed 65-byte hex signature
+func decodeSignature(signature string) ([]byte, error) {
+	sig := common.FromHex(signature)
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	return sig, nil
 }
 
 // Helper function to send JSON responses
@@ -129,10 +237,135 @@ func sendJSONError(w http.ResponseWriter, message string, status int) {
 	})
 }
 
-// HandleGaslessBuyIn handles gasless buy-in requests
+// HandleGaslessBuyIn registers a crash bet from a signed buy-in request
 func HandleGaslessBuyIn(w http.ResponseWriter, r *http.Request) {
-	// Similar to cashout but for buy-in
-	sendJSONError(w, "Not implemented yet", http.StatusNotImplemented)
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req GaslessBuyInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	playerAddr, err := wagers.Address("playerAddress", req.PlayerAddress)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+	gameID, ok := new(big.Int).SetString(req.GameID, 10)
+	if !ok {
+		sendJSONError(w, "Invalid game ID", http.StatusBadRequest)
+		return
+	}
+	amount, err := wagers.AmountWei("betAmount", req.BetAmount)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := wagers.AutoCashout("autoCashout", req.AutoCashout); err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+	if len(common.FromHex(req.DepositTxHash)) != common.HashLength {
+		sendJSONError(w, "Invalid deposit transaction hash", http.StatusBadRequest)
+		return
+	}
+	signature, err := decodeSignature(req.Signature)
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), serverConfig.Relayer.TxTimeout.D())
+	defer cancel()
+
+	rel, err := getRelayer()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	depositHash := common.HexToHash(req.DepositTxHash)
+
+	if gameID.String() != GetCurrentGameID() {
+		sendJSONError(w, "Game is not the current round", http.StatusBadRequest)
+		return
+	}
+
+	if err := rel.AuthorizeBuyIn(ctx, &contract.BuyInRequest{
+		PlayerAddress: playerAddr,
+		GameID:        gameID,
+		Amount:        amount,
+		DepositTxHash: depositHash,
+		Nonce:         req.Nonce,
+		Signature:     signature,
+	}); err != nil {
+		log.Printf("❌ Gasless buy-in rejected for %s: %v", playerAddr.Hex(), err)
+		sendJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// placeCrashBet claims the deposit once the round accepts the bet
+	bet, err := placeCrashBet(ctx, PlaceCrashBetRequest{
+		Address:     playerAddr.Hex(),
+		BetAmount:   amount.String(),
+		TxHash:      depositHash.Hex(),
+		AutoCashout: req.AutoCashout,
+		GameID:      gameID.String(),
+	})
+	if errors.Is(err, errDepositClaimed) {
+		sendWagerError(w, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("✅ Gasless buy-in registered for %s in game %s", playerAddr.Hex(), bet.GameID)
+
+	sendJSONResponse(w, map[string]interface{}{
+		"success": true,
+		"bet":     bet,
+	})
+}
+
+// HandleGaslessNonce returns the nonce and EIP-712 domain a player signs with
+// GET /api/gasless/nonce?address=0x...
+func HandleGaslessNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	address := r.URL.Query().Get("address")
+	if !common.IsHexAddress(address) {
+		sendJSONError(w, "Invalid player address", http.StatusBadRequest)
+		return
+	}
+
+	rel, err := getRelayer()
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	nonce, err := db.GetRelayerNonce(ctx, common.HexToAddress(address).Hex())
+	if err != nil {
+		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSONResponse(w, map[string]interface{}{
+		"nonce":  nonce,
+		"domain": rel.Domain(),
+	})
 }
 
 // Request types for bettor notifications
@@ -160,22 +393,23 @@ func HandleAddBettor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
-	if req.Address == "" {
-		http.Error(w, "Missing address", http.StatusBadRequest)
+	// Validate the bet against the configured limits
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
 		return
 	}
-	if req.BetAmount <= 0 {
-		http.Error(w, "Invalid bet amount", http.StatusBadRequest)
+	if err := wagers.AmountMNT("betAmount", req.BetAmount); err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
 		return
 	}
-	if req.Multiplier <= 0 {
-		http.Error(w, "Invalid multiplier", http.StatusBadRequest)
+	if err := wagers.Multiplier("multiplier", req.Multiplier); err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
 		return
 	}
 
 	// Add bettor to active list
-	AddActiveBettor(req.Address, req.BetAmount, req.Multiplier)
+	AddActiveBettor(player.Hex(), req.BetAmount, req.Multiplier)
 
 	// Send success response
 	w.Header().Set("Content-Type", "application/json")
@@ -200,13 +434,14 @@ func HandleRemoveBettor(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request
-	if req.Address == "" {
-		http.Error(w, "Missing address", http.StatusBadRequest)
+	player, err := wagers.Address("address", req.Address)
+	if err != nil {
+		sendWagerError(w, err, http.StatusBadRequest)
 		return
 	}
 
 	// Remove bettor from active list
-	RemoveActiveBettor(req.Address)
+	RemoveActiveBettor(player.Hex())
 
 	// Send success response
 	w.Header().Set("Content-Type", "application/json")
@@ -1,12 +1,16 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	"goLangServer/db"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -15,26 +19,28 @@ type RoomInfo struct {
 	RoomID         string    `json:"roomId"`
 	GameType       string    `json:"gameType"` // "crash" or "candleflip"
 	BetAmount      float64   `json:"betAmount"`
-	Trend          string    `json:"trend,omitempty"`      // For candleflip: "bullish" or "bearish" (player's choice)
-	Status         string    `json:"status"`               // "active", "running", "finished"
+	Trend          string    `json:"trend,omitempty"` // For candleflip: "bullish" or "bearish" (player's choice)
+	Status         string    `json:"status"`          // "active", "running", "finished"
 	CreatedAt      time.Time `json:"createdAt"`
 	Players        int       `json:"players"`
-	CreatorId      string    `json:"creatorId,omitempty"`  // ID of player who created the room
-	BotName        string    `json:"botName,omitempty"`    // Bot opponent name for candleflip
-	BearSide       string    `json:"bearSide,omitempty"`   // "player" or "bot" - who is on bearish side
-	BullSide       string    `json:"bullSide,omitempty"`   // "player" or "bot" - who is on bullish side
-	MaxPlayers     int       `json:"maxPlayers"`           // 1 for candleflip (player vs bot), unlimited for crash
+	CreatorId      string    `json:"creatorId,omitempty"`      // ID of player who created the room
+	BotName        string    `json:"botName,omitempty"`        // Bot opponent name for candleflip
+	BearSide       string    `json:"bearSide,omitempty"`       // "player" or "bot" - who is on bearish side
+	BullSide       string    `json:"bullSide,omitempty"`       // "player" or "bot" - who is on bullish side
+	MaxPlayers     int       `json:"maxPlayers"`               // 1 for candleflip (player vs bot), unlimited for crash
 	ContractGameID string    `json:"contractGameId,omitempty"` // Contract game ID from placeCandleFlip
-	RoomsCount     int       `json:"roomsCount,omitempty"` // Number of rooms for CandleFlip
+	RoomsCount     int       `json:"roomsCount,omitempty"`     // Number of rooms for CandleFlip
 }
 
 var (
-	// Global rooms visible to all clients
+	// Global rooms visible to all clients. Kept in Redis when it is
+	// available, so every instance lists the same rooms; this map is the
+	// fallback for a single instance without Redis.
 	globalRooms      = make(map[string]*RoomInfo)
 	globalRoomsMutex sync.RWMutex
 
 	// Clients subscribed to room updates
-	globalRoomClients      = make(map[*websocket.Conn]bool)
+	globalRoomClients      = make(map[*wsConn]bool)
 	globalRoomClientsMutex sync.RWMutex
 
 	// Bot names for candleflip opponents
@@ -48,16 +54,9 @@ var (
 
 // BroadcastRoomUpdate sends room list to all subscribed clients via unified broadcast
 func BroadcastRoomUpdate() {
-	globalRoomsMutex.RLock()
-	rooms := make([]*RoomInfo, 0, len(globalRooms))
-	for _, room := range globalRooms {
-		rooms = append(rooms, room)
-	}
-	globalRoomsMutex.RUnlock()
-
 	message := map[string]interface{}{
 		"type":  "rooms_update",
-		"rooms": rooms,
+		"rooms": listRooms(),
 	}
 
 	// Send to unified broadcast channel instead of direct writes
@@ -95,9 +94,7 @@ func CreateRoom(roomID, gameType string, betAmount float64, trend string) *RoomI
 		MaxPlayers: maxPlayers,
 	}
 
-	globalRoomsMutex.Lock()
-	globalRooms[roomID] = room
-	globalRoomsMutex.Unlock()
+	saveRoom(room)
 
 	log.Printf("🌍 Created global %s room: %s (max players: %d)", gameType, roomID, maxPlayers)
 	BroadcastRoomUpdate()
@@ -107,31 +104,25 @@ func CreateRoom(roomID, gameType string, betAmount float64, trend string) *RoomI
 
 // UpdateRoomStatus updates a room's status
 func UpdateRoomStatus(roomID, status string) {
-	globalRoomsMutex.Lock()
-	if room, exists := globalRooms[roomID]; exists {
+	updateRoom(roomID, func(room *RoomInfo) {
 		room.Status = status
-	}
-	globalRoomsMutex.Unlock()
+	})
 
 	BroadcastRoomUpdate()
 }
 
 // UpdateRoomPlayers updates player count in a room
 func UpdateRoomPlayers(roomID string, players int) {
-	globalRoomsMutex.Lock()
-	if room, exists := globalRooms[roomID]; exists {
+	updateRoom(roomID, func(room *RoomInfo) {
 		room.Players = players
-	}
-	globalRoomsMutex.Unlock()
+	})
 
 	BroadcastRoomUpdate()
 }
 
 // RemoveRoom removes a room from global list
 func RemoveRoom(roomID string) {
-	globalRoomsMutex.Lock()
-	delete(globalRooms, roomID)
-	globalRoomsMutex.Unlock()
+	deleteRoom(roomID)
 
 	log.Printf("🗑️  Removed global room: %s", roomID)
 	BroadcastRoomUpdate()
@@ -141,7 +132,7 @@ func RemoveRoom(roomID string) {
 func HandleGlobalRoomsWS(w http.ResponseWriter, r *http.Request) {
 	log.Println("🌍 Global rooms WebSocket connection attempt from:", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgradeConn(w, r, nil, "rooms")
 	if err != nil {
 		log.Println("❌ Global rooms WebSocket upgrade failed:", err)
 		return
@@ -156,16 +147,9 @@ func HandleGlobalRoomsWS(w http.ResponseWriter, r *http.Request) {
 	log.Printf("✅ Global rooms client connected. Total: %d", len(globalRoomClients))
 
 	// Send current room list immediately
-	globalRoomsMutex.RLock()
-	rooms := make([]*RoomInfo, 0, len(globalRooms))
-	for _, room := range globalRooms {
-		rooms = append(rooms, room)
-	}
-	globalRoomsMutex.RUnlock()
-
 	if err := conn.WriteJSON(map[string]interface{}{
 		"type":  "rooms_update",
-		"rooms": rooms,
+		"rooms": listRooms(),
 	}); err != nil {
 		log.Printf("❌ Failed to send initial room list: %v", err)
 	}
@@ -209,3 +193,120 @@ func HandleGlobalRoomsWS(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+/* =========================
+   ROOM STORE
+========================= */
+
+// saveRoom adds or replaces a room in the global room list
+func saveRoom(room *RoomInfo) {
+	globalRoomsMutex.Lock()
+	defer globalRoomsMutex.Unlock()
+
+	if db.RedisClient == nil {
+		globalRooms[room.RoomID] = room
+		return
+	}
+	storeRoom(room)
+}
+
+// updateRoom applies update to a room of the global room list, and reports
+// whether the room exists. Updates from this instance are applied in order;
+// with Redis the last writer among instances wins.
+func updateRoom(roomID string, update func(room *RoomInfo)) bool {
+	globalRoomsMutex.Lock()
+	defer globalRoomsMutex.Unlock()
+
+	if db.RedisClient == nil {
+		room, exists := globalRooms[roomID]
+		if exists {
+			update(room)
+		}
+		return exists
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	data, err := db.GetGlobalRoom(ctx, roomID)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+	var room RoomInfo
+	if err := json.Unmarshal(data, &room); err != nil {
+		log.Printf("⚠️ Failed to unmarshal room %s: %v", roomID, err)
+		return false
+	}
+	update(&room)
+	storeRoom(&room)
+	return true
+}
+
+// deleteRoom removes a room from the global room list
+func deleteRoom(roomID string) {
+	globalRoomsMutex.Lock()
+	defer globalRoomsMutex.Unlock()
+
+	if db.RedisClient == nil {
+		delete(globalRooms, roomID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := db.DeleteGlobalRoom(ctx, roomID); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// listRooms returns copies of the rooms in the global room list, oldest first
+func listRooms() []*RoomInfo {
+	rooms := make([]*RoomInfo, 0)
+
+	if db.RedisClient == nil {
+		globalRoomsMutex.RLock()
+		for _, room := range globalRooms {
+			copied := *room
+			rooms = append(rooms, &copied)
+		}
+		globalRoomsMutex.RUnlock()
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		entries, err := db.GetGlobalRooms(ctx)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+		for _, data := range entries {
+			var room RoomInfo
+			if err := json.Unmarshal(data, &room); err != nil {
+				log.Printf("⚠️ Failed to unmarshal room: %v", err)
+				continue
+			}
+			rooms = append(rooms, &room)
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].CreatedAt.Before(rooms[j].CreatedAt) })
+	return rooms
+}
+
+// storeRoom writes a room to Redis. The caller holds globalRoomsMutex.
+func storeRoom(room *RoomInfo) {
+	data, err := json.Marshal(room)
+	if err != nil {
+		log.Printf("❌ Failed to marshal room %s: %v", room.RoomID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := db.StoreGlobalRoom(ctx, room.RoomID, data); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"goLangServer/db"
+)
+
+// Payout kinds in the payout queue
+const (
+	PayoutKindCandleflip  = "candleflip"
+	PayoutKindCrash       = "crash"
+	PayoutKindCrashRefund = "crash_refund" // Stake returned for a round interrupted by a restart
+)
+
+// queueCrashPayout adds a crash cashout to the payout queue
+func queueCrashPayout(cashedOut *db.CrashCashedOutData) {
+	if db.PostgresPool == nil {
+		log.Printf("⚠️ Payout queue unavailable, crash payout for %s not queued", cashedOut.PlayerAddress)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.EnqueuePayout(ctx, PayoutKindCrash, cashedOut.GameID, cashedOut.PlayerAddress, cashedOut.Payout); err != nil {
+		log.Printf("❌ Failed to queue crash payout for %s: %v", cashedOut.PlayerAddress, err)
+	}
+}
+
+// HandlePayoutUpdate applies payout worker state changes to live games and
+// notifies clients. Set as the payout worker's OnUpdate callback.
+func HandlePayoutUpdate(p *db.PayoutRecord) {
+	message := map[string]interface{}{
+		"type": "payout_update",
+		"data": map[string]interface{}{
+			"payoutId":  p.ID,
+			"kind":      p.Kind,
+			"reference": p.Reference,
+			"player":    p.PlayerAddress,
+			"status":    p.Status,
+			"txHash":    p.TxHash,
+			"error":     p.LastError,
+		},
+	}
+
+	if p.Status == db.PayoutStatusConfirmed {
+		invalidateHouseLiquidity()
+	}
+
+	switch p.Kind {
+	case PayoutKindCandleflip:
+		if batch := GetBatch(p.Reference); batch != nil {
+			batch.mu.Lock()
+			batch.PayoutTxHash = p.TxHash
+			switch p.Status {
+			case db.PayoutStatusConfirmed:
+				batch.Status = "paid"
+				batch.PayoutError = ""
+			case db.PayoutStatusFailed:
+				batch.Status = "payout_failed"
+				batch.PayoutError = p.LastError
+			case db.PayoutStatusPending, db.PayoutStatusSent:
+				// Retried from the admin endpoint
+				batch.Status = "paying"
+				batch.PayoutError = ""
+			}
+			batch.mu.Unlock()
+
+			persistBatch(batch)
+		}
+		broadcastToAllCandleflipClients(message)
+
+	case PayoutKindCrash, PayoutKindCrashRefund:
+		publishCrash(message)
+	}
+}
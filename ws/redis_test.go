@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"goLangServer/db"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis answers the Redis commands the game state uses from memory, as a
+// client hook, so tests need no Redis server. Expiry is ignored.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	sets    map[string]map[string]bool
+	lists   map[string][]string
+}
+
+// useFakeRedis points db.RedisClient at a new fakeRedis for the test
+func useFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	f := &fakeRedis{
+		strings: make(map[string]string),
+		sets:    make(map[string]map[string]bool),
+		lists:   make(map[string][]string),
+	}
+	client := redis.NewClient(&redis.Options{Addr: "fake:6379"})
+	client.AddHook(f)
+
+	previous := db.RedisClient
+	db.RedisClient = client
+	t.Cleanup(func() { db.RedisClient = previous })
+	return f
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "blpop" {
+			f.blpop(ctx, cmd.(*redis.StringSliceCmd))
+		} else {
+			f.process(cmd)
+		}
+		return cmd.Err()
+	}
+}
+
+// blpop polls the lists, which commands from other goroutines may fill
+func (f *fakeRedis) blpop(ctx context.Context, cmd *redis.StringSliceCmd) {
+	args := cmd.Args()
+	seconds, _ := strconv.Atoi(fmt.Sprint(args[len(args)-1]))
+	deadline := time.Now().Add(time.Duration(seconds) * time.Second)
+
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		f.mu.Lock()
+		for _, a := range args[1 : len(args)-1] {
+			key := fmt.Sprint(a)
+			if list := f.lists[key]; len(list) > 0 {
+				f.lists[key] = list[1:]
+				f.mu.Unlock()
+				cmd.SetVal([]string{key, list[0]})
+				return
+			}
+		}
+		f.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	cmd.SetErr(redis.Nil)
+}
+
+func (f *fakeRedis) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			f.process(cmd)
+		}
+		return nil
+	}
+}
+
+func (f *fakeRedis) process(cmd redis.Cmder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	args := make([]string, len(cmd.Args()))
+	for i, a := range cmd.Args() {
+		switch v := a.(type) {
+		case []byte:
+			args[i] = string(v)
+		default:
+			args[i] = fmt.Sprint(v)
+		}
+	}
+	name := strings.ToLower(args[0])
+
+	switch name {
+	case "get":
+		if v, ok := f.strings[args[1]]; ok {
+			cmd.(*redis.StringCmd).SetVal(v)
+		} else {
+			cmd.SetErr(redis.Nil)
+		}
+
+	case "set":
+		nx := false
+		for _, opt := range args[3:] {
+			nx = nx || strings.EqualFold(opt, "nx")
+		}
+		if _, exists := f.strings[args[1]]; nx && exists {
+			cmd.SetErr(redis.Nil)
+			return
+		}
+		f.strings[args[1]] = args[2]
+		switch c := cmd.(type) {
+		case *redis.StatusCmd:
+			c.SetVal("OK")
+		case *redis.BoolCmd:
+			c.SetVal(true)
+		}
+
+	case "setnx":
+		_, exists := f.strings[args[1]]
+		if !exists {
+			f.strings[args[1]] = args[2]
+		}
+		cmd.(*redis.BoolCmd).SetVal(!exists)
+
+	case "del":
+		n := int64(0)
+		for _, key := range args[1:] {
+			if _, ok := f.strings[key]; ok {
+				n++
+			}
+			delete(f.strings, key)
+			delete(f.sets, key)
+			delete(f.lists, key)
+		}
+		cmd.(*redis.IntCmd).SetVal(n)
+
+	case "sadd", "srem":
+		set := f.sets[args[1]]
+		if set == nil {
+			set = make(map[string]bool)
+			f.sets[args[1]] = set
+		}
+		n := int64(0)
+		for _, member := range args[2:] {
+			if set[member] != (name == "sadd") {
+				n++
+			}
+			if name == "sadd" {
+				set[member] = true
+			} else {
+				delete(set, member)
+			}
+		}
+		cmd.(*redis.IntCmd).SetVal(n)
+
+	case "smembers":
+		members := []string{}
+		for member := range f.sets[args[1]] {
+			members = append(members, member)
+		}
+		cmd.(*redis.StringSliceCmd).SetVal(members)
+
+	case "expire", "pexpire":
+		cmd.(*redis.BoolCmd).SetVal(true)
+
+	case "rpush":
+		f.lists[args[1]] = append(f.lists[args[1]], args[2:]...)
+		cmd.(*redis.IntCmd).SetVal(int64(len(f.lists[args[1]])))
+
+	case "lrange":
+		list := f.lists[args[1]]
+		start, _ := strconv.Atoi(args[2])
+		stop, _ := strconv.Atoi(args[3])
+		if stop < 0 {
+			stop += len(list)
+		}
+		if stop >= len(list) {
+			stop = len(list) - 1
+		}
+		out := []string{}
+		for i := start; i <= stop; i++ {
+			out = append(out, list[i])
+		}
+		cmd.(*redis.StringSliceCmd).SetVal(out)
+
+	default:
+		cmd.SetErr(fmt.Errorf("fakeRedis: unsupported command %s", name))
+	}
+}
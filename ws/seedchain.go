@@ -0,0 +1,175 @@
+package ws
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"goLangServer/crypto"
+	"goLangServer/db"
+)
+
+// SeedChainInfo is the public description of the active crash seed chain
+type SeedChainInfo struct {
+	TerminatingHash string    `json:"terminatingHash"`
+	ChainLength     uint64    `json:"chainLength"`
+	RoundsUsed      uint64    `json:"roundsUsed"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// crashSeed is the pre-committed seed of a single crash round
+type crashSeed struct {
+	Seed            string
+	SeedHash        string
+	ChainRound      uint64
+	TerminatingHash string
+}
+
+var (
+	seedChain       *crypto.HashChain
+	seedChainRecord *db.SeedChainRecord
+	seedChainMutex  sync.Mutex
+	seedChainReady  = make(chan struct{})
+	seedChainOnce   sync.Once
+)
+
+// InitSeedChain loads the active crash seed chain from PostgreSQL, generating
+// and storing a new one if none is usable, and publishes its terminating hash.
+// The crash loop waits for this before starting its first round.
+func InitSeedChain() {
+	seedChainMutex.Lock()
+	defer seedChainMutex.Unlock()
+
+	if err := loadOrCreateSeedChain(); err != nil {
+		log.Printf("⚠️  Crash rounds paused until a seed chain is committed: %v", err)
+	}
+
+	seedChainOnce.Do(func() { close(seedChainReady) })
+}
+
+// loadOrCreateSeedChain sets up seedChain. A chain is only used once its
+// commitment and sealed root are stored, so rounds stay verifiable across
+// restarts. Must be called with seedChainMutex held.
+func loadOrCreateSeedChain() error {
+	seedChain, seedChainRecord = nil, nil
+
+	if db.PostgresPool == nil {
+		return fmt.Errorf("PostgreSQL unavailable, the seed chain cannot be committed")
+	}
+	key, err := seedChainKey()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	record, err := db.GetActiveSeedChain(ctx)
+	if err != nil {
+		return err
+	}
+
+	if record != nil {
+		root, err := crypto.OpenChainRoot(key, record.SealedRoot, record.TerminatingHash)
+		if err != nil {
+			log.Printf("⚠️  Stored seed chain #%d cannot be opened, replacing it: %v", record.ID, err)
+		} else {
+			log.Printf("⛓️  Rebuilding seed chain #%d (%d links)...", record.ID, record.ChainLength)
+			chain := crypto.NewHashChain(root, record.ChainLength)
+			if chain.TerminatingHash() == record.TerminatingHash {
+				seedChain, seedChainRecord = chain, record
+			} else {
+				log.Printf("⚠️  Stored seed chain #%d does not match its terminating hash, replacing it", record.ID)
+			}
+		}
+	}
+
+	if seedChainRecord == nil {
+		log.Printf("⛓️  Generating new seed chain (%d links)...", serverConfig.Crash.SeedChainLength)
+		root := crypto.GenerateChainRoot()
+		chain := crypto.NewHashChain(root, serverConfig.Crash.SeedChainLength)
+
+		sealed, err := crypto.SealChainRoot(key, root, chain.TerminatingHash())
+		if err != nil {
+			return err
+		}
+		record = &db.SeedChainRecord{
+			SealedRoot:      sealed,
+			ChainLength:     chain.Length(),
+			TerminatingHash: chain.TerminatingHash(),
+			CreatedAt:       time.Now(),
+		}
+		if err := db.StoreSeedChain(ctx, record); err != nil {
+			return err
+		}
+		seedChain, seedChainRecord = chain, record
+	}
+
+	log.Printf("🔒 Crash seed chain terminating hash: %s (%d/%d rounds used)",
+		seedChainRecord.TerminatingHash, seedChainRecord.RoundsUsed, seedChainRecord.ChainLength)
+	return nil
+}
+
+// seedChainKey decodes the key that seals stored chain roots
+func seedChainKey() ([]byte, error) {
+	key := strings.TrimPrefix(serverConfig.Crash.SeedChainKey, "0x")
+	if key == "" {
+		return nil, fmt.Errorf("crash.seedChainKey is not set, the seed chain root cannot be stored")
+	}
+	return hex.DecodeString(key)
+}
+
+// nextCrashSeed claims the next round of the seed chain
+func nextCrashSeed() (*crashSeed, error) {
+	<-seedChainReady
+
+	seedChainMutex.Lock()
+	defer seedChainMutex.Unlock()
+
+	if seedChain == nil || seedChainRecord == nil || seedChainRecord.RoundsUsed >= seedChainRecord.ChainLength {
+		if err := loadOrCreateSeedChain(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	round, err := db.UseSeedChainRound(ctx, seedChainRecord.ID)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	seedChainRecord.RoundsUsed = round
+
+	seed, err := seedChain.Seed(round)
+	if err != nil {
+		return nil, err
+	}
+
+	return &crashSeed{
+		Seed:            seed,
+		SeedHash:        crypto.HashSeed(seed),
+		ChainRound:      round,
+		TerminatingHash: seedChainRecord.TerminatingHash,
+	}, nil
+}
+
+// GetSeedChainInfo returns the public commitment of the active seed chain
+func GetSeedChainInfo() (*SeedChainInfo, bool) {
+	seedChainMutex.Lock()
+	defer seedChainMutex.Unlock()
+
+	if seedChainRecord == nil {
+		return nil, false
+	}
+
+	return &SeedChainInfo{
+		TerminatingHash: seedChainRecord.TerminatingHash,
+		ChainLength:     seedChainRecord.ChainLength,
+		RoundsUsed:      seedChainRecord.RoundsUsed,
+		CreatedAt:       seedChainRecord.CreatedAt,
+	}, true
+}
@@ -9,16 +9,27 @@ import (
 	"sync/atomic"
 	"time"
 
+	"goLangServer/wager"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/websocket"
 )
 
 // ClientConnection represents a connected client with their subscriptions
 type ClientConnection struct {
 	ID            string
-	Conn          *websocket.Conn
-	Subscriptions map[string]bool // crash, chat, rooms, candleflip:<roomId>
+	Conn          *wsConn
+	Subscriptions map[string]int // Protocol version per channel: crash, chat, rooms, candleflip:<roomId>
+	Encoding      string         // Wire encoding negotiated at upgrade, encodingJSON or encodingMsgpack
 	mu            sync.RWMutex
 	Send          chan []byte
+
+	// Guards Send against sends after close, and the close frame writePump
+	// sends once Send is closed (code 0 sends none)
+	sendMu      sync.Mutex
+	closed      bool
+	closeCode   int
+	closeReason string
 }
 
 var (
@@ -27,17 +38,18 @@ var (
 	clientsMutex sync.RWMutex
 
 	// Channels for different event types
-	crashBroadcast   = make(chan interface{}, 100)
-	chatBroadcastCh  = make(chan interface{}, 100)
-	roomsBroadcast   = make(chan interface{}, 100)
+	crashBroadcast   = make(chan hubMessage, 100)
+	chatBroadcastCh  = make(chan map[string]interface{}, 100)
+	roomsBroadcast   = make(chan map[string]interface{}, 100)
 	clientRegister   = make(chan *ClientConnection)
 	clientUnregister = make(chan *ClientConnection)
 
 	// Client ID counter
 	clientIDCounter int64
 
-	// Chat ring buffer (FIFO, max 100 messages)
-	chatHistory      []interface{}
+	// Chat ring buffer (FIFO, max 100 messages), stored with sequence numbers.
+	// Only used without Redis: otherwise the history is kept in Redis.
+	chatHistory      []map[string]interface{}
 	chatHistoryMutex sync.RWMutex
 	maxChatHistory   = 100
 )
@@ -57,6 +69,11 @@ func init() {
 func runEventHub() {
 	log.Println("🚀 Unified Event Hub started")
 
+	// Seed the state replayed to subscribers before the first broadcasts
+	seedChannel("crash", map[string]interface{}{"type": "crash_history", "history": []CrashGameHistory{}})
+	seedChannel("crash", map[string]interface{}{"type": "active_bettors", "bettors": []*ActiveBettor{}, "count": 0})
+	seedChannel("rooms", map[string]interface{}{"type": "rooms_update", "rooms": []*RoomInfo{}})
+
 	for {
 		select {
 		case client := <-clientRegister:
@@ -69,39 +86,62 @@ func runEventHub() {
 			clientsMutex.Lock()
 			if _, ok := clients[client]; ok {
 				delete(clients, client)
-				close(client.Send)
+				client.close(0, "")
 			}
 			clientsMutex.Unlock()
 			log.Printf("👋 Client unregistered: %s (Total: %d)", client.ID, len(clients))
 
+		case req := <-hubRequests:
+			handleHubRequest(req)
+
 		case message := <-crashBroadcast:
-			broadcastToSubscribers("crash", message)
+			dispatchHubMessage("crash", message.message, message.variants)
 
 		case message := <-chatBroadcastCh:
-			// Add to chat history ring buffer
-			chatHistoryMutex.Lock()
-			chatHistory = append(chatHistory, message)
-			if len(chatHistory) > maxChatHistory {
-				// Remove oldest message (FIFO)
-				chatHistory = chatHistory[1:]
-			}
-			chatHistoryMutex.Unlock()
-
-			// Broadcast to all chat subscribers
-			broadcastToSubscribers("chat", message)
+			dispatchHubMessage("chat", message, nil)
 
 		case message := <-roomsBroadcast:
-			broadcastToSubscribers("rooms", message)
+			dispatchHubMessage("rooms", message, nil)
+
+		case message := <-candleflipBroadcast:
+			dispatchHubMessage(candleflipChannel(message), message, nil)
+
+		case message := <-hubIncoming:
+			if message.channel == "chat" && message.seq <= channelSeq("chat") {
+				continue // Already replayed from the shared history
+			}
+			deliverHubMessage(message.channel, message.seq, message.message, message.variants)
 		}
 	}
 }
 
-// broadcastToSubscribers sends message to all clients subscribed to a channel
-func broadcastToSubscribers(channel string, message interface{}) {
-	data, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("❌ Failed to marshal message for %s: %v", channel, err)
-		return
+// broadcastToSubscribers stamps message with a sequence number of the
+// channel, as stampMessage does, and sends it to all clients subscribed to the channel, or its
+// variant for their protocol version. Each form is encoded once per wire
+// encoding. Clients too far behind to take it are disconnected. Returns the
+// stamped message.
+func broadcastToSubscribers(channel string, seq uint64, message map[string]interface{}, variants map[int]map[string]interface{}) map[string]interface{} {
+	stamped := stampMessage(channel, seq, message)
+
+	type form struct {
+		protocol int
+		encoding string
+	}
+	encoded := make(map[form][]byte)
+	encode := func(f form) []byte {
+		if data, ok := encoded[f]; ok {
+			return data
+		}
+		out := stamped
+		if variant, ok := variants[f.protocol]; ok {
+			out = stampVariant(stamped, variant)
+		}
+		data, err := encodeMessage(f.encoding, out)
+		if err != nil {
+			log.Printf("❌ Failed to encode message for %s (protocol %d, %s): %v", channel, f.protocol, f.encoding, err)
+		}
+		encoded[f] = data
+		return data
 	}
 
 	clientsMutex.RLock()
@@ -109,25 +149,69 @@ func broadcastToSubscribers(channel string, message interface{}) {
 
 	for client := range clients {
 		client.mu.RLock()
-		subscribed := client.Subscriptions[channel]
+		protocol := client.Subscriptions[channel]
 		client.mu.RUnlock()
 
-		if subscribed {
-			select {
-			case client.Send <- data:
-			default:
-				// Client's send channel is full, skip
-				log.Printf("⚠️  Client %s send buffer full, skipping message", client.ID)
-			}
+		if protocol == 0 {
+			continue
 		}
+		if data := encode(form{protocol, client.Encoding}); data != nil {
+			client.send(data)
+		}
+	}
+	return stamped
+}
+
+// send queues data for the client without blocking. A client whose send
+// buffer is full has fallen too far behind to catch up and is disconnected
+// with a close reason, rather than silently missing messages.
+func (c *ClientConnection) send(data []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return false
 	}
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		log.Printf("⚠️ Client %s fell %d messages behind, disconnecting", c.ID, len(c.Send))
+		c.Conn.dropped()
+		c.closeLocked(websocket.CloseTryAgainLater, "too slow: send buffer full, reconnect and resync")
+		return false
+	}
+}
+
+// close stops sending to the client. writePump sends a close frame with the
+// given code and reason, if code is not 0, and closes the connection.
+func (c *ClientConnection) close(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.closeLocked(code, reason)
+}
+
+func (c *ClientConnection) closeLocked(code int, reason string) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode, c.closeReason = code, reason
+	close(c.Send)
 }
 
 // HandleUnifiedWS is the single WebSocket endpoint
 func HandleUnifiedWS(w http.ResponseWriter, r *http.Request) {
 	log.Println("📥 Unified WebSocket connection from:", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Confirm the wire encoding the client asked for, if the server speaks it
+	encoding, ok := negotiateEncoding(websocket.Subprotocols(r))
+	var header http.Header
+	if ok {
+		header = http.Header{"Sec-Websocket-Protocol": {encoding}}
+	}
+
+	conn, err := upgradeConn(w, r, header, "unified")
 	if err != nil {
 		log.Println("❌ WebSocket upgrade failed:", err)
 		return
@@ -137,8 +221,9 @@ func HandleUnifiedWS(w http.ResponseWriter, r *http.Request) {
 	client := &ClientConnection{
 		ID:            generateClientID(),
 		Conn:          conn,
-		Subscriptions: make(map[string]bool),
-		Send:          make(chan []byte, 256),
+		Subscriptions: make(map[string]int),
+		Encoding:      encoding,
+		Send:          make(chan []byte, serverConfig.WebSocket.SendBufferSize),
 	}
 
 	// Register client
@@ -156,11 +241,19 @@ func (c *ClientConnection) writePump() {
 	}()
 
 	for message := range c.Send {
-		if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		if err := c.Conn.WriteMessage(frameType(c.Encoding), message); err != nil {
 			log.Printf("❌ Write error for client %s: %v", c.ID, err)
 			return
 		}
 	}
+
+	// Send was closed: tell the client why before dropping the connection
+	c.sendMu.Lock()
+	code, reason := c.closeCode, c.closeReason
+	c.sendMu.Unlock()
+	if code != 0 {
+		c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
 }
 
 // readPump reads messages from the WebSocket and handles subscriptions/requests
@@ -192,15 +285,14 @@ func (c *ClientConnection) readPump() {
 // handleMessage processes incoming client messages
 func (c *ClientConnection) handleMessage(msg ClientMessage) {
 	switch msg.Type {
-	case "subscribe":
-		channel := msg.Data["channel"].(string)
-		c.mu.Lock()
-		c.Subscriptions[channel] = true
-		c.mu.Unlock()
-		log.Printf("📡 Client %s subscribed to: %s", c.ID, channel)
-
-		// Send initial data for the channel
-		c.sendInitialData(channel)
+	case "subscribe", "resync":
+		channel, _ := msg.Data["channel"].(string)
+		if channel == "" {
+			log.Printf("⚠️  Client %s sent %s without a channel", c.ID, msg.Type)
+			return
+		}
+		protocol, _ := msg.Data["protocol"].(float64)
+		hubRequests <- hubRequest{client: c, channel: channel, protocol: int(protocol), resync: msg.Type == "resync"}
 
 	case "unsubscribe":
 		channel := msg.Data["channel"].(string)
@@ -210,7 +302,7 @@ func (c *ClientConnection) handleMessage(msg ClientMessage) {
 		log.Printf("📴 Client %s unsubscribed from: %s", c.ID, channel)
 
 	case "create_room":
-		handleCreateRoom(msg.Data)
+		handleCreateRoom(c, msg.Data)
 
 	case "chat_message":
 		handleChatMessage(c, msg.Data)
@@ -219,70 +311,22 @@ func (c *ClientConnection) handleMessage(msg ClientMessage) {
 		roomID := msg.Data["roomId"].(string)
 		handleJoinCandleflipRoom(c, roomID)
 
-	default:
-		log.Printf("⚠️  Unknown message type from client %s: %s", c.ID, msg.Type)
-	}
-}
-
-// sendInitialData sends current state when client subscribes to a channel
-func (c *ClientConnection) sendInitialData(channel string) {
-	switch channel {
-	case "crash":
-		// Send crash game history
-		history := getCrashGameHistory()
+	case "place_bet":
+		handlePlaceBetMessage(c, msg.Data)
 
-		data, _ := json.Marshal(map[string]interface{}{
-			"type":    "crash_history",
-			"history": history,
-		})
-		c.Send <- data
-
-		// Send current active bettors
-		bettors := GetActiveBettors()
-		bettorData, _ := json.Marshal(map[string]interface{}{
-			"type":    "active_bettors",
-			"bettors": bettors,
-			"count":   len(bettors),
-		})
-		c.Send <- bettorData
-
-	case "rooms":
-		// Send current room list
-		globalRoomsMutex.RLock()
-		rooms := make([]*RoomInfo, 0, len(globalRooms))
-		for _, room := range globalRooms {
-			rooms = append(rooms, room)
-		}
-		globalRoomsMutex.RUnlock()
+	case "cash_out":
+		handleCashOutMessage(c, msg.Data)
 
-		data, _ := json.Marshal(map[string]interface{}{
-			"type":  "rooms_update",
-			"rooms": rooms,
-		})
-		c.Send <- data
-
-	case "chat":
-		// Send chat history to new client
-		chatHistoryMutex.RLock()
-		history := make([]interface{}, len(chatHistory))
-		copy(history, chatHistory)
-		chatHistoryMutex.RUnlock()
-
-		// Send each message individually to maintain order
-		for _, msg := range history {
-			data, _ := json.Marshal(msg)
-			c.Send <- data
-		}
-
-		log.Printf("📨 Client %s joined chat (sent %d history messages)", c.ID, len(history))
+	default:
+		log.Printf("⚠️  Unknown message type from client %s: %s", c.ID, msg.Type)
 	}
 }
 
 // Helper functions
-func handleCreateRoom(data map[string]interface{}) {
-	roomID := data["roomId"].(string)
-	gameType := data["gameType"].(string)
-	betAmount := data["betAmount"].(float64)
+func handleCreateRoom(c *ClientConnection, data map[string]interface{}) {
+	roomID, _ := data["roomId"].(string)
+	gameType, _ := data["gameType"].(string)
+	betAmount, _ := data["betAmount"].(float64)
 	creatorId := ""
 	if id, ok := data["creatorId"].(string); ok {
 		creatorId = id
@@ -304,12 +348,20 @@ func handleCreateRoom(data map[string]interface{}) {
 		roomsCount = int(count)
 	}
 
+	if err := validateCreateRoom(roomID, gameType, betAmount, creatorId, trend, roomsCount); err != nil {
+		log.Printf("⚠️ Client %s room rejected: %v", c.ID, err)
+		c.sendMessage(errorReply("room_error", err))
+		return
+	}
+	if creatorId != "" {
+		creatorId = common.HexToAddress(creatorId).Hex()
+	}
+
 	CreateRoom(roomID, gameType, betAmount, trend)
 
 	// For candleflip, assign player vs bot and start game
 	if gameType == "candleflip" && creatorId != "" {
-		globalRoomsMutex.Lock()
-		if globalRoom, exists := globalRooms[roomID]; exists {
+		updateRoom(roomID, func(globalRoom *RoomInfo) {
 			globalRoom.CreatorId = creatorId
 			globalRoom.Players = 1
 			globalRoom.ContractGameID = contractGameId
@@ -329,8 +381,7 @@ func handleCreateRoom(data map[string]interface{}) {
 
 			// Mark room as ready to start
 			globalRoom.Status = "active"
-		}
-		globalRoomsMutex.Unlock()
+		})
 		BroadcastRoomUpdate()
 		log.Printf("🎮 Candleflip room %s created by %s vs Bot '%s' (player side: %s, contractGameId: %s)",
 			roomID, creatorId, GetBotName(botNameSeed), trend, contractGameId)
@@ -340,6 +391,34 @@ func handleCreateRoom(data map[string]interface{}) {
 	}
 }
 
+// validateCreateRoom checks a create_room message against the bet limits.
+// Candleflip rooms are the player's wager, so they also need the player's
+// address, a side and a room count.
+func validateCreateRoom(roomID, gameType string, betAmount float64, creatorId, trend string, roomsCount int) error {
+	if roomID == "" {
+		return fmt.Errorf("roomId is required")
+	}
+	if err := wager.GameType("gameType", gameType); err != nil {
+		return err
+	}
+	if err := wagers.AmountMNT("betAmount", betAmount); err != nil {
+		return err
+	}
+	if gameType != "candleflip" {
+		return nil
+	}
+
+	if creatorId != "" {
+		if _, err := wagers.Address("creatorId", creatorId); err != nil {
+			return err
+		}
+	}
+	if err := wager.Side("trend", trend, "bullish", "bearish"); err != nil {
+		return err
+	}
+	return wagers.Rooms("roomsCount", roomsCount)
+}
+
 func handleChatMessage(client *ClientConnection, data map[string]interface{}) {
 	message := data["message"].(string)
 
@@ -363,10 +442,7 @@ func min(a, b int) int {
 
 func handleJoinCandleflipRoom(client *ClientConnection, roomID string) {
 	// Subscribe client to specific candleflip room updates (for spectating)
-	channel := "candleflip:" + roomID
-	client.mu.Lock()
-	client.Subscriptions[channel] = true
-	client.mu.Unlock()
+	hubRequests <- hubRequest{client: client, channel: "candleflip:" + roomID}
 
 	log.Printf("🎮 Client %s subscribed to Candleflip room: %s (spectator/player)", client.ID, roomID)
 }
@@ -1,27 +1,40 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"goLangServer/crypto"
+	"goLangServer/db"
 	"goLangServer/game"
 	"log"
 	"net/http"
 )
 
 type VerifyRequest struct {
-	ServerSeed     string `json:"serverSeed"`
-	ServerSeedHash string `json:"serverSeedHash"`
-	GameID         string `json:"gameId"`
+	ServerSeed      string                  `json:"serverSeed"`
+	ServerSeedHash  string                  `json:"serverSeedHash"`
+	GameID          string                  `json:"gameId"`
+	ClientSeed      string                  `json:"clientSeed,omitempty"`   // Round client seed, mixed in with the chain round as nonce
+	RNGVersion      int                     `json:"rngVersion,omitempty"`   // Defaults to the current RNG
+	Distribution    *game.CrashDistribution `json:"distribution,omitempty"` // Defaults to the configured distribution, {} for legacy rounds
+	ChainRound      uint64                  `json:"chainRound,omitempty"`   // Optional hash chain position of the seed
+	TerminatingHash string                  `json:"terminatingHash,omitempty"`
 }
 
 type VerifyResponse struct {
 	Valid          bool    `json:"valid"`
 	PeakMultiplier float64 `json:"peakMultiplier,omitempty"`
+	Rugged         bool    `json:"rugged"`
+	TotalTicks     int     `json:"totalTicks,omitempty"`
+	ChainVerified  bool    `json:"chainVerified,omitempty"`
 	Error          string  `json:"error,omitempty"`
 }
 
 // HandleVerifyGame verifies a game result by recalculating the peak multiplier
-// from the server seed and game ID
+// from the server seed and game ID. Crash rounds are shared by every player,
+// so they are seeded from the crash seed chain mixed with the combined client
+// seed of the round's players, rather than from one player's seed pair.
 func HandleVerifyGame(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -68,13 +81,78 @@ func HandleVerifyGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate the peak multiplier
-	peak := game.VerifyGamePeak(req.ServerSeed, req.GameID)
+	// Chain seeded rounds must hash to the stored seed of the round before
+	// them, or to the terminating hash for round 1. One hash per request: the
+	// earlier rounds were checked the same way when they were revealed.
+	chainVerified := false
+	if req.ChainRound > 0 {
+		previousSeed, err := previousChainSeed(r.Context(), req.TerminatingHash, req.ChainRound)
+		if err != nil {
+			json.NewEncoder(w).Encode(VerifyResponse{
+				Valid: false,
+				Error: "Cannot verify the seed chain: " + err.Error(),
+			})
+			return
+		}
+		if !crypto.VerifyChainLink(req.ServerSeed, req.ChainRound, previousSeed, req.TerminatingHash) {
+			json.NewEncoder(w).Encode(VerifyResponse{
+				Valid: false,
+				Error: "Server seed does not hash to the previous round of the chain",
+			})
+			return
+		}
+		chainVerified = true
+	}
+
+	rngVersion := req.RNGVersion
+	if rngVersion == 0 {
+		rngVersion = game.CurrentRNGVersion
+	}
+
+	dist := serverConfig.Crash.Distribution
+	if req.Distribution != nil {
+		dist = *req.Distribution
+	}
+	if err := dist.Validate(); err != nil {
+		json.NewEncoder(w).Encode(VerifyResponse{
+			Valid: false,
+			Error: err.Error(),
+		})
+		return
+	}
+
+	// Replay the round with the same simulator the live loop uses
+	roundSeed := crypto.CrashRoundSeed(req.ServerSeed, req.ClientSeed, req.ChainRound)
+	result := game.CalculateGameVersion(rngVersion, dist, roundSeed, req.GameID)
 
-	log.Printf("✅ Game verified - GameID: %s, Peak: %.2fx", req.GameID, peak)
+	log.Printf("✅ Game verified - GameID: %s, Peak: %.2fx", req.GameID, result.PeakMultiplier)
 
 	json.NewEncoder(w).Encode(VerifyResponse{
 		Valid:          true,
-		PeakMultiplier: peak,
+		PeakMultiplier: result.PeakMultiplier,
+		Rugged:         result.Rugged,
+		TotalTicks:     result.TotalTicks,
+		ChainVerified:  chainVerified,
 	})
 }
+
+// previousChainSeed returns the stored seed of the round before round, which
+// its seed must hash to. Round 1 hashes to the terminating hash instead.
+func previousChainSeed(ctx context.Context, terminatingHash string, round uint64) (string, error) {
+	if round <= 1 {
+		return "", nil
+	}
+	if db.PostgresPool == nil {
+		return "", fmt.Errorf("round history unavailable")
+	}
+
+	seed, err := db.GetChainRoundSeed(ctx, terminatingHash, round-1)
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return "", fmt.Errorf("failed to load chain round %d", round-1)
+	}
+	if seed == "" {
+		return "", fmt.Errorf("chain round %d has not been revealed", round-1)
+	}
+	return seed, nil
+}
@@ -0,0 +1,51 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goLangServer/crypto"
+)
+
+func postVerify(t *testing.T, body string) VerifyResponse {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	HandleVerifyGame(rec, httptest.NewRequest(http.MethodPost, "/api/verify", strings.NewReader(body)))
+
+	var resp VerifyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// TestVerifyChecksOneChainLink checks a chain round is verified against the
+// round before it rather than hashed back to the terminating hash
+func TestVerifyChecksOneChainLink(t *testing.T) {
+	chain := crypto.NewHashChain("verify-chain-root", 10)
+	first, _ := chain.Seed(1)
+
+	body := `{"serverSeed":"` + first + `","serverSeedHash":"` + crypto.HashSeed(first) +
+		`","gameId":"20260101-000000.000","chainRound":1,"terminatingHash":"` + chain.TerminatingHash() + `"}`
+	if resp := postVerify(t, body); !resp.Valid || !resp.ChainVerified {
+		t.Errorf("round 1 response = %+v", resp)
+	}
+
+	body = `{"serverSeed":"` + first + `","serverSeedHash":"` + crypto.HashSeed(first) +
+		`","gameId":"20260101-000000.000","chainRound":1,"terminatingHash":"00"}`
+	if resp := postVerify(t, body); resp.Valid {
+		t.Errorf("wrong terminating hash response = %+v", resp)
+	}
+
+	// Later rounds need the stored previous round, which there is none of here.
+	// A huge round must not be walked back to the terminating hash.
+	body = `{"serverSeed":"` + first + `","serverSeedHash":"` + crypto.HashSeed(first) +
+		`","gameId":"20260101-000000.000","chainRound":18446744073709551615,"terminatingHash":"` + chain.TerminatingHash() + `"}`
+	if resp := postVerify(t, body); resp.Valid || !strings.Contains(resp.Error, "round history unavailable") {
+		t.Errorf("huge round response = %+v", resp)
+	}
+}
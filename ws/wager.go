@@ -0,0 +1,35 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"goLangServer/wager"
+)
+
+// errorReply builds a WebSocket error message of the given type. Rejected
+// wagers also carry their error code and the offending field.
+func errorReply(msgType string, err error) map[string]interface{} {
+	reply := map[string]interface{}{
+		"type":  msgType,
+		"error": err.Error(),
+	}
+	var werr *wager.Error
+	if errors.As(err, &werr) {
+		reply["code"] = werr.Code
+		reply["field"] = werr.Field
+	}
+	return reply
+}
+
+// sendWagerError sends an HTTP error like sendJSONError, adding the error
+// code and field when err is a rejected wager
+func sendWagerError(w http.ResponseWriter, err error, status int) {
+	reply := errorReply("", err)
+	delete(reply, "type")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(reply)
+}
@@ -0,0 +1,305 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"goLangServer/game"
+	"goLangServer/msgpack"
+
+	"github.com/gorilla/websocket"
+)
+
+// Clients on /ws choose the wire encoding with the Sec-WebSocket-Protocol
+// header. Without one, or with none the server speaks, they get JSON.
+//
+// A msgpack client gets every message as a binary frame holding the
+// MessagePack array [type, channel, seq, body]; channel and seq are nil on
+// replies that are not hub channel messages. The body of a message with a
+// schema below is the fixed array the schema lists. The body of any other
+// message is the map of its fields other than type, channel and seq, as in
+// JSON, and the body of a snapshot is the array of its messages as frames.
+//
+//	price_update  [gameId, tick, price, previousCandles, currentCandle]
+//	price_tick    [gameId, tick, price, time, completedCandle|nil, merged, newCandleDurationMs]
+//	game_end      [gameId, roundId, rngVersion, serverSeed, serverSeedHash, chainRound,
+//	               terminatingHash, peakMultiplier, rugged, totalTicks, previousCandles, distribution]
+//	rooms_update  [room...]
+//	chat_message  [username, message, userId, timestamp]
+//
+//	candle        [open, close|nil, max, min, startTime, durationMs, isComplete, valueList]
+//	room          [roomId, gameType, betAmount, trend, status, createdAt (unix ms), players,
+//	               creatorId, botName, bearSide, bullSide, maxPlayers, contractGameId, roomsCount]
+const (
+	encodingJSON    = "json"
+	encodingMsgpack = "msgpack"
+)
+
+// negotiateEncoding returns the first encoding offered that the server speaks,
+// and whether one was found
+func negotiateEncoding(offered []string) (string, bool) {
+	for _, encoding := range offered {
+		if encoding == encodingJSON || encoding == encodingMsgpack {
+			return encoding, true
+		}
+	}
+	return encodingJSON, false
+}
+
+// encodeMessage encodes a message for clients using the given encoding
+func encodeMessage(encoding string, message interface{}) ([]byte, error) {
+	if encoding != encodingMsgpack {
+		return json.Marshal(message)
+	}
+	if m, ok := message.(map[string]interface{}); ok {
+		return appendFrame(nil, m)
+	}
+	return msgpack.AppendValue(nil, message)
+}
+
+// frameType is the WebSocket message type frames of an encoding are sent as
+func frameType(encoding string) int {
+	if encoding == encodingMsgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+/* =========================
+   MSGPACK FRAMES
+========================= */
+
+// appendFrame appends a message as a msgpack frame
+func appendFrame(b []byte, message map[string]interface{}) ([]byte, error) {
+	msgType, _ := message["type"].(string)
+
+	b = msgpack.AppendArrayHeader(b, 4)
+	b = msgpack.AppendString(b, msgType)
+	b, _ = msgpack.AppendValue(b, message["channel"])
+	b, _ = msgpack.AppendValue(b, message["seq"])
+
+	switch msgType {
+	case "snapshot":
+		if messages, ok := message["messages"].([]map[string]interface{}); ok {
+			b = msgpack.AppendArrayHeader(b, len(messages))
+			for _, m := range messages {
+				var err error
+				if b, err = appendFrame(b, m); err != nil {
+					return nil, err
+				}
+			}
+			return b, nil
+		}
+
+	case "rooms_update":
+		if rooms, ok := message["rooms"].([]*RoomInfo); ok {
+			b = msgpack.AppendArrayHeader(b, len(rooms))
+			for _, room := range rooms {
+				b = room.AppendMsgpack(b)
+			}
+			return b, nil
+		}
+
+	case "chat_message":
+		if chat, ok := chatFromMessage(message); ok {
+			return chat.AppendMsgpack(b), nil
+		}
+	}
+
+	if data, ok := message["data"].(msgpack.Marshaler); ok {
+		return data.AppendMsgpack(b), nil
+	}
+
+	// No schema: the remaining fields as a map
+	body := make(map[string]interface{}, len(message))
+	for k, v := range message {
+		switch k {
+		case "type", "channel", "seq":
+		default:
+			body[k] = v
+		}
+	}
+	b, err := msgpack.AppendValue(b, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", msgType, err)
+	}
+	return b, nil
+}
+
+/* =========================
+   SCHEMAS
+========================= */
+
+// PriceUpdate is the data of a price_update, carrying every candle of the round
+type PriceUpdate struct {
+	GameID          string             `json:"gameId"`
+	Tick            int                `json:"tick"`
+	Price           float64            `json:"price"`
+	Multiplier      float64            `json:"multiplier"`
+	GameEnded       bool               `json:"gameEnded"`
+	PreviousCandles []game.CandleGroup `json:"previousCandles"`
+	CurrentCandle   game.CandleGroup   `json:"currentCandle"`
+}
+
+// AppendMsgpack implements msgpack.Marshaler. Multiplier always equals
+// Price and GameEnded is always false, so neither is sent.
+func (p *PriceUpdate) AppendMsgpack(b []byte) []byte {
+	b = msgpack.AppendArrayHeader(b, 5)
+	b = msgpack.AppendString(b, p.GameID)
+	b = msgpack.AppendInt(b, int64(p.Tick))
+	b = msgpack.AppendFloat(b, p.Price)
+	b = appendCandles(b, p.PreviousCandles)
+	return appendCandle(b, &p.CurrentCandle)
+}
+
+// PriceTick is the data of a price_tick, the delta protocol form of a
+// price_update
+type PriceTick struct {
+	GameID              string            `json:"gameId"`
+	Tick                int               `json:"tick"`
+	Price               float64           `json:"price"`
+	Time                int64             `json:"time"`
+	CompletedCandle     *game.CandleGroup `json:"completedCandle,omitempty"`
+	Merged              bool              `json:"merged,omitempty"`
+	NewCandleDurationMs int64             `json:"newCandleDurationMs,omitempty"`
+}
+
+// AppendMsgpack implements msgpack.Marshaler
+func (p *PriceTick) AppendMsgpack(b []byte) []byte {
+	b = msgpack.AppendArrayHeader(b, 7)
+	b = msgpack.AppendString(b, p.GameID)
+	b = msgpack.AppendInt(b, int64(p.Tick))
+	b = msgpack.AppendFloat(b, p.Price)
+	b = msgpack.AppendInt(b, p.Time)
+	if p.CompletedCandle != nil {
+		b = appendCandle(b, p.CompletedCandle)
+	} else {
+		b = msgpack.AppendNil(b)
+	}
+	b = msgpack.AppendBool(b, p.Merged)
+	return msgpack.AppendInt(b, p.NewCandleDurationMs)
+}
+
+// GameEnd is the data of a game_end, revealing the seed of the round
+type GameEnd struct {
+	GameID          string                 `json:"gameId"`
+	RoundID         string                 `json:"roundId"` // Seed game ID, used by /api/verify/:gameId
+	RNGVersion      int                    `json:"rngVersion"`
+	Distribution    game.CrashDistribution `json:"distribution"`
+	ServerSeed      string                 `json:"serverSeed"`
+	ServerSeedHash  string                 `json:"serverSeedHash"`
+	ClientSeed      string                 `json:"clientSeed"`  // crypto.CrashClientSeed of ClientSeeds
+	ClientSeeds     map[string]string      `json:"clientSeeds"` // Client seed of every player in the round
+	ChainRound      uint64                 `json:"chainRound"`
+	TerminatingHash string                 `json:"terminatingHash"`
+	PeakMultiplier  float64                `json:"peakMultiplier"`
+	Rugged          bool                   `json:"rugged"`
+	TotalTicks      int                    `json:"totalTicks"`
+	PreviousCandles []game.CandleGroup     `json:"previousCandles"`
+}
+
+// AppendMsgpack implements msgpack.Marshaler. The distribution is sent in
+// its JSON shape, followed by the client seeds.
+func (g *GameEnd) AppendMsgpack(b []byte) []byte {
+	b = msgpack.AppendArrayHeader(b, 14)
+	b = msgpack.AppendString(b, g.GameID)
+	b = msgpack.AppendString(b, g.RoundID)
+	b = msgpack.AppendInt(b, int64(g.RNGVersion))
+	b = msgpack.AppendString(b, g.ServerSeed)
+	b = msgpack.AppendString(b, g.ServerSeedHash)
+	b = msgpack.AppendUint(b, g.ChainRound)
+	b = msgpack.AppendString(b, g.TerminatingHash)
+	b = msgpack.AppendFloat(b, g.PeakMultiplier)
+	b = msgpack.AppendBool(b, g.Rugged)
+	b = msgpack.AppendInt(b, int64(g.TotalTicks))
+	b = appendCandles(b, g.PreviousCandles)
+	if withDist, err := msgpack.AppendValue(b, g.Distribution); err == nil {
+		b = withDist
+	} else {
+		b = msgpack.AppendNil(b)
+	}
+	b = msgpack.AppendString(b, g.ClientSeed)
+	if withSeeds, err := msgpack.AppendValue(b, g.ClientSeeds); err == nil {
+		return withSeeds
+	}
+	return msgpack.AppendNil(b)
+}
+
+// AppendMsgpack implements msgpack.Marshaler
+func (r *RoomInfo) AppendMsgpack(b []byte) []byte {
+	b = msgpack.AppendArrayHeader(b, 14)
+	b = msgpack.AppendString(b, r.RoomID)
+	b = msgpack.AppendString(b, r.GameType)
+	b = msgpack.AppendFloat(b, r.BetAmount)
+	b = msgpack.AppendString(b, r.Trend)
+	b = msgpack.AppendString(b, r.Status)
+	b = msgpack.AppendInt(b, r.CreatedAt.UnixMilli())
+	b = msgpack.AppendInt(b, int64(r.Players))
+	b = msgpack.AppendString(b, r.CreatorId)
+	b = msgpack.AppendString(b, r.BotName)
+	b = msgpack.AppendString(b, r.BearSide)
+	b = msgpack.AppendString(b, r.BullSide)
+	b = msgpack.AppendInt(b, int64(r.MaxPlayers))
+	b = msgpack.AppendString(b, r.ContractGameID)
+	return msgpack.AppendInt(b, int64(r.RoomsCount))
+}
+
+// chatMessage is the schema of a chat_message
+type chatMessage struct {
+	Username, Message, UserID, Timestamp string
+}
+
+// chatFromMessage reads a chat_message's fields, which are stored in the
+// message itself rather than under "data"
+func chatFromMessage(message map[string]interface{}) (chatMessage, bool) {
+	var chat chatMessage
+	for _, f := range []struct {
+		key string
+		dst *string
+	}{
+		{"username", &chat.Username},
+		{"message", &chat.Message},
+		{"userId", &chat.UserID},
+		{"timestamp", &chat.Timestamp},
+	} {
+		v, ok := message[f.key].(string)
+		if !ok {
+			return chat, false
+		}
+		*f.dst = v
+	}
+	return chat, true
+}
+
+// AppendMsgpack implements msgpack.Marshaler
+func (c chatMessage) AppendMsgpack(b []byte) []byte {
+	b = msgpack.AppendArrayHeader(b, 4)
+	b = msgpack.AppendString(b, c.Username)
+	b = msgpack.AppendString(b, c.Message)
+	b = msgpack.AppendString(b, c.UserID)
+	return msgpack.AppendString(b, c.Timestamp)
+}
+
+func appendCandles(b []byte, groups []game.CandleGroup) []byte {
+	b = msgpack.AppendArrayHeader(b, len(groups))
+	for i := range groups {
+		b = appendCandle(b, &groups[i])
+	}
+	return b
+}
+
+func appendCandle(b []byte, g *game.CandleGroup) []byte {
+	b = msgpack.AppendArrayHeader(b, 8)
+	b = msgpack.AppendFloat(b, g.Open)
+	if g.Close != nil {
+		b = msgpack.AppendFloat(b, *g.Close)
+	} else {
+		b = msgpack.AppendNil(b)
+	}
+	b = msgpack.AppendFloat(b, g.Max)
+	b = msgpack.AppendFloat(b, g.Min)
+	b = msgpack.AppendInt(b, g.StartTime)
+	b = msgpack.AppendInt(b, g.DurationMs)
+	b = msgpack.AppendBool(b, g.IsComplete)
+	return msgpack.AppendFloats(b, g.ValueList)
+}
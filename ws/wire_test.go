@@ -0,0 +1,170 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"goLangServer/game"
+	"goLangServer/msgpack"
+)
+
+// benchRound plays a synthetic round of the given length and returns the
+// stamped price_update and price_tick of its last tick, as the hub sends them
+func benchRound(ticks int) (update, tick map[string]interface{}) {
+	candles := game.NewCandleBuilder(game.DefaultCandleLayout)
+	var delta game.CandleDelta
+	var price float64
+	for i := 0; i < ticks; i++ {
+		price = 1 + float64(i)/50 + 0.2*math.Sin(float64(i)/5)
+		delta = candles.AddDelta(price, 1700000000000+int64(i)*500)
+	}
+
+	stamped := map[string]interface{}{
+		"type": "price_update",
+		"data": &PriceUpdate{
+			GameID:          "1700000000",
+			Tick:            ticks - 1,
+			Price:           price,
+			Multiplier:      price,
+			PreviousCandles: candles.Completed(),
+			CurrentCandle:   *candles.Current(),
+		},
+		"channel": "crash",
+		"seq":     uint64(4242),
+	}
+	return stamped, stampVariant(stamped, priceTickMessage("1700000000", ticks-1, delta))
+}
+
+// BenchmarkPriceUpdate compares the per-tick cost of encoding a price_update
+// late in a round, and of the delta protocol's price_tick, in JSON and
+// MessagePack. Each message is encoded once per channel, so this is the
+// per-tick CPU of the hub; bytes/msg is what every subscriber receives.
+//
+//	go test ./ws -run '^$' -bench PriceUpdate -benchmem
+func BenchmarkPriceUpdate(b *testing.B) {
+	for _, ticks := range []int{20, 120, 600} {
+		update, tick := benchRound(ticks)
+		for _, m := range []struct {
+			name    string
+			message map[string]interface{}
+		}{
+			{"full", update},
+			{"delta", tick},
+		} {
+			for _, encoding := range []string{encodingJSON, encodingMsgpack} {
+				name := fmt.Sprintf("%s/ticks=%d/%s", m.name, ticks, encoding)
+				b.Run(name, func(b *testing.B) {
+					var size int
+					for i := 0; i < b.N; i++ {
+						data, err := encodeMessage(encoding, m.message)
+						if err != nil {
+							b.Fatal(err)
+						}
+						size = len(data)
+					}
+					b.ReportMetric(float64(size), "bytes/msg")
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkRoomsUpdate compares encoding a rooms_update of 50 rooms
+func BenchmarkRoomsUpdate(b *testing.B) {
+	rooms := make([]*RoomInfo, 50)
+	for i := range rooms {
+		rooms[i] = &RoomInfo{
+			RoomID:     fmt.Sprintf("room-%d", i),
+			GameType:   "candleflip",
+			BetAmount:  0.5,
+			Trend:      "bullish",
+			Status:     "active",
+			CreatedAt:  time.Unix(1700000000, 0),
+			Players:    1,
+			BotName:    "CandleMaster",
+			BearSide:   "bot",
+			BullSide:   "player",
+			MaxPlayers: 1,
+			RoomsCount: 3,
+		}
+	}
+	message := map[string]interface{}{"type": "rooms_update", "rooms": rooms, "channel": "rooms", "seq": uint64(7)}
+
+	for _, encoding := range []string{encodingJSON, encodingMsgpack} {
+		b.Run(encoding, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				data, err := encodeMessage(encoding, message)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes/msg")
+		})
+	}
+}
+
+// TestMsgpackFrames decodes frames and checks them against their schema
+func TestMsgpackFrames(t *testing.T) {
+	update, tick := benchRound(120)
+
+	data, err := encodeMessage(encodingMsgpack, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := decodeFrame(t, data)
+	if frame[0] != "price_update" || frame[1] != "crash" || frame[2] != uint64(4242) {
+		t.Fatalf("price_update header = %v", frame[:3])
+	}
+	pu := update["data"].(*PriceUpdate)
+	body := frame[3].([]interface{})
+	if body[0] != pu.GameID || body[2] != pu.Price || len(body[3].([]interface{})) != len(pu.PreviousCandles) {
+		t.Errorf("price_update body = %v", body[:3])
+	}
+	current := body[4].([]interface{})
+	if current[0] != pu.CurrentCandle.Open || current[5] != uint64(pu.CurrentCandle.DurationMs) {
+		t.Errorf("current candle = %v, want %+v", current, pu.CurrentCandle)
+	}
+
+	data, err = encodeMessage(encodingMsgpack, tick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame = decodeFrame(t, data)
+	if frame[0] != "price_tick" || frame[2] != uint64(4242) {
+		t.Fatalf("price_tick header = %v", frame[:3])
+	}
+
+	// Messages without a schema keep their JSON fields
+	data, err = encodeMessage(encodingMsgpack, map[string]interface{}{"type": "countdown", "data": map[string]interface{}{"countdown": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame = decodeFrame(t, data)
+	want := map[string]interface{}{"data": map[string]interface{}{"countdown": uint64(3)}}
+	if got, _ := json.Marshal(frame[3]); string(got) != mustJSON(want) || frame[1] != nil {
+		t.Errorf("countdown frame = %v", frame)
+	}
+}
+
+func decodeFrame(t *testing.T, data []byte) []interface{} {
+	t.Helper()
+	v, rest, err := msgpack.Decode(data)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decode: %v (%d bytes left)", err, len(rest))
+	}
+	frame, ok := v.([]interface{})
+	if !ok || len(frame) != 4 {
+		t.Fatalf("frame = %#v", v)
+	}
+	return frame
+}
+
+func mustJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}